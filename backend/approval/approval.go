@@ -0,0 +1,18 @@
+package approval
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Requires(req *model.WorkloadRequest) bool
+	Submit(ctx context.Context, req *model.WorkloadRequest) (*model.ApprovalRequest, error)
+	RequiresUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (bool, error)
+	SubmitUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (*model.ApprovalRequest, error)
+	Approve(ctx context.Context, id, comment string) (*model.ApprovalRequest, error)
+	Reject(ctx context.Context, id, comment string) (*model.ApprovalRequest, error)
+	GetApproval(ctx context.Context, id string) (*model.ApprovalRequest, error)
+	ListApprovals(ctx context.Context, status string) ([]model.ApprovalRequest, error)
+}
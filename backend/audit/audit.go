@@ -0,0 +1,14 @@
+package audit
+
+import (
+	"context"
+	"io"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Record(ctx context.Context, event *model.AuditEvent)
+	ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error)
+	ExportEvents(ctx context.Context, filter model.AuditFilter, w io.Writer) error
+}
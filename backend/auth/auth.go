@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Authenticate(ctx context.Context, token string) (*model.User, error)
+}
@@ -1,13 +1,17 @@
 package blueprint
 
 import (
-	"github.com/labstack/echo/v4"
+	"context"
+
 	"github.com/pulumi-idp/internal/model"
 )
 
 type Service interface {
-	GetBlueprints(ctx echo.Context) ([]model.Blueprint, error)
-	GetBlueprintSchema(ctx echo.Context, name string) (map[string]interface{}, error)
-	GetBlueprintUISchema(ctx echo.Context, name string) (map[string]map[string]interface{}, error)
-	GetEnvironmentsForUserAndTag(user, tag string) (*model.EnvironmentsResponse0, error)
+	GetBlueprints(ctx context.Context) ([]model.Blueprint, error)
+	GetBlueprintSchema(ctx context.Context, name, version string) (map[string]interface{}, error)
+	GetBlueprintUISchema(ctx context.Context, name, version string) (map[string]map[string]interface{}, error)
+	GetBlueprintVersions(ctx context.Context, name string) ([]model.BlueprintVersion, error)
+	LatestBlueprintVersion(ctx context.Context, name string) (string, error)
+	GetBlueprintLocation(ctx context.Context, name, version string) (model.BlueprintLocation, error)
+	GetEnvironmentsForUserAndTag(ctx context.Context, continuationToken, tag string) (*model.EnvironmentsResponse0, error)
 }
@@ -7,8 +7,9 @@ import (
 )
 
 type Service interface {
-	ExchangeCodeForToken(code string) (*model.OAuthResponse, error)
+	ExchangeCodeForToken(ctx context.Context, code string) (*model.OAuthResponse, error)
 	CreateRepository(ctx context.Context, req *model.RepoCreationRequest) (*github.Repository, *github.Response, error)
+	DeleteRepository(ctx context.Context, owner, repo string) error
 	SetupBranchProtection(ctx context.Context, owner, repo, branch string, requireReviews bool) error
 	CommitPulumiFilesToRepo(ctx context.Context, tempDir, owner, repo string) error
 	CollectFilesRecursively(dir string) ([]string, error)
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"github.com/pulumi-idp/internal/service"
+)
+
+// GetApprovals handles the request to list approval requests, optionally filtered by status
+func (h *Handler) GetApprovals(c echo.Context) error {
+	status := c.QueryParam("status")
+	switch status {
+	case "", model.ApprovalStatusPending, model.ApprovalStatusApproved, model.ApprovalStatusRejected, model.ApprovalStatusFailed:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Unknown approval status %q", status),
+		})
+	}
+
+	approvals, err := h.services.ApprovalService.ListApprovals(c.Request().Context(), status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to list approval requests: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, approvals)
+}
+
+// GetApproval handles the request to get a single approval request
+func (h *Handler) GetApproval(c echo.Context) error {
+	approval, err := h.services.ApprovalService.GetApproval(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return approvalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, approval)
+}
+
+// ApproveWorkload handles the request to approve a pending workload create
+func (h *Handler) ApproveWorkload(c echo.Context) error {
+	var req model.ApprovalDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Invalid request format: %v", err),
+		})
+	}
+
+	approval, err := h.services.ApprovalService.Approve(c.Request().Context(), c.Param("id"), req.Comment)
+	if err != nil {
+		return approvalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, approval)
+}
+
+// RejectWorkload handles the request to reject a pending workload create
+func (h *Handler) RejectWorkload(c echo.Context) error {
+	var req model.ApprovalDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Invalid request format: %v", err),
+		})
+	}
+
+	approval, err := h.services.ApprovalService.Reject(c.Request().Context(), c.Param("id"), req.Comment)
+	if err != nil {
+		return approvalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, approval)
+}
+
+// approvalError responds to a failed approval request operation
+func approvalError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Approval request not found",
+		})
+	case errors.Is(err, service.ErrApprovalClosed):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": err.Error(),
+	})
+}
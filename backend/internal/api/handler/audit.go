@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pulumi-idp/internal/model"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// GetAuditEvents handles the request to list audit events, newest first
+func (h *Handler) GetAuditEvents(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	filter.Limit = defaultAuditLimit
+	if limit := c.QueryParam("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxAuditLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit),
+			})
+		}
+	}
+	if offset := c.QueryParam("offset"); offset != "" {
+		filter.Offset, err = strconv.Atoi(offset)
+		if err != nil || filter.Offset < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "offset must be a non-negative number",
+			})
+		}
+	}
+
+	events, err := h.services.AuditService.ListEvents(c.Request().Context(), filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to list audit events: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
+// ExportAuditEvents handles the request to export audit events as JSON lines, oldest first
+func (h *Handler) ExportAuditEvents(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="audit-%s.jsonl"`, time.Now().UTC().Format("20060102T150405Z")))
+	response.WriteHeader(http.StatusOK)
+
+	// The status is already sent, a failure can only cut the export short
+	if err := h.services.AuditService.ExportEvents(c.Request().Context(), filter, response); err != nil {
+		c.Logger().Errorf("Audit export error: %v", err)
+	}
+	return nil
+}
+
+// auditFilter reads the audit filters from the query parameters. since and until are RFC 3339 timestamps.
+func auditFilter(c echo.Context) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		Actor:        c.QueryParam("actor"),
+		Action:       c.QueryParam("action"),
+		Organization: c.QueryParam("organization"),
+		Project:      c.QueryParam("project"),
+		Stack:        c.QueryParam("stack"),
+		Blueprint:    c.QueryParam("blueprint"),
+	}
+
+	for name, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.QueryParam(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+		*target = &parsed
+	}
+
+	return filter, nil
+}
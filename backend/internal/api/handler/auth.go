@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/service"
+)
+
+// Authenticate is the middleware that resolves the caller of a request. The token is read from
+// the Authorization header, then from the session cookie, and for WebSocket upgrades, which
+// browsers cannot send headers with, from the bearer subprotocol. The user is stored in the
+// request context for the services.
+func (h *Handler) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !h.cfg.Auth.Enabled {
+			return next(h.withUser(c, &model.User{
+				Login:    model.AnonymousUser,
+				Provider: model.AuthProviderNone,
+				Teams:    []string{},
+			}))
+		}
+
+		token, fromCookie := requestToken(c, h.cfg.Auth.SessionCookie)
+		if token == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Authentication required",
+			})
+		}
+		// Browsers send the cookie along with cross-site requests, those must not change anything
+		if fromCookie && !safeRequest(c.Request()) && !allowedOrigin(c.Request(), h.cfg.Cors.AllowOrigin, true) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Cross-site request rejected",
+			})
+		}
+
+		user, err := h.services.AuthService.Authenticate(c.Request().Context(), token)
+		if errors.Is(err, service.ErrUnauthenticated) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired token",
+			})
+		}
+		if err != nil {
+			c.Logger().Errorf("Token verification error: %v", err)
+			return c.JSON(http.StatusBadGateway, map[string]string{
+				"error": "Failed to verify token",
+			})
+		}
+
+		return next(h.withUser(c, user))
+	}
+}
+
+// GetCurrentUser handles the request to get the authenticated user
+func (h *Handler) GetCurrentUser(c echo.Context) error {
+	return c.JSON(http.StatusOK, service.UserFromContext(c.Request().Context()))
+}
+
+// withUser stores a copy of the user with their resolved role in the request context
+func (h *Handler) withUser(c echo.Context, user *model.User) echo.Context {
+	caller := *user
+	caller.Role = h.services.RBACService.Role(&caller)
+	c.SetRequest(c.Request().WithContext(service.WithUser(c.Request().Context(), &caller)))
+	return c
+}
+
+// authorizationError responds to a request the caller is not allowed to make
+func authorizationError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrForbidden) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": fmt.Sprintf("Failed to authorize request: %v", err),
+	})
+}
+
+// requestToken returns the token of a request and whether it was read from the session cookie
+func requestToken(c echo.Context, sessionCookie string) (string, bool) {
+	if header := c.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if found && (strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "token")) {
+			return strings.TrimSpace(token), false
+		}
+		return "", false
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request()) {
+		if token := subprotocolToken(c.Request()); token != "" {
+			return token, false
+		}
+	}
+
+	if sessionCookie != "" {
+		if cookie, err := c.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+			return cookie.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// safeRequest reports whether a request only reads. WebSocket upgrades are GET requests, but
+// are not protected by CORS, so they count as changing requests.
+func safeRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return !websocket.IsWebSocketUpgrade(r)
+	}
+	return false
+}
+
+// allowedOrigin reports whether a request comes from the API host itself or one of the CORS
+// origins. Requests without Origin header do not come from browsers. With strict, they are
+// rejected and the wildcard origin does not count, the request has to name a trusted origin.
+func allowedOrigin(r *http.Request, origins []string, strict bool) bool {
+	origin := r.Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return !strict
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range origins {
+		if (allowed == "*" && !strict) || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// logStreamProtocol is the subprotocol of the log stream WebSocket. Browsers offer it next to
+// the bearer subprotocol, the server answers with it so that the token is not echoed back.
+const logStreamProtocol = "pulumi-idp.logs"
+
+// bearerProtocolPrefix marks the subprotocol carrying the token, base64url encoded because
+// subprotocols are limited to token characters. Unlike a query parameter, the header does not
+// end up in access logs, proxy logs or the browser history.
+const bearerProtocolPrefix = "base64url.bearer."
+
+func subprotocolToken(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		encoded, found := strings.CutPrefix(protocol, bearerProtocolPrefix)
+		if !found {
+			continue
+		}
+		token, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return ""
+		}
+		return string(token)
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newWebSocketContext(header http.Header) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/api/workloads/ws/acme/web/dev/deployments/1/logs?access_token=query", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestRequestTokenReadsWebSocketSubprotocol(t *testing.T) {
+	token := "eyJhbGciOi.payload+/=.signature"
+	protocols := logStreamProtocol + ", " + bearerProtocolPrefix + base64.RawURLEncoding.EncodeToString([]byte(token))
+
+	c := newWebSocketContext(http.Header{"Sec-Websocket-Protocol": {protocols}})
+	if got, fromCookie := requestToken(c, ""); got != token || fromCookie {
+		t.Fatalf("requestToken = %q, %t, want %q", got, fromCookie, token)
+	}
+}
+
+func TestRequestTokenIgnoresQueryParameter(t *testing.T) {
+	c := newWebSocketContext(nil)
+	if got, _ := requestToken(c, ""); got != "" {
+		t.Fatalf("the token was read from the query string: %q", got)
+	}
+}
+
+func TestRequestTokenReadsCookieOnlyWhenEnabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/workloads", nil)
+	req.AddCookie(&http.Cookie{Name: "idp_session", Value: "cookie-token"})
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	if got, _ := requestToken(c, ""); got != "" {
+		t.Fatalf("the cookie was read although cookie authentication is disabled: %q", got)
+	}
+	if got, fromCookie := requestToken(c, "idp_session"); got != "cookie-token" || !fromCookie {
+		t.Fatalf("requestToken = %q, %t", got, fromCookie)
+	}
+}
+
+func TestAllowedOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		origins []string
+		strict  bool
+		want    bool
+	}{
+		{"same host", "https://idp.example.com", nil, true, true},
+		{"listed origin", "https://portal.example.com", []string{"https://portal.example.com"}, true, true},
+		{"other origin", "https://evil.example.com", []string{"https://portal.example.com"}, false, false},
+		{"wildcard", "https://evil.example.com", []string{"*"}, false, true},
+		{"wildcard when strict", "https://evil.example.com", []string{"*"}, true, false},
+		{"no origin", "", []string{"https://portal.example.com"}, false, true},
+		{"no origin when strict", "", []string{"*"}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://idp.example.com/api/workloads", nil)
+			if test.origin != "" {
+				req.Header.Set(echo.HeaderOrigin, test.origin)
+			}
+			if got := allowedOrigin(req, test.origins, test.strict); got != test.want {
+				t.Fatalf("allowedOrigin = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSafeRequest(t *testing.T) {
+	if !safeRequest(httptest.NewRequest(http.MethodGet, "/api/workloads", nil)) {
+		t.Fatal("GET must count as safe")
+	}
+	if safeRequest(httptest.NewRequest(http.MethodDelete, "/api/workloads/acme/web/dev", nil)) {
+		t.Fatal("DELETE must not count as safe")
+	}
+	if safeRequest(newWebSocketContext(nil).Request()) {
+		t.Fatal("WebSocket upgrades must not count as safe")
+	}
+}
@@ -3,11 +3,12 @@ package handler
 import (
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/pulumi-idp/internal/model"
 	"net/http"
 )
 
 func (h *Handler) GetBlueprints(c echo.Context) error {
-	blueprints, err := h.services.BlueprintService.GetBlueprints(c)
+	blueprints, err := h.services.BlueprintService.GetBlueprints(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -20,12 +21,13 @@ func (h *Handler) GetBlueprints(c echo.Context) error {
 func (h *Handler) GetBlueprintSchema(c echo.Context) error {
 	name := c.Param("name")
 
-	schema, err := h.services.BlueprintService.GetBlueprintSchema(c, name)
+	schema, err := h.services.BlueprintService.GetBlueprintSchema(c.Request().Context(), name, c.QueryParam("version"))
 	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "GITHUB_TOKEN environment variable not set" {
 			status = http.StatusInternalServerError
-		} else if err.Error() == fmt.Sprintf("blueprint %s not found", name) {
+		} else if err.Error() == fmt.Sprintf("blueprint %s not found", name) ||
+			err.Error() == fmt.Sprintf("version %s of blueprint %s not found", c.QueryParam("version"), name) {
 			status = http.StatusNotFound
 		}
 
@@ -39,12 +41,13 @@ func (h *Handler) GetBlueprintSchema(c echo.Context) error {
 func (h *Handler) GetBlueprintUISchema(c echo.Context) error {
 	name := c.Param("name")
 
-	uiSchema, err := h.services.BlueprintService.GetBlueprintUISchema(c, name)
+	uiSchema, err := h.services.BlueprintService.GetBlueprintUISchema(c.Request().Context(), name, c.QueryParam("version"))
 	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "GITHUB_TOKEN environment variable not set" {
 			status = http.StatusInternalServerError
-		} else if err.Error() == fmt.Sprintf("blueprint %s not found", name) {
+		} else if err.Error() == fmt.Sprintf("blueprint %s not found", name) ||
+			err.Error() == fmt.Sprintf("version %s of blueprint %s not found", c.QueryParam("version"), name) {
 			status = http.StatusNotFound
 		}
 
@@ -55,3 +58,41 @@ func (h *Handler) GetBlueprintUISchema(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, uiSchema)
 }
+
+// GetBlueprintVersions handles the request to list the released versions of a blueprint
+func (h *Handler) GetBlueprintVersions(c echo.Context) error {
+	name := c.Param("name")
+
+	versions, err := h.services.BlueprintService.GetBlueprintVersions(c.Request().Context(), name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == fmt.Sprintf("blueprint %s not found", name) {
+			status = http.StatusNotFound
+		}
+
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+// PurgeBlueprintCache handles the request to purge the blueprint cache. Repeated name query
+// parameters only invalidate these blueprints together with the catalog.
+func (h *Handler) PurgeBlueprintCache(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	names := c.QueryParams()["name"]
+	if err := h.services.BlueprintService.PurgeCache(c.Request().Context(), names...); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to purge the blueprint cache: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"purged": names,
+	})
+}
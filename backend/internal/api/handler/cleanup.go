@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	cleanup "github.com/pulumi-idp/internal/cron"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+const (
+	defaultCleanupRunLimit = 20
+	maxCleanupRunLimit     = 200
+)
+
+// GetCleanupStatus handles the request to get the state of the cleanup scheduler and its latest run
+func (h *Handler) GetCleanupStatus(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, h.cleanup.Status(c.Request().Context()))
+}
+
+// GetCleanupRuns handles the request to list the cleanup runs, newest first
+func (h *Handler) GetCleanupRuns(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	limit := defaultCleanupRunLimit
+	if value := c.QueryParam("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxCleanupRunLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxCleanupRunLimit),
+			})
+		}
+	}
+	offset := 0
+	if value := c.QueryParam("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "offset must be a non-negative number",
+			})
+		}
+	}
+
+	runs, err := h.cleanup.Runs(c.Request().Context(), limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to list cleanup runs: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+// GetCleanupRun handles the request to get a single cleanup run
+func (h *Handler) GetCleanupRun(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Run ID must be a number",
+		})
+	}
+
+	run, err := h.cleanup.Run(c.Request().Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Cleanup run not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// TriggerCleanup handles the request to start a cleanup run right away. The run continues in the background.
+func (h *Handler) TriggerCleanup(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	run, err := h.cleanup.TriggerCleanup(c.Request().Context())
+	if errors.Is(err, cleanup.ErrCleanupInProgress) || errors.Is(err, cleanup.ErrNotLeader) {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to start cleanup run: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, run)
+}
+
+// PauseCleanup handles the request to skip the scheduled cleanup runs
+func (h *Handler) PauseCleanup(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	status, err := h.cleanup.Pause(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to pause cleanup: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// ResumeCleanup handles the request to continue the scheduled cleanup runs
+func (h *Handler) ResumeCleanup(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	status, err := h.cleanup.Resume(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to resume cleanup: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// UpdateCleanupSettings handles the request to change the cleanup schedule or deletion criteria
+func (h *Handler) UpdateCleanupSettings(c echo.Context) error {
+	if err := h.services.RBACService.AuthorizeRole(c.Request().Context(), model.RoleAdmin); err != nil {
+		return authorizationError(c, err)
+	}
+
+	var req model.CleanupSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Invalid request format: %v", err),
+		})
+	}
+
+	status, err := h.cleanup.UpdateSettings(c.Request().Context(), &req)
+	if errors.Is(err, cleanup.ErrInvalidCleanupSettings) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to update cleanup settings: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
@@ -1,11 +1,20 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/service"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
+// maxWebhookPayload is the largest payload GitHub delivers to webhooks
+const maxWebhookPayload = 25 << 20
+
 func (h *Handler) HandleGitHubToken(c echo.Context) error {
 	var req model.OAuthRequest
 
@@ -23,7 +32,7 @@ func (h *Handler) HandleGitHubToken(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Missing required field: code")
 	}
 
-	oauthResp, err := h.services.GitHubService.ExchangeCodeForToken(req.Code)
+	oauthResp, err := h.services.GitHubService.ExchangeCodeForToken(c.Request().Context(), req.Code)
 	if err != nil {
 		c.Logger().Errorf("GitHub token exchange error: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to exchange token with GitHub")
@@ -31,3 +40,73 @@ func (h *Handler) HandleGitHubToken(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, oauthResp)
 }
+
+// HandleGitHubWebhook handles the deliveries of the GitHub webhook of the blueprint repository.
+// Deliveries are authenticated by their X-Hub-Signature-256 header instead of a token.
+func (h *Handler) HandleGitHubWebhook(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookPayload+1))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read the webhook payload",
+		})
+	}
+	if len(body) > maxWebhookPayload {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+			"error": "Webhook payload is too large",
+		})
+	}
+
+	err = h.services.GitHubService.VerifyWebhookSignature(body, c.Request().Header.Get("X-Hub-Signature-256"))
+	if errors.Is(err, service.ErrWebhookNotConfigured) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	// Webhooks with the form content type send the JSON in the payload field
+	payload := body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationForm) {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid form payload",
+			})
+		}
+		payload = []byte(values.Get("payload"))
+	}
+
+	event := c.Request().Header.Get("X-GitHub-Event")
+	switch event {
+	case "ping":
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "pong",
+		})
+	case "push":
+	default:
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": fmt.Sprintf("Ignoring %s event", event),
+		})
+	}
+
+	refresh, err := h.services.BlueprintService.RefreshFromPush(c.Request().Context(), payload)
+	if errors.Is(err, service.ErrInvalidWebhookPayload) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if refresh == nil {
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Push does not concern the blueprint catalog",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, refresh)
+}
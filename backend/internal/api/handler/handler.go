@@ -3,6 +3,7 @@ package handler
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/pulumi-idp/internal/config"
+	cleanup "github.com/pulumi-idp/internal/cron"
 	"github.com/pulumi-idp/internal/service"
 )
 
@@ -11,6 +12,7 @@ type Handler struct {
 	services  *service.Service
 	validator *validator.Validate
 	cfg       *config.Config
+	cleanup   *cleanup.StackCleanupService
 }
 
 // NewHandler creates a new handler instance
@@ -22,3 +24,8 @@ func NewHandler(services *service.Service, cfg *config.Config) *Handler {
 		cfg:       cfg,
 	}
 }
+
+// SetCleanupService makes the stack cleanup manageable through the admin API
+func (h *Handler) SetCleanupService(service *cleanup.StackCleanupService) {
+	h.cleanup = service
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pulumi-idp/internal/repository"
+	"github.com/pulumi-idp/internal/service"
+)
+
+// GetJob handles the request to get the status of a provisioning job
+func (h *Handler) GetJob(c echo.Context) error {
+	id := c.Param("id")
+
+	job, err := h.services.JobService.GetJob(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Job not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionView, job.Organization, job.Project, job.Stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
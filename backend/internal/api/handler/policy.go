@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetPolicies handles the request to list the policies workload requests are admitted against
+func (h *Handler) GetPolicies(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.services.PolicyService.Policies())
+}
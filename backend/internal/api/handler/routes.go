@@ -5,25 +5,60 @@ import (
 )
 
 func (h *Handler) Register(v1 *echo.Group) {
+	// The OAuth code exchange is how clients obtain a token, every other route requires one
+	// except for the webhook, which GitHub signs instead
 	github := v1.Group("/github")
 	github.POST("/token", h.HandleGitHubToken)
+	github.POST("/webhook", h.HandleGitHubWebhook)
 
-	blueprint := v1.Group("/blueprints")
+	v1.GET("/user", h.GetCurrentUser, h.Authenticate)
+	v1.GET("/policies", h.GetPolicies, h.Authenticate)
+
+	blueprint := v1.Group("/blueprints", h.Authenticate)
 	blueprint.GET("", h.GetBlueprints)
 	blueprint.GET("/:name/schema", h.GetBlueprintSchema)
 	blueprint.GET("/:name/ui-schema", h.GetBlueprintUISchema)
+	blueprint.GET("/:name/versions", h.GetBlueprintVersions)
+	blueprint.DELETE("/cache", h.PurgeBlueprintCache)
 
-	workload := v1.Group("/workloads")
+	workload := v1.Group("/workloads", h.Authenticate)
 	workload.GET("/schema", h.GetWorkloadSchema)
 	workload.POST("", h.CreateWorkload)
+	workload.POST("/validate", h.ValidateWorkload)
 
 	workload.PUT("/:organization/:project/:stack", h.UpdateWorkload)
+	workload.POST("/:organization/:project/:stack/upgrade", h.PreviewWorkloadUpgrade)
+	workload.POST("/:organization/:project/:stack/upgrade/apply", h.ApplyWorkloadUpgrade)
 	workload.DELETE("/:organization/:project/:stack", h.DeleteWorkload)
+	workload.POST("/:organization/:project/:stack/extend", h.ExtendWorkload)
 	workload.GET("", h.GetWorkloads)
+	workload.GET("/outdated", h.GetOutdatedWorkloads)
 	workload.GET("/:organization/:project/:stack", h.GetWorkloadDetails)
 
 	workload.GET("/:organization/:project/:stack/deployments/:deploymentID/logs", h.GetDeploymentLogs)
 
 	// WebSocket endpoint for streaming logs
 	workload.GET("/ws/:organization/:project/:stack/deployments/:deploymentID/logs", h.StreamDeploymentLogsWS)
+
+	job := v1.Group("/jobs", h.Authenticate)
+	job.GET("/:id", h.GetJob)
+
+	approval := v1.Group("/approvals", h.Authenticate)
+	approval.GET("", h.GetApprovals)
+	approval.GET("/:id", h.GetApproval)
+	approval.POST("/:id/approve", h.ApproveWorkload)
+	approval.POST("/:id/reject", h.RejectWorkload)
+
+	audit := v1.Group("/audit", h.Authenticate)
+	audit.GET("", h.GetAuditEvents)
+	audit.GET("/export", h.ExportAuditEvents)
+
+	cleanup := v1.Group("/cleanup", h.Authenticate)
+	cleanup.GET("", h.GetCleanupStatus)
+	cleanup.PUT("", h.UpdateCleanupSettings)
+	cleanup.GET("/runs", h.GetCleanupRuns)
+	cleanup.GET("/runs/:id", h.GetCleanupRun)
+	cleanup.POST("/runs", h.TriggerCleanup)
+	cleanup.POST("/pause", h.PauseCleanup)
+	cleanup.POST("/resume", h.ResumeCleanup)
 }
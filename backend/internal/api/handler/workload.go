@@ -1,17 +1,22 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"github.com/pulumi-idp/internal/service"
+	"io"
 	"net/http"
 	"time"
 )
 
 // GetWorkloadSchema handles the request to get the workload JSON schema
 func (h *Handler) GetWorkloadSchema(c echo.Context) error {
-	schema, err := h.services.WorkloadService.GetWorkloadSchema(c)
+	schema, err := h.services.WorkloadService.GetWorkloadSchema(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -25,8 +30,10 @@ func (h *Handler) GetWorkloads(c echo.Context) error {
 	workload := c.QueryParam("workload")
 	projectID := c.QueryParam("projectid")
 
-	stacks, err := h.services.WorkloadService.GetWorkloads(c, workload, projectID)
-	if err != nil {
+	stacks, err := h.services.WorkloadService.GetWorkloads(c.Request().Context(), workload, projectID)
+	if errors.Is(err, service.ErrForbidden) {
+		return authorizationError(c, err)
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": fmt.Sprintf("Failed to list stacks: %v", err),
 		})
@@ -35,6 +42,20 @@ func (h *Handler) GetWorkloads(c echo.Context) error {
 	return c.JSON(http.StatusOK, stacks)
 }
 
+// GetOutdatedWorkloads handles the request to list workloads running an outdated blueprint version
+func (h *Handler) GetOutdatedWorkloads(c echo.Context) error {
+	workloads, err := h.services.WorkloadService.GetOutdatedWorkloads(c.Request().Context())
+	if errors.Is(err, service.ErrForbidden) {
+		return authorizationError(c, err)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to list outdated workloads: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, workloads)
+}
+
 // DeleteWorkload handles the request to delete a workload
 func (h *Handler) DeleteWorkload(c echo.Context) error {
 	organization := c.Param("organization")
@@ -59,7 +80,11 @@ func (h *Handler) DeleteWorkload(c echo.Context) error {
 		})
 	}
 
-	err := h.services.WorkloadService.DeleteWorkload(organization, project, stack)
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionDelete, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	err := h.services.WorkloadService.DeleteWorkload(c.Request().Context(), organization, project, stack)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -95,30 +120,209 @@ func (h *Handler) UpdateWorkload(c echo.Context) error {
 		})
 	}
 
-	req := new(model.WorkloadRequest)
-	if err := c.Bind(req); err != nil {
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionUpdate, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	req, raw, err := bindWorkloadRequest(c)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request format",
 		})
 	}
 
-	err := h.services.WorkloadService.UpdateWorkload(organization, project, stack, req)
+	// Handing the workload over to another team takes the permissions to provision it for that team
+	if err := h.services.RBACService.AuthorizeTransfer(c.Request().Context(), organization, project, stack, req.Team); err != nil {
+		return authorizationError(c, err)
+	}
+
+	if err := h.services.WorkloadService.ValidateWorkloadUpdate(c.Request().Context(), organization, project, stack, req, raw); err != nil {
+		return validationError(c, err)
+	}
+
+	if err := h.services.PolicyService.AdmitUpdate(c.Request().Context(), organization, project, stack, req); err != nil {
+		return validationError(c, err)
+	}
+
+	// Moving a workload to a stage that needs approval waits for it like a create
+	requiresApproval, err := h.services.ApprovalService.RequiresUpdate(c.Request().Context(), organization, project, stack, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if requiresApproval {
+		approval, err := h.services.ApprovalService.SubmitUpdate(c.Request().Context(), organization, project, stack, req)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": fmt.Sprintf("Failed to submit workload update for approval: %v", err),
+			})
+		}
+		return c.JSON(http.StatusAccepted, approval)
+	}
+
+	job, err := h.services.WorkloadService.UpdateWorkload(c.Request().Context(), organization, project, stack, req)
+	if errors.Is(err, service.ErrJobInProgress) {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Workload updated successfully",
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// ExtendWorkload handles the request to move the expiry of an ephemeral workload
+func (h *Handler) ExtendWorkload(c echo.Context) error {
+	organization := c.Param("organization")
+	project := c.Param("project")
+	stack := c.Param("stack")
+
+	req := new(model.ExtendRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionUpdate, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	record, err := h.services.WorkloadService.ExtendWorkload(c.Request().Context(), organization, project, stack, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrInvalidExpiry):
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, record)
+}
+
+// PreviewWorkloadUpgrade handles the request to preview a workload on another blueprint version
+func (h *Handler) PreviewWorkloadUpgrade(c echo.Context) error {
+	organization := c.Param("organization")
+	project := c.Param("project")
+	stack := c.Param("stack")
+
+	req := new(model.UpgradeRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionUpdate, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	plan, err := h.services.WorkloadService.PreviewUpgrade(c.Request().Context(), organization, project, stack, req.Version)
+	if err != nil {
+		return upgradeError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, plan)
+}
+
+// ApplyWorkloadUpgrade handles the request to move a workload to another blueprint version
+func (h *Handler) ApplyWorkloadUpgrade(c echo.Context) error {
+	organization := c.Param("organization")
+	project := c.Param("project")
+	stack := c.Param("stack")
+
+	req := new(model.UpgradeRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionUpdate, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	job, err := h.services.WorkloadService.ApplyUpgrade(c.Request().Context(), organization, project, stack, req)
+	if err != nil {
+		return upgradeError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// bindWorkloadRequest decodes a workload request body. The generic form of the body is returned as well,
+// so that custom workload properties can be validated.
+func bindWorkloadRequest(c echo.Context) (*model.WorkloadRequest, map[string]interface{}, error) {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := new(model.WorkloadRequest)
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, err
+	}
+
+	return req, raw, nil
+}
+
+// validationError responds with the field errors or policy violations of a rejected request
+func validationError(c echo.Context, err error) error {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Workload request failed validation",
+			"fields": validationErr.Fields,
+		})
+	}
+
+	var policyErr *service.PolicyViolationError
+	if errors.As(err, &policyErr) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      "Workload request violates policies",
+			"violations": policyErr.Violations,
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": fmt.Sprintf("Failed to validate workload request: %v", err),
+	})
+}
+
+func upgradeError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrMissingRequiredConfig):
+		status = http.StatusBadRequest
+	}
+
+	return c.JSON(status, map[string]string{
+		"error": err.Error(),
 	})
 }
 
 // CreateWorkload handles the request to create a new workload
 func (h *Handler) CreateWorkload(c echo.Context) error {
 	ctx := c.Request().Context()
-	req := new(model.WorkloadRequest)
-	if err := c.Bind(req); err != nil {
+	req, raw, err := bindWorkloadRequest(c)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": fmt.Sprintf("Invalid request format: %v", err),
 		})
@@ -134,14 +338,70 @@ func (h *Handler) CreateWorkload(c echo.Context) error {
 		})
 	}
 
-	response, err := h.services.WorkloadService.CreateWorkload(ctx, req)
+	if err := h.services.RBACService.AuthorizeProvision(ctx, req.BlueprintName, req.Team); err != nil {
+		return authorizationError(c, err)
+	}
+
+	if err := h.services.WorkloadService.ValidateWorkloadRequest(c.Request().Context(), req, raw); err != nil {
+		return validationError(c, err)
+	}
+
+	if err := h.services.PolicyService.AdmitCreate(ctx, req); err != nil {
+		return validationError(c, err)
+	}
+
+	// Nothing is provisioned until the request is approved
+	if h.services.ApprovalService.Requires(req) {
+		approval, err := h.services.ApprovalService.Submit(ctx, req)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": fmt.Sprintf("Failed to submit workload for approval: %v", err),
+			})
+		}
+		return c.JSON(http.StatusAccepted, approval)
+	}
+
+	job, err := h.services.WorkloadService.CreateWorkload(ctx, req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// ValidateWorkload handles the request to check a create request against the workload and
+// blueprint schemas and the policies, without provisioning anything
+func (h *Handler) ValidateWorkload(c echo.Context) error {
+	ctx := c.Request().Context()
+	req, raw, err := bindWorkloadRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Invalid request format: %v", err),
+		})
+	}
+
+	result := model.ValidationResult{
+		Fields: []model.FieldError{},
+	}
+
+	var validationErr *service.ValidationError
+	if err := h.services.WorkloadService.ValidateWorkloadRequest(c.Request().Context(), req, raw); errors.As(err, &validationErr) {
+		result.Fields = validationErr.Fields
+	} else if err != nil {
+		return validationError(c, err)
+	}
+
+	result.Violations, err = h.services.PolicyService.EvaluateCreate(ctx, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to evaluate policies: %v", err),
+		})
+	}
+
+	result.Valid = len(result.Fields) == 0 && len(result.Violations) == 0
+	return c.JSON(http.StatusOK, result)
 }
 
 // GetWorkloadDetails handles the request to get detailed information about a workload
@@ -150,7 +410,11 @@ func (h *Handler) GetWorkloadDetails(c echo.Context) error {
 	project := c.Param("project")
 	stack := c.Param("stack")
 
-	response, err := h.services.WorkloadService.GetWorkloadDetails(organization, project, stack)
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionView, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
+	response, err := h.services.WorkloadService.GetWorkloadDetails(c.Request().Context(), organization, project, stack)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -160,13 +424,16 @@ func (h *Handler) GetWorkloadDetails(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-// WebSocket upgrader configuration
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // This allows all origins, restrict in production
-	},
+// upgrader returns the WebSocket upgrader, it accepts the origins CORS allows
+func (h *Handler) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{logStreamProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			return allowedOrigin(r, h.cfg.Cors.AllowOrigin, false)
+		},
+	}
 }
 
 // GetDeploymentLogs handles GET requests for deployment logs
@@ -177,8 +444,12 @@ func (h *Handler) GetDeploymentLogs(c echo.Context) error {
 	deploymentID := c.Param("deploymentID")
 	continuationToken := c.QueryParam("continuationToken")
 
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionView, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
 	logResponse, err := h.services.WorkloadService.GetDeploymentLogs(
-		organization, project, stack, deploymentID, continuationToken)
+		c.Request().Context(), organization, project, stack, deploymentID, continuationToken)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -196,8 +467,12 @@ func (h *Handler) StreamDeploymentLogsWS(c echo.Context) error {
 	stack := c.Param("stack")
 	deploymentID := c.Param("deploymentID")
 
+	if err := h.services.RBACService.AuthorizeWorkload(c.Request().Context(), service.ActionView, organization, project, stack); err != nil {
+		return authorizationError(c, err)
+	}
+
 	// Upgrade HTTP connection to WebSocket
-	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	ws, err := h.upgrader().Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
 	}
@@ -207,7 +482,7 @@ func (h *Handler) StreamDeploymentLogsWS(c echo.Context) error {
 	var continuationToken string
 	for {
 		logResponse, err := h.services.WorkloadService.GetDeploymentLogs(
-			organization, project, stack, deploymentID, continuationToken)
+			c.Request().Context(), organization, project, stack, deploymentID, continuationToken)
 
 		if err != nil {
 			ws.WriteJSON(map[string]string{
@@ -9,10 +9,21 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server ServerConfig
-	GitHub GitHubConfig
-	Pulumi PulumiConfig
-	Cors   CorsConfig
+	Server   ServerConfig
+	GitHub   GitHubConfig
+	Pulumi   PulumiConfig
+	Cors     CorsConfig
+	Database DatabaseConfig
+	Auth     AuthConfig
+	RBAC     RBACConfig
+	Approval ApprovalConfig
+	Policy   PolicyConfig
+	Expiry   ExpiryConfig
+	Notify   NotificationConfig
+	Cleanup  CleanupConfig
+	Leader   LeaderConfig
+	Status   StackStatusConfig
+	Cache    BlueprintCacheConfig
 }
 
 type CorsConfig struct {
@@ -26,9 +37,13 @@ type CorsConfig struct {
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
+	Port        string
+	ReadTimeout time.Duration
+	// WriteTimeout is the deadline of a request, its outbound calls are cancelled when it passes.
+	// Provisioning jobs run in the background and are not bound by it.
 	WriteTimeout time.Duration
+	// ShutdownTimeout is how long requests, jobs and cleanup runs are waited for on shutdown
+	ShutdownTimeout time.Duration
 }
 
 // GitHubConfig holds GitHub-related configuration
@@ -37,6 +52,11 @@ type GitHubConfig struct {
 	ClientSecret string
 	RedirectURI  string
 	Token        string
+	// WebhookSecret verifies the signature of webhook deliveries, webhooks are rejected without it
+	WebhookSecret string
+	// Organization is the GitHub organization users have to be members of to sign in with GitHub.
+	// Only its teams count for RBAC.
+	Organization string
 }
 
 type PulumiConfig struct {
@@ -45,22 +65,151 @@ type PulumiConfig struct {
 	APIVersion                 string
 	Organization               string
 	BlueprintGithubLocation    string
+	BlueprintSources           []string
 	WorkloadDefinitionLocation string
+	// MaxRetries is how often a rate limited or failed API request is retried
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, it doubles with every further one
+	RetryBackoff time.Duration
+}
+
+// AuthConfig holds API authentication configuration
+type AuthConfig struct {
+	// Enabled requires a valid token on every API route except the OAuth code exchange
+	Enabled bool
+	// OIDCIssuer enables OIDC JWTs issued by this issuer; OIDCAudience is the audience they have
+	// to be issued for, it is required with an issuer
+	OIDCIssuer      string
+	OIDCAudience    string
+	OIDCGroupsClaim string
+	// IdentityCacheTTL is how long a resolved token identity is reused
+	IdentityCacheTTL time.Duration
+	// SessionCookie is the cookie read when a request carries no bearer token, empty disables
+	// cookie authentication
+	SessionCookie string
+}
+
+// RBACConfig holds the role assignments checked before workload operations.
+// Role entries are team names, or user:<provider>:<login> for a single user, like user:github:alice.
+type RBACConfig struct {
+	Enabled       bool
+	AdminTeams    []string
+	OperatorTeams []string
+	ViewerTeams   []string
+	// DefaultRole is the role of users without an assignment
+	DefaultRole string
+	// BlueprintProvisioners restricts who may provision a blueprint, as blueprint:team entries.
+	// Blueprints without an entry can be provisioned by every operator.
+	BlueprintProvisioners []string
+}
+
+// ApprovalConfig holds the policy for workloads that need approval before they are provisioned
+type ApprovalConfig struct {
+	// Creates for one of these stages or blueprints wait for approval, as do updates moving a
+	// workload to one of these stages
+	Stages     []string
+	Blueprints []string
+	// ApproverTeams may approve or reject requests, entries as in RBACConfig. Admins always may.
+	ApproverTeams     []string
+	RequiredApprovals int
+	// SecretsProject is the ESC project that holds the config of pending requests, secrets included
+	SecretsProject string
+}
+
+// PolicyConfig holds the location of the policies workload requests are admitted against
+type PolicyConfig struct {
+	// Dir holds the policy files, no policies are enforced without it
+	Dir string
+}
+
+// ExpiryConfig holds the settings of ephemeral workloads
+type ExpiryConfig struct {
+	// WarningPeriod is how long before its expiry the owners of a workload are warned
+	WarningPeriod time.Duration
+	// MaxTTL caps how far in the future a workload can expire, unlimited if zero
+	MaxTTL time.Duration
+	// StageTTLs are stage:ttl entries, the TTL of workloads created for a stage without one
+	StageTTLs []string
+}
+
+// NotificationConfig holds where notifications about workloads are sent
+type NotificationConfig struct {
+	// WebhookURL receives notifications as JSON, with a text field for chat webhooks
+	WebhookURL string
+}
+
+// CleanupConfig holds the schedule of the cleanup and how it retries the destroy of deleted workloads
+type CleanupConfig struct {
+	// Schedule is when the cleanup runs, a Go duration like 5m or a cron expression
+	Schedule string
+	// RulesFile is a YAML file of stack deletion rules, only stacks tagged idp:auto-delete are deleted without it
+	RulesFile string
+	// DryRun only logs what the deletion rules would do
+	DryRun bool
+	// RunTimeout bounds a cleanup run, by default it ends shortly before the next run is due
+	RunTimeout time.Duration
+	// MaxDestroyAttempts is how often a failed destroy is retried before the workload is marked destroy-failed
+	MaxDestroyAttempts int
+	// DestroyRetryBackoff is the wait before the first retry, it doubles with every further one
+	DestroyRetryBackoff time.Duration
+}
+
+// LeaderConfig holds the lease that elects the replica running the background work
+type LeaderConfig struct {
+	// ID identifies this replica, its hostname by default
+	ID string
+	// LeaseDuration is how long a lease lasts without being renewed
+	LeaseDuration time.Duration
+}
+
+// StackStatusConfig holds the cache of the stack statuses shown in the workload list
+type StackStatusConfig struct {
+	// TTL is how long a status is shown before it is read again
+	TTL time.Duration
+	// RefreshInterval is how often all statuses are read in the background, 0 disables it
+	RefreshInterval time.Duration
+	// Concurrency limits the parallel Pulumi API calls while reading the statuses
+	Concurrency int
+}
+
+// BlueprintCacheConfig holds the cache of the blueprint catalog, Pulumi.yaml files and schemas
+type BlueprintCacheConfig struct {
+	// Backend is memory, an LRU cache for single-replica deployments, or database, shared by all
+	// replicas. Invalidations of the memory backend only reach the replica they run on.
+	Backend string
+	// Size is the maximum number of entries of the memory backend
+	Size int
+	// TTL is how long the catalog, the versions and the Pulumi.yaml files are cached
+	TTL time.Duration
+	// SchemaTTL is how long the schemas are cached, without the ESC environments of the stage
+	SchemaTTL time.Duration
+}
+
+// DatabaseConfig holds database-related configuration
+type DatabaseConfig struct {
+	Driver          string
+	ConnectionURL   string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "3000"),
-			ReadTimeout:  time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
-			WriteTimeout: time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 10)) * time.Second,
+			Port:            getEnv("PORT", "3000"),
+			ReadTimeout:     time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
+			WriteTimeout:    time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 30)) * time.Second,
+			ShutdownTimeout: time.Duration(getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 120)) * time.Second,
 		},
 		GitHub: GitHubConfig{
-			ClientID:     getEnv("GITHUB_CLIENT_ID", ""),
-			ClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
-			RedirectURI:  getEnv("GITHUB_REDIRECT_URI", ""),
-			Token:        getEnv("GITHUB_TOKEN", ""),
+			ClientID:      getEnv("GITHUB_CLIENT_ID", ""),
+			ClientSecret:  getEnv("GITHUB_CLIENT_SECRET", ""),
+			RedirectURI:   getEnv("GITHUB_REDIRECT_URI", ""),
+			Token:         getEnv("GITHUB_TOKEN", ""),
+			WebhookSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
+			Organization:  getEnv("GITHUB_ORGANIZATION", getEnv("PULUMI_ORGANIZATION", "")),
 		},
 		Pulumi: PulumiConfig{
 			APIBaseURL:                 getEnv("PULUMI_BASE_URL", "https://api.pulumi.com"),
@@ -68,16 +217,83 @@ func Load() *Config {
 			Organization:               getEnv("PULUMI_ORGANIZATION", ""),
 			APIVersion:                 "application/vnd.pulumi+8",
 			BlueprintGithubLocation:    getEnv("PULUMI_BLUEPRINT_GITHUB_LOCATION", ""),
+			BlueprintSources:           getEnvAsArray("PULUMI_BLUEPRINT_SOURCES", nil),
 			WorkloadDefinitionLocation: getEnv("PULUMI_WORKLOAD_DEFINITION_LOCATION", ""),
+			MaxRetries:                 getEnvAsInt("PULUMI_API_MAX_RETRIES", 4),
+			RetryBackoff:               time.Duration(getEnvAsInt("PULUMI_API_RETRY_BACKOFF", 1)) * time.Second,
 		},
 		Cors: CorsConfig{
 			AllowOrigin:      getEnvAsArray("CORS_ALLOW_ORIGIN", []string{"*"}),
 			AllowHeaders:     getEnvAsArray("CORS_ALLOW_HEADERS", []string{"*"}),
 			AllowMethods:     getEnvAsArray("CORS_ALLOW_METHODS", []string{"*"}),
-			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
 			ExposeHeaders:    getEnvAsArray("CORS_EXPOSE_HEADERS", []string{"Content-Length", "Content-Type", "Access-Control-Allow-Origin"}),
 			MaxAge:           getEnvAsInt("CORS_MAX_AGE", 86400), // 24 hours
 		},
+		Database: DatabaseConfig{
+			Driver:          getEnv("DATABASE_DRIVER", "sqlite"),
+			ConnectionURL:   getEnv("DATABASE_URL", "pulumi-idp.db"),
+			MaxIdleConns:    getEnvAsInt("DATABASE_MAX_IDLE_CONNS", 2),
+			MaxOpenConns:    getEnvAsInt("DATABASE_MAX_OPEN_CONNS", 10),
+			ConnMaxLifetime: time.Duration(getEnvAsInt("DATABASE_CONN_MAX_LIFETIME", 3600)) * time.Second,
+		},
+		Auth: AuthConfig{
+			Enabled:          getEnvAsBool("AUTH_ENABLED", true),
+			OIDCIssuer:       getEnv("AUTH_OIDC_ISSUER", ""),
+			OIDCAudience:     getEnv("AUTH_OIDC_AUDIENCE", ""),
+			OIDCGroupsClaim:  getEnv("AUTH_OIDC_GROUPS_CLAIM", "groups"),
+			IdentityCacheTTL: time.Duration(getEnvAsInt("AUTH_IDENTITY_CACHE_TTL", 300)) * time.Second,
+			SessionCookie:    getEnv("AUTH_SESSION_COOKIE", ""),
+		},
+		RBAC: RBACConfig{
+			Enabled:               getEnvAsBool("RBAC_ENABLED", getEnvAsBool("AUTH_ENABLED", true)),
+			AdminTeams:            getEnvAsArray("RBAC_ADMIN_TEAMS", nil),
+			OperatorTeams:         getEnvAsArray("RBAC_OPERATOR_TEAMS", nil),
+			ViewerTeams:           getEnvAsArray("RBAC_VIEWER_TEAMS", nil),
+			DefaultRole:           getEnv("RBAC_DEFAULT_ROLE", "viewer"),
+			BlueprintProvisioners: getEnvAsArray("RBAC_BLUEPRINT_PROVISIONERS", nil),
+		},
+		Approval: ApprovalConfig{
+			Stages:            getEnvAsArray("APPROVAL_STAGES", nil),
+			Blueprints:        getEnvAsArray("APPROVAL_BLUEPRINTS", nil),
+			ApproverTeams:     getEnvAsArray("APPROVAL_APPROVER_TEAMS", nil),
+			RequiredApprovals: getEnvAsInt("APPROVAL_REQUIRED_APPROVALS", 1),
+			SecretsProject:    getEnv("APPROVAL_SECRETS_PROJECT", "idp-approvals"),
+		},
+		Policy: PolicyConfig{
+			Dir: getEnv("POLICY_DIR", ""),
+		},
+		Expiry: ExpiryConfig{
+			WarningPeriod: time.Duration(getEnvAsInt("EXPIRY_WARNING_PERIOD", 86400)) * time.Second,
+			MaxTTL:        time.Duration(getEnvAsInt("EXPIRY_MAX_TTL", 0)) * time.Second,
+			StageTTLs:     getEnvAsArray("EXPIRY_STAGE_TTLS", nil),
+		},
+		Notify: NotificationConfig{
+			WebhookURL: getEnv("NOTIFICATION_WEBHOOK_URL", ""),
+		},
+		Cleanup: CleanupConfig{
+			Schedule:            getEnv("CLEANUP_SCHEDULE", "1m"),
+			RulesFile:           getEnv("CLEANUP_RULES_FILE", ""),
+			DryRun:              getEnvAsBool("CLEANUP_DRY_RUN", false),
+			RunTimeout:          time.Duration(getEnvAsInt("CLEANUP_RUN_TIMEOUT", 0)) * time.Second,
+			MaxDestroyAttempts:  getEnvAsInt("CLEANUP_MAX_DESTROY_ATTEMPTS", 3),
+			DestroyRetryBackoff: time.Duration(getEnvAsInt("CLEANUP_DESTROY_RETRY_BACKOFF", 120)) * time.Second,
+		},
+		Leader: LeaderConfig{
+			ID:            getEnv("LEADER_ID", hostname()),
+			LeaseDuration: time.Duration(getEnvAsInt("LEADER_LEASE_DURATION", 30)) * time.Second,
+		},
+		Status: StackStatusConfig{
+			TTL:             time.Duration(getEnvAsInt("STACK_STATUS_TTL", 60)) * time.Second,
+			RefreshInterval: time.Duration(getEnvAsInt("STACK_STATUS_REFRESH_INTERVAL", 30)) * time.Second,
+			Concurrency:     getEnvAsInt("STACK_STATUS_CONCURRENCY", 8),
+		},
+		Cache: BlueprintCacheConfig{
+			Backend:   getEnv("BLUEPRINT_CACHE_BACKEND", "memory"),
+			Size:      getEnvAsInt("BLUEPRINT_CACHE_SIZE", 1000),
+			TTL:       time.Duration(getEnvAsInt("BLUEPRINT_CACHE_TTL", 3600)) * time.Second,
+			SchemaTTL: time.Duration(getEnvAsInt("BLUEPRINT_SCHEMA_CACHE_TTL", 300)) * time.Second,
+		},
 	}
 }
 
@@ -89,6 +305,15 @@ func getEnvAsArray(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// hostname returns the hostname of the machine, or a fixed name if it cannot be determined
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "pulumi-idp"
+	}
+	return name
+}
+
 // getEnv retrieves environment variable or returns default value
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -0,0 +1,174 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultDeletionRule deletes the stacks of deleted workloads once their destroy has succeeded
+var defaultDeletionRule = model.StackDeletionRule{
+	Name:   "auto-delete",
+	Tags:   map[string]string{"idp:auto-delete": "true"},
+	Action: model.DeletionActionDelete,
+}
+
+// LoadDeletionCriteria reads the deletion rules from the configured rules file. Without one,
+// only the stacks of deleted workloads are cleaned up. The default rule comes first either way.
+func LoadDeletionCriteria(cfg *config.Config) (model.StackDeletionCriteria, error) {
+	criteria := model.StackDeletionCriteria{
+		Rules: []model.StackDeletionRule{defaultDeletionRule},
+	}
+
+	if cfg.Cleanup.RulesFile != "" {
+		data, err := os.ReadFile(cfg.Cleanup.RulesFile)
+		if err != nil {
+			return criteria, fmt.Errorf("failed to read cleanup rules file: %w", err)
+		}
+		criteria = model.StackDeletionCriteria{}
+		if err := yaml.Unmarshal(data, &criteria); err != nil {
+			return criteria, fmt.Errorf("failed to parse cleanup rules file: %w", err)
+		}
+	}
+	criteria.DryRun = criteria.DryRun || cfg.Cleanup.DryRun
+
+	if err := ValidateDeletionCriteria(criteria); err != nil {
+		return criteria, err
+	}
+	return withDefaultRule(criteria), nil
+}
+
+// withDefaultRule puts the default rule before the other rules, so that the stacks of deleted
+// workloads are always deleted and never only handled by another rule
+func withDefaultRule(criteria model.StackDeletionCriteria) model.StackDeletionCriteria {
+	rules := []model.StackDeletionRule{defaultDeletionRule}
+	for _, rule := range criteria.Rules {
+		if rule.Name != defaultDeletionRule.Name {
+			rules = append(rules, rule)
+		}
+	}
+	criteria.Rules = rules
+	return criteria
+}
+
+// isDefaultRule reports whether a rule does exactly what the default rule does
+func isDefaultRule(rule model.StackDeletionRule) bool {
+	return rule.Action == defaultDeletionRule.Action && maps.Equal(rule.Tags, defaultDeletionRule.Tags) &&
+		len(rule.Projects) == 0 && rule.OlderThan == "" && !rule.Empty
+}
+
+// ValidateDeletionCriteria checks that every rule has a name, a known action and at least one
+// condition. A rule named like the default rule has to be the default rule.
+func ValidateDeletionCriteria(criteria model.StackDeletionCriteria) error {
+	names := make(map[string]bool)
+	for i, rule := range criteria.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("%w: rule %d has no name", ErrInvalidCleanupSettings, i+1)
+		}
+		if rule.Name == defaultDeletionRule.Name && !isDefaultRule(rule) {
+			return fmt.Errorf("%w: rule %s is reserved for deleting the stacks tagged idp:auto-delete=true",
+				ErrInvalidCleanupSettings, rule.Name)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("%w: rule %s is defined twice", ErrInvalidCleanupSettings, rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Action {
+		case model.DeletionActionDestroy, model.DeletionActionDelete, model.DeletionActionNotify:
+		default:
+			return fmt.Errorf("%w: rule %s has unknown action %q", ErrInvalidCleanupSettings, rule.Name, rule.Action)
+		}
+
+		// A rule without conditions would match every stack of the organization
+		if len(rule.Tags) == 0 && len(rule.Projects) == 0 && rule.OlderThan == "" && !rule.Empty {
+			return fmt.Errorf("%w: rule %s has no conditions", ErrInvalidCleanupSettings, rule.Name)
+		}
+		if rule.OlderThan != "" {
+			age, err := time.ParseDuration(rule.OlderThan)
+			if err != nil || age <= 0 {
+				return fmt.Errorf("%w: rule %s has an invalid olderThan %q", ErrInvalidCleanupSettings, rule.Name, rule.OlderThan)
+			}
+		}
+	}
+	return nil
+}
+
+// findStacks lists the stacks of the organization that match all conditions of a rule.
+// The first tag and a single project are filtered by the Pulumi API, the rest here.
+func (s *StackCleanupService) findStacks(ctx context.Context, rule model.StackDeletionRule) ([]model.Stack, error) {
+	options := &model.ListStacksOptions{
+		Organization: s.cfg.Pulumi.Organization,
+	}
+
+	tagNames := make([]string, 0, len(rule.Tags))
+	for name := range rule.Tags {
+		tagNames = append(tagNames, name)
+	}
+	sort.Strings(tagNames)
+	if len(tagNames) > 0 {
+		options.TagName = tagNames[0]
+		options.TagValue = rule.Tags[tagNames[0]]
+	}
+	if len(rule.Projects) == 1 {
+		options.Project = rule.Projects[0]
+	}
+
+	var updatedBefore time.Time
+	if rule.OlderThan != "" {
+		// The rules were validated when they were loaded
+		age, _ := time.ParseDuration(rule.OlderThan)
+		updatedBefore = time.Now().Add(-age)
+	}
+
+	var stacks []model.Stack
+	for stack, err := range s.pulumiService.Stacks(ctx, options) {
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rule.Projects) > 0 && !slices.Contains(rule.Projects, stack.ProjectName) {
+			continue
+		}
+		if rule.Empty && stack.ResourceCount > 0 {
+			continue
+		}
+		// Stacks that were never updated have no age to compare
+		if rule.OlderThan != "" && (stack.LastUpdate == 0 || !time.Unix(stack.LastUpdate, 0).Before(updatedBefore)) {
+			continue
+		}
+		if len(tagNames) > 1 {
+			matches, err := s.matchesTags(ctx, stack, rule.Tags)
+			if err != nil {
+				return nil, err
+			}
+			if !matches {
+				continue
+			}
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks, nil
+}
+
+// matchesTags checks all tags of a rule against the tags of a stack, which the stack list does not include
+func (s *StackCleanupService) matchesTags(ctx context.Context, stack model.Stack, tags map[string]string) (bool, error) {
+	current, err := s.pulumiService.GetStack(ctx, stack.ProjectName, stack.StackName)
+	if err != nil {
+		return false, err
+	}
+	for name, value := range tags {
+		if current.Tags[name] != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+)
+
+func loadRules(t *testing.T, rules string) error {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Cleanup.RulesFile = filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(cfg.Cleanup.RulesFile, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	criteria, err := LoadDeletionCriteria(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(criteria.Rules) == 0 || criteria.Rules[0].Name != defaultDeletionRule.Name {
+		t.Fatalf("the default rule does not come first: %+v", criteria.Rules)
+	}
+	for _, rule := range criteria.Rules[1:] {
+		if rule.Name == defaultDeletionRule.Name {
+			t.Fatalf("the default rule is listed twice: %+v", criteria.Rules)
+		}
+	}
+	return nil
+}
+
+func TestLoadDeletionCriteriaKeepsDefaultRule(t *testing.T) {
+	rules := map[string]string{
+		"without default rule": `
+rules:
+  - name: old-previews
+    tags: {idp:preview: "true"}
+    olderThan: 24h
+    action: notify
+`,
+		"with default rule": `
+rules:
+  - name: old-previews
+    tags: {idp:preview: "true"}
+    action: notify
+  - name: auto-delete
+    tags: {idp:auto-delete: "true"}
+    action: delete
+`,
+	}
+	for name, rules := range rules {
+		t.Run(name, func(t *testing.T) {
+			if err := loadRules(t, rules); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLoadDeletionCriteriaRejectsChangedDefaultRule(t *testing.T) {
+	err := loadRules(t, `
+rules:
+  - name: auto-delete
+    tags: {idp:auto-delete: "true"}
+    action: notify
+`)
+	if !errors.Is(err, ErrInvalidCleanupSettings) {
+		t.Fatalf("expected ErrInvalidCleanupSettings, got %v", err)
+	}
+}
+
+func TestScheduleInterval(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"1m":           time.Minute,
+		"90s":          90 * time.Second,
+		"*/15 * * * *": 15 * time.Minute,
+		"0 3 * * *":    24 * time.Hour,
+	}
+	for schedule, want := range tests {
+		interval, err := scheduleInterval(schedule, now)
+		if err != nil || interval != want {
+			t.Errorf("scheduleInterval(%q) = %s, %v, want %s", schedule, interval, err, want)
+		}
+	}
+}
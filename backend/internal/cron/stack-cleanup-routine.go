@@ -2,230 +2,819 @@ package cleanup
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/pulumi-idp/internal/config"
 	"github.com/pulumi-idp/internal/model"
-	"io"
+	"github.com/pulumi-idp/internal/repository"
+	"github.com/pulumi-idp/internal/service"
 	"log"
-	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-co-op/gocron"
+	"github.com/robfig/cron/v3"
 )
 
+// ErrCleanupInProgress is returned when a cleanup run is started while another one is going on
+var ErrCleanupInProgress = errors.New("a cleanup run is already in progress")
+
+// ErrNotLeader is returned when a cleanup run is started on a replica that is not the leader
+var ErrNotLeader = errors.New("only the leader replica runs the cleanup")
+
+// ErrInvalidCleanupSettings is returned for a schedule or deletion criteria that cannot be applied
+var ErrInvalidCleanupSettings = errors.New("invalid cleanup settings")
+
 // StackCleanupService handles scheduled deletion of stacks
 type StackCleanupService struct {
-	HTTPClient       *http.Client
-	scheduler        *gocron.Scheduler
-	isRunning        bool
+	scheduler     *gocron.Scheduler
+	job           *gocron.Job
+	schedule      string
+	isRunning     bool
+	leader        bool
+	paused        bool
+	runInProgress bool
+	// inFlight tracks the current run, so that shutdown can wait for it
+	inFlight sync.WaitGroup
+	// ctx is the parent of the run contexts, it is cancelled when shutdown stops waiting
+	ctx              context.Context
+	cancel           context.CancelFunc
 	mutex            sync.Mutex
 	lastRunTime      time.Time
-	deletionCriteria StackDeletionCriteria
+	deletionCriteria model.StackDeletionCriteria
 	logger           *log.Logger
 	cfg              *config.Config
-}
-
-type DeletionTags struct {
-	Key   string
-	Value string
-}
-
-// StackDeletionCriteria defines rules for which stacks should be deleted
-type StackDeletionCriteria struct {
-	DeletionTags DeletionTags
+	workloads        *repository.WorkloadRepository
+	runs             *repository.CleanupRunRepository
+	// state holds the settings changed at runtime and the notified stacks, shared by the replicas
+	state *repository.CleanupStateRepository
+	// defaultCriteria are the configured deletion criteria, used until admins replace them
+	defaultCriteria model.StackDeletionCriteria
+	pulumiService   *service.PulumiService
+	workloadService *service.WorkloadService
+	notifications   *service.NotificationService
+	auditService    *service.AuditService
 }
 
 // NewStackCleanupService creates a new stack cleanup service
-func NewStackCleanupService(cfg *config.Config, criteria StackDeletionCriteria, logger *log.Logger) *StackCleanupService {
+func NewStackCleanupService(cfg *config.Config, repos *repository.Repository, criteria model.StackDeletionCriteria, logger *log.Logger) *StackCleanupService {
 	if logger == nil {
 		logger = log.New(log.Writer(), "[StackCleanup] ", log.LstdFlags)
 	}
 
 	// Create a new scheduler that runs in its own goroutine
 	scheduler := gocron.NewScheduler(time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
 
 	return &StackCleanupService{
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
 		cfg:              cfg,
+		workloads:        repos.Workload,
+		runs:             repos.Cleanup,
+		state:            repos.CleanupState,
 		scheduler:        scheduler,
+		schedule:         cfg.Cleanup.Schedule,
 		isRunning:        false,
 		deletionCriteria: criteria,
+		defaultCriteria:  criteria,
 		logger:           logger,
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 }
 
+func (s *StackCleanupService) SetPulumiService(service *service.PulumiService) {
+	s.pulumiService = service
+}
+
+func (s *StackCleanupService) SetWorkloadService(service *service.WorkloadService) {
+	s.workloadService = service
+}
+
+func (s *StackCleanupService) SetNotificationService(service *service.NotificationService) {
+	s.notifications = service
+}
+
+func (s *StackCleanupService) SetAuditService(service *service.AuditService) {
+	s.auditService = service
+}
+
 // Start begins the stack cleanup routine
 func (s *StackCleanupService) Start() error {
+	settings, err := s.settings(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read stack cleanup settings: %w", err)
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	if s.isRunning {
 		return fmt.Errorf("stack cleanup service is already running")
 	}
+	// Without a job yet, this only takes over the settings changed at runtime
+	if _, err := s.applySettings(settings); err != nil {
+		return err
+	}
 
-	// Schedule the cleanup to run every minute
-	_, err := s.scheduler.Every(1).Minute().Do(s.runCleanup)
+	job, err := s.scheduleJob(s.schedule, false)
 	if err != nil {
 		return fmt.Errorf("failed to schedule stack cleanup: %w", err)
 	}
+	s.job = job
 
 	// Start the scheduler
 	s.scheduler.StartAsync()
 	s.isRunning = true
-	s.logger.Println("Stack cleanup service started")
+	s.logger.Printf("Stack cleanup service started with schedule %s", s.schedule)
 
 	return nil
 }
 
+// scheduleJob adds the cleanup job to the scheduler. The schedule is either a Go duration
+// or a cron expression. Jobs added to a running scheduler wait for their first scheduled run.
+func (s *StackCleanupService) scheduleJob(schedule string, waitForSchedule bool) (*gocron.Job, error) {
+	if interval, err := time.ParseDuration(schedule); err == nil {
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval %s is shorter than a second", schedule)
+		}
+		s.scheduler.Every(interval)
+	} else {
+		s.scheduler.Cron(schedule)
+	}
+
+	if waitForSchedule {
+		s.scheduler.WaitForSchedule()
+	}
+	return s.scheduler.Do(s.runScheduledCleanup)
+}
+
 // Stop stops the stack cleanup routine
 func (s *StackCleanupService) Stop() {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	running := s.isRunning
+	s.isRunning = false
+	s.mutex.Unlock()
 
-	if s.isRunning {
+	// The scheduler waits for a scheduled run, which needs the mutex to finish
+	if running {
 		s.scheduler.Stop()
-		s.isRunning = false
 		s.logger.Println("Stack cleanup service stopped")
 	}
 }
 
-// TriggerCleanup manually triggers the cleanup process
-func (s *StackCleanupService) TriggerCleanup() {
-	s.logger.Println("Manual cleanup triggered")
-	go s.runCleanup()
+// SetLeader starts or stops the runs on this replica. Only the leader replica runs the cleanup,
+// so that no two replicas delete the same stacks.
+func (s *StackCleanupService) SetLeader(leader bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.leader = leader
+}
+
+// Shutdown stops the scheduler and waits for the current run to finish or the context to end,
+// then cancels the run
+func (s *StackCleanupService) Shutdown(ctx context.Context) error {
+	s.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// The cancelled run stops at the next stack and saves what it has done so far
+	s.cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+	}
+	return fmt.Errorf("cleanup run cancelled: %w", ctx.Err())
 }
 
-// runCleanup performs the actual stack cleanup process
-func (s *StackCleanupService) runCleanup() {
+// Pause skips the scheduled runs until the cleanup is resumed. Manual runs are still possible.
+func (s *StackCleanupService) Pause(ctx context.Context) (*model.CleanupStatus, error) {
+	status, err := s.changeSettings(ctx, model.AuditActionCleanupPause, func(settings *model.CleanupSettings) {
+		settings.Paused = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Println("Stack cleanup paused")
+	return status, nil
+}
+
+// Resume continues the scheduled runs of a paused cleanup
+func (s *StackCleanupService) Resume(ctx context.Context) (*model.CleanupStatus, error) {
+	status, err := s.changeSettings(ctx, model.AuditActionCleanupResume, func(settings *model.CleanupSettings) {
+		settings.Paused = false
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Println("Stack cleanup resumed")
+	return status, nil
+}
+
+// UpdateSettings changes the schedule or the deletion criteria at runtime
+func (s *StackCleanupService) UpdateSettings(ctx context.Context, req *model.CleanupSettingsRequest) (*model.CleanupStatus, error) {
+	if req.Criteria != nil {
+		if err := ValidateDeletionCriteria(*req.Criteria); err != nil {
+			return nil, err
+		}
+	}
+	if req.Schedule != nil {
+		if err := validateSchedule(*req.Schedule); err != nil {
+			return nil, fmt.Errorf("%w: schedule %q: %v", ErrInvalidCleanupSettings, *req.Schedule, err)
+		}
+	}
+
+	status, err := s.changeSettings(ctx, model.AuditActionCleanupUpdate, func(settings *model.CleanupSettings) {
+		if req.Schedule != nil {
+			schedule := *req.Schedule
+			settings.Schedule = &schedule
+		}
+		if req.Criteria != nil {
+			criteria := withDefaultRule(*req.Criteria)
+			settings.Criteria = &criteria
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Stack cleanup settings changed to schedule %s with %d deletion rules, dry run %t",
+		status.Schedule, len(status.Criteria.Rules), status.Criteria.DryRun)
+	return status, nil
+}
+
+// changeSettings stores a change of the settings for all replicas and applies it on this one.
+// The other replicas apply it when they read the settings, the leader before its next run.
+func (s *StackCleanupService) changeSettings(ctx context.Context, action string, change func(settings *model.CleanupSettings)) (*model.CleanupStatus, error) {
+	settings, err := s.settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	before := s.settingsSnapshot(settings)
+	change(settings)
+	after := s.settingsSnapshot(settings)
+
+	if err := s.state.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
 	s.mutex.Lock()
-	s.lastRunTime = time.Now()
+	_, err = s.applySettings(settings)
 	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
 
-	s.logger.Println("Starting scheduled stack cleanup")
+	s.audit(ctx, action, before, after)
+	return s.Status(ctx), nil
+}
 
-	// Create a context with timeout for the operation
-	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Second)
-	defer cancel()
+// settings returns the stored settings, or empty ones if they were never changed at runtime
+func (s *StackCleanupService) settings(ctx context.Context) (*model.CleanupSettings, error) {
+	settings, err := s.state.Settings(ctx)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &model.CleanupSettings{}, nil
+	}
+	return settings, err
+}
 
-	// Get stacks that meet deletion criteria
-	stacksToDelete, err := s.findStacksToDelete()
+// syncSettings applies the stored settings, which any replica may have changed.
+// It reports whether the schedule changed.
+func (s *StackCleanupService) syncSettings(ctx context.Context) (bool, error) {
+	settings, err := s.settings(ctx)
 	if err != nil {
-		s.logger.Printf("Error finding stacks to delete: %v", err)
-		return
+		return false, err
 	}
 
-	s.logger.Printf("Found %d stacks to delete", len(stacksToDelete.Stacks))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.applySettings(settings)
+}
 
-	// Delete each stack
-	for _, stack := range stacksToDelete.Stacks {
-		// Check if our context is still valid before proceeding
-		if ctx.Err() != nil {
-			s.logger.Println("Cleanup operation timed out, will continue in next run")
-			return
+// applySettings makes the settings effective on this replica and reschedules the job if the
+// schedule changed. The caller holds the mutex.
+func (s *StackCleanupService) applySettings(settings *model.CleanupSettings) (bool, error) {
+	schedule, criteria := s.effectiveSettings(settings)
+	s.paused = settings.Paused
+	s.deletionCriteria = criteria
+	if schedule == s.schedule {
+		return false, nil
+	}
+
+	// Before the start, the job is scheduled with the schedule taken over here
+	if s.job != nil {
+		job, err := s.scheduleJob(schedule, true)
+		if err != nil {
+			return false, fmt.Errorf("%w: schedule %q: %v", ErrInvalidCleanupSettings, schedule, err)
 		}
+		s.scheduler.RemoveByReference(s.job)
+		s.job = job
+	}
+	s.schedule = schedule
+	return true, nil
+}
 
-		fullStackName := fmt.Sprintf("%s/%s/%s", stack.OrgName, stack.ProjectName, stack.StackName)
-		s.logger.Printf("Deleting stack: %s", fullStackName)
+// effectiveSettings returns the schedule and deletion criteria of the settings, falling back to
+// the configured ones
+func (s *StackCleanupService) effectiveSettings(settings *model.CleanupSettings) (string, model.StackDeletionCriteria) {
+	schedule := s.cfg.Cleanup.Schedule
+	if settings.Schedule != nil {
+		schedule = *settings.Schedule
+	}
+	criteria := s.defaultCriteria
+	if settings.Criteria != nil {
+		criteria = withDefaultRule(*settings.Criteria)
+	}
+	return schedule, criteria
+}
 
-		if err := s.deleteStack(ctx, stack.OrgName, stack.ProjectName, stack.StackName); err != nil {
-			s.logger.Printf("Failed to delete stack %s: %v", fullStackName, err)
-			continue
-		}
+// settingsSnapshot describes the settings for the audit log
+func (s *StackCleanupService) settingsSnapshot(settings *model.CleanupSettings) map[string]interface{} {
+	schedule, criteria := s.effectiveSettings(settings)
+	return map[string]interface{}{
+		"schedule": schedule,
+		"paused":   settings.Paused,
+		"rules":    criteria.Rules,
+		"dryRun":   criteria.DryRun,
 	}
+}
 
-	s.logger.Println("Scheduled stack cleanup completed")
+func (s *StackCleanupService) audit(ctx context.Context, action string, before, after map[string]interface{}) {
+	if s.auditService == nil {
+		return
+	}
+	event := &model.AuditEvent{
+		Action:       action,
+		Organization: s.cfg.Pulumi.Organization,
+		Outcome:      model.JobStatusSucceeded,
+		Before:       before,
+		After:        after,
+	}
+	if user := service.UserFromContext(ctx); user != nil {
+		event.Actor = user.Login
+	}
+	s.auditService.Record(ctx, event)
 }
 
-// Stack represents the metadata of a stack
-type Stack struct {
-	OrgName     string
-	ProjectName string
-	StackName   string
-	LastUpdate  time.Time
-	Tags        map[string]string
+// Status returns the state of the scheduler and the latest run
+func (s *StackCleanupService) Status(ctx context.Context) *model.CleanupStatus {
+	if _, err := s.syncSettings(ctx); err != nil {
+		s.logger.Printf("Failed to read the cleanup settings: %v", err)
+	}
+
+	s.mutex.Lock()
+	status := &model.CleanupStatus{
+		Replica:       s.cfg.Leader.ID,
+		Leader:        s.leader,
+		Running:       s.isRunning,
+		Paused:        s.paused,
+		Schedule:      s.schedule,
+		Criteria:      s.deletionCriteria,
+		RunInProgress: s.runInProgress,
+	}
+	if !s.lastRunTime.IsZero() {
+		lastRun := s.lastRunTime
+		status.LastRunAt = &lastRun
+	}
+	if s.isRunning && s.leader && !s.paused && s.job != nil {
+		nextRun := s.job.NextRun()
+		status.NextRunAt = &nextRun
+	}
+	s.mutex.Unlock()
+
+	run, err := s.runs.Latest(ctx)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.logger.Printf("Failed to read the latest cleanup run: %v", err)
+	}
+	status.LastRun = run
+	return status
 }
 
-// findStacksToDelete identifies stacks that should be deleted based on criteria
-func (s *StackCleanupService) findStacksToDelete() (*model.ListStacksResponse, error) {
-	url := fmt.Sprintf("%s/user/stacks", s.cfg.Pulumi.APIBaseURL)
+// Runs returns the history of cleanup runs, newest first
+func (s *StackCleanupService) Runs(ctx context.Context, limit, offset int) ([]model.CleanupRun, error) {
+	runs, err := s.runs.List(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []model.CleanupRun{}
+	}
+	return runs, nil
+}
+
+// Run returns a single cleanup run
+func (s *StackCleanupService) Run(ctx context.Context, id uint) (*model.CleanupRun, error) {
+	return s.runs.FindByID(ctx, id)
+}
 
-	params := make([]string, 0)
+// TriggerCleanup manually triggers the cleanup process. The run continues in the background.
+func (s *StackCleanupService) TriggerCleanup(ctx context.Context) (*model.CleanupRun, error) {
+	s.logger.Println("Manual cleanup triggered")
 
-	params = append(params, fmt.Sprintf("organization=%s", s.cfg.Pulumi.Organization))
+	user := service.UserFromContext(ctx)
+	triggeredBy := ""
+	if user != nil {
+		triggeredBy = user.Login
+	}
 
-	params = append(params, fmt.Sprintf("tagName=%s", s.deletionCriteria.DeletionTags.Key))
+	if _, err := s.syncSettings(ctx); err != nil {
+		return nil, err
+	}
+	run, criteria, err := s.startRun(ctx, model.CleanupTriggerManual, triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, model.AuditActionCleanupTrigger, nil, map[string]interface{}{
+		"run": run.ID,
+	})
 
-	params = append(params, fmt.Sprintf("tagValue=%s", s.deletionCriteria.DeletionTags.Value))
+	// The run outlives the request that triggered it
+	go s.runCleanup(run, criteria)
+	return run, nil
+}
 
-	if len(params) > 0 {
-		url += "?" + strings.Join(params, "&")
+// runScheduledCleanup is the job run by the scheduler
+func (s *StackCleanupService) runScheduledCleanup() {
+	s.mutex.Lock()
+	leader := s.leader
+	s.mutex.Unlock()
+	if !leader {
+		return
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	// Settings changed through another replica apply from this run on
+	changed, err := s.syncSettings(s.ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		s.logger.Printf("Failed to read the cleanup settings, skipping scheduled run: %v", err)
+		return
+	}
+	s.mutex.Lock()
+	paused := s.paused
+	schedule := s.schedule
+	s.mutex.Unlock()
+	if changed {
+		s.logger.Printf("Stack cleanup schedule changed to %s, skipping the run due under the previous schedule", schedule)
+		return
+	}
+	if paused {
+		s.logger.Println("Stack cleanup is paused, skipping scheduled run")
+		return
+	}
+
+	run, criteria, err := s.startRun(s.ctx, model.CleanupTriggerSchedule, "")
+	if errors.Is(err, ErrCleanupInProgress) {
+		s.logger.Println("Previous cleanup run still in progress, skipping scheduled run")
+		return
+	} else if err != nil {
+		s.logger.Printf("Failed to start cleanup run: %v", err)
+		return
+	}
+	s.runCleanup(run, criteria)
+}
+
+// startRun records a new cleanup run unless another one is in progress
+func (s *StackCleanupService) startRun(ctx context.Context, trigger, triggeredBy string) (*model.CleanupRun, model.StackDeletionCriteria, error) {
+	s.mutex.Lock()
+	if !s.isRunning || !s.leader {
+		s.mutex.Unlock()
+		return nil, model.StackDeletionCriteria{}, ErrNotLeader
+	}
+	if s.runInProgress {
+		s.mutex.Unlock()
+		return nil, model.StackDeletionCriteria{}, ErrCleanupInProgress
+	}
+	s.runInProgress = true
+	s.inFlight.Add(1)
+	s.lastRunTime = time.Now()
+	criteria := s.deletionCriteria
+	run := &model.CleanupRun{
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      model.CleanupRunStatusRunning,
+		DryRun:      criteria.DryRun,
+		Stacks:      []model.CleanupStackResult{},
+		StartedAt:   s.lastRunTime,
 	}
+	s.mutex.Unlock()
 
-	// Add the necessary headers
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "token "+s.cfg.Pulumi.APIToken)
+	if err := s.runs.Create(ctx, run); err != nil {
+		s.finishRun()
+		return nil, criteria, err
+	}
+	return run, criteria, nil
+}
 
-	// Execute the request
-	resp, err := s.HTTPClient.Do(req)
+func (s *StackCleanupService) finishRun() {
+	s.mutex.Lock()
+	s.runInProgress = false
+	s.mutex.Unlock()
+	s.inFlight.Done()
+}
+
+// runTimeout is how long a run may take: CLEANUP_RUN_TIMEOUT, or by default until a twelfth of
+// the schedule's interval before the next run is due, 55 seconds for a run every minute
+func (s *StackCleanupService) runTimeout(schedule string) time.Duration {
+	if s.cfg.Cleanup.RunTimeout > 0 {
+		return s.cfg.Cleanup.RunTimeout
+	}
+
+	interval, err := scheduleInterval(schedule, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
+		// The schedule was checked when it was set
+		s.logger.Printf("Failed to read the interval of schedule %s: %v", schedule, err)
+		return time.Minute
 	}
-	defer resp.Body.Close()
+	return interval - interval/12
+}
 
-	// Check for successful response
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list stacks: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
+// validateSchedule checks that a schedule can be applied, like scheduleJob reads it
+func validateSchedule(schedule string) error {
+	if interval, err := time.ParseDuration(schedule); err == nil && interval < time.Second {
+		return fmt.Errorf("interval %s is shorter than a second", schedule)
 	}
+	_, err := scheduleInterval(schedule, time.Now())
+	return err
+}
 
-	// Parse the response
-	var listResp model.ListStacksResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+// scheduleInterval returns the time between the next two runs of a schedule
+func scheduleInterval(schedule string, now time.Time) (time.Duration, error) {
+	if interval, err := time.ParseDuration(schedule); err == nil {
+		return interval, nil
 	}
 
-	return &listResp, nil
+	// Cron schedules are read like the scheduler reads them
+	cronSchedule, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return 0, err
+	}
+	next := cronSchedule.Next(now)
+	return cronSchedule.Next(next).Sub(next), nil
 }
 
-// deleteStack deletes a stack
-func (s *StackCleanupService) deleteStack(ctx context.Context, organization, project, stack string) error {
-	if organization == "" || project == "" || stack == "" {
-		return fmt.Errorf("organization, project, and stack are required")
+// runCleanup performs the actual stack cleanup process and records its results on the run
+func (s *StackCleanupService) runCleanup(run *model.CleanupRun, criteria model.StackDeletionCriteria) {
+	defer s.finishRun()
+
+	s.logger.Printf("Starting %s stack cleanup", run.Trigger)
+
+	s.mutex.Lock()
+	timeout := s.runTimeout(s.schedule)
+	s.mutex.Unlock()
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		finishedAt := time.Now()
+		run.FinishedAt = &finishedAt
+		if run.Error != "" {
+			run.Status = model.CleanupRunStatusFailed
+		} else {
+			run.Status = model.CleanupRunStatusSucceeded
+		}
+		// The run context may have timed out, the results are saved regardless
+		if err := s.runs.Save(context.Background(), run); err != nil {
+			s.logger.Printf("Failed to save cleanup run %d: %v", run.ID, err)
+		}
+	}()
+
+	if s.workloadService == nil || s.pulumiService == nil {
+		run.Error = "no workload or Pulumi service set"
+		s.logger.Println("No workload or Pulumi service set, skipping cleanup")
+		return
 	}
 
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s", s.cfg.Pulumi.APIBaseURL, organization, project, stack)
+	// Expired workloads are destroyed first, their stacks are deleted by a later run
+	if criteria.DryRun {
+		s.logger.Println("Dry run, expired workloads are not destroyed")
+	} else {
+		s.expireWorkloads(ctx)
+	}
 
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	// The owners of stacks matched by notify rules are notified once, by whichever replica leads
+	notified, err := s.state.Notified(ctx)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		run.Error = err.Error()
+		s.logger.Printf("Error reading the notified stacks: %v", err)
+		return
 	}
 
-	// Add the necessary headers
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "token "+s.cfg.Pulumi.APIToken)
+	// A stack is only handled by the first rule it matches
+	handled := make(map[string]bool)
+	matched := make(map[string]bool)
+	for _, rule := range criteria.Rules {
+		if ctx.Err() != nil {
+			run.Error = "cleanup timed out, will continue in next run"
+			s.logger.Println("Cleanup operation timed out, will continue in next run")
+			return
+		}
+
+		stacks, err := s.findStacks(ctx, rule)
+		if err != nil {
+			run.Error = fmt.Sprintf("rule %s: %v", rule.Name, err)
+			s.logger.Printf("Error finding stacks for rule %s: %v", rule.Name, err)
+			return
+		}
+		s.logger.Printf("Rule %s matched %d stacks", rule.Name, len(stacks))
+
+		for _, stack := range stacks {
+			fullStackName := fmt.Sprintf("%s/%s/%s", stack.OrgName, stack.ProjectName, stack.StackName)
+			if handled[fullStackName] {
+				continue
+			}
+			handled[fullStackName] = true
+			run.StacksFound++
+
+			// Check if our context is still valid before proceeding
+			if ctx.Err() != nil {
+				run.Error = "cleanup timed out, will continue in next run"
+				s.logger.Println("Cleanup operation timed out, will continue in next run")
+				return
+			}
+
+			result := model.CleanupStackResult{
+				Stack:  fullStackName,
+				Rule:   rule.Name,
+				Action: rule.Action,
+			}
+			if criteria.DryRun {
+				result.Outcome = model.CleanupOutcomeDryRun
+				s.logger.Printf("Dry run, rule %s would %s stack %s", rule.Name, rule.Action, fullStackName)
+			} else {
+				s.applyRule(ctx, run, rule, stack, &result, notified, matched)
+			}
+			run.Stacks = append(run.Stacks, result)
+		}
+	}
 
-	resp, err := s.HTTPClient.Do(req)
+	// Stacks that no longer match a notify rule are notified again when they match once more
+	if !criteria.DryRun {
+		keys := make([]string, 0, len(matched))
+		for key := range matched {
+			keys = append(keys, key)
+		}
+		if err := s.state.KeepNotified(context.Background(), keys); err != nil {
+			s.logger.Printf("Failed to forget the notified stacks that no longer match: %v", err)
+		}
+	}
+
+	s.logger.Printf("Stack cleanup completed: %d found, %d destroyed, %d deleted, %d retried, %d notified, %d failed",
+		run.StacksFound, run.StacksDestroyed, run.StacksDeleted, run.StacksRetried, run.StacksNotified, run.StacksFailed)
+}
+
+// applyRule applies the action of a rule to a matching stack and records the outcome
+func (s *StackCleanupService) applyRule(ctx context.Context, run *model.CleanupRun, rule model.StackDeletionRule, stack model.Stack, result *model.CleanupStackResult, notified, matched map[string]bool) {
+	switch rule.Action {
+	case model.DeletionActionDestroy:
+		queued, err := s.workloadService.DestroyStack(ctx, stack.OrgName, stack.ProjectName, stack.StackName)
+		if err != nil {
+			result.Error = err.Error()
+			run.StacksFailed++
+			s.logger.Printf("Failed to destroy stack %s: %v", result.Stack, err)
+			return
+		}
+		result.Outcome = model.CleanupOutcomeSkipped
+		if queued {
+			result.Outcome = model.CleanupOutcomeDestroyQueued
+			run.StacksDestroyed++
+			s.logger.Printf("Queued destroy of stack %s matched by rule %s", result.Stack, rule.Name)
+		}
+
+	case model.DeletionActionDelete:
+		outcome, record, err := s.workloadService.CleanupDeletedWorkload(ctx, stack.OrgName, stack.ProjectName, stack.StackName)
+		result.Outcome = outcome
+		if err != nil {
+			result.Error = err.Error()
+			run.StacksFailed++
+			s.logger.Printf("Failed to clean up stack %s: %v", result.Stack, err)
+			return
+		}
+
+		switch outcome {
+		case model.DestroyOutcomeDeleted:
+			run.StacksDeleted++
+			s.logger.Printf("Deleted stack: %s", result.Stack)
+		case model.DestroyOutcomeRetried:
+			run.StacksRetried++
+			s.logger.Printf("Retried destroy of stack: %s", result.Stack)
+		case model.DestroyOutcomeFailed:
+			run.StacksFailed++
+			s.logger.Printf("Destroy of stack %s failed for good, marked %s", result.Stack, model.WorkloadStatusDestroyFailed)
+			s.notifyDestroyFailed(ctx, stack, record)
+		}
+
+	case model.DeletionActionNotify:
+		key := rule.Name + "/" + result.Stack
+		matched[key] = true
+		result.Outcome = model.CleanupOutcomeSkipped
+		if notified[key] {
+			return
+		}
+
+		// Not marking the stack as notified retries the notification with the next run
+		if err := s.notifyCleanupMatched(ctx, rule, stack); err != nil {
+			result.Error = err.Error()
+			run.StacksFailed++
+			s.logger.Printf("Failed to notify about stack %s: %v", result.Stack, err)
+			return
+		}
+		if err := s.state.MarkNotified(ctx, key); err != nil {
+			s.logger.Printf("Failed to record the notification about stack %s: %v", result.Stack, err)
+		}
+		result.Outcome = model.CleanupOutcomeNotified
+		run.StacksNotified++
+	}
+}
+
+// expireWorkloads destroys the workloads whose expiry has passed and warns the owners
+// of the workloads that expire within the warning period
+func (s *StackCleanupService) expireWorkloads(ctx context.Context) {
+	if s.workloadService == nil {
+		return
+	}
+
+	now := time.Now()
+	records, err := s.workloadService.ExpiringWorkloads(ctx, now.Add(s.cfg.Expiry.WarningPeriod))
 	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
+		s.logger.Printf("Error finding expiring workloads: %v", err)
+		return
+	}
+
+	for i := range records {
+		record := &records[i]
+		if ctx.Err() != nil {
+			s.logger.Println("Expiry check timed out, will continue in next run")
+			return
+		}
+
+		fullStackName := fmt.Sprintf("%s/%s/%s", record.Organization, record.Blueprint, record.Stack)
+		// Workloads with a running job are picked up once the job has finished
+		if record.Status == model.WorkloadStatusProvisioning || record.Status == model.WorkloadStatusUpdating {
+			continue
+		}
+
+		if !record.ExpiresAt.After(now) {
+			s.logger.Printf("Destroying expired workload: %s", fullStackName)
+			if err := s.workloadService.ExpireWorkload(ctx, record); err != nil {
+				s.logger.Printf("Failed to destroy expired workload %s: %v", fullStackName, err)
+				continue
+			}
+			s.notify(ctx, model.NotificationWorkloadExpired, record)
+			continue
+		}
+
+		if record.ExpiryNotifiedAt == nil {
+			s.notify(ctx, model.NotificationWorkloadExpiring, record)
+			if err := s.workloadService.MarkExpiryNotified(ctx, record); err != nil {
+				s.logger.Printf("Failed to record expiry warning of %s: %v", fullStackName, err)
+			}
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusNoContent {
-		s.logger.Printf("Failed to delete stack %s: HTTP %d, response: %s", stack, resp.StatusCode, resp.Status)
+func (s *StackCleanupService) notify(ctx context.Context, event string, record *model.WorkloadRecord) {
+	if s.notifications == nil {
+		return
 	}
-	return nil
+	if err := s.notifications.NotifyExpiry(ctx, event, record); err != nil {
+		s.logger.Printf("Failed to send %s notification for %s: %v", event, record.Stack, err)
+	}
+}
+
+func (s *StackCleanupService) notifyCleanupMatched(ctx context.Context, rule model.StackDeletionRule, stack model.Stack) error {
+	if s.notifications == nil {
+		return nil
+	}
+	record, err := s.workloads.FindByStack(ctx, stack.OrgName, stack.ProjectName, stack.StackName)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return s.notifications.NotifyCleanupMatched(ctx, rule.Name, stack.OrgName, stack.ProjectName, stack.StackName, record)
+}
+
+func (s *StackCleanupService) notifyDestroyFailed(ctx context.Context, stack model.Stack, record *model.WorkloadRecord) {
+	if s.notifications == nil {
+		return
+	}
+	if err := s.notifications.NotifyDestroyFailed(ctx, stack.OrgName, stack.ProjectName, stack.StackName, record); err != nil {
+		s.logger.Printf("Failed to send %s notification for %s: %v", model.NotificationWorkloadDestroyFailed, stack.StackName, err)
+	}
+}
+
+// Stack represents the metadata of a stack
+type Stack struct {
+	OrgName     string
+	ProjectName string
+	StackName   string
+	LastUpdate  time.Time
+	Tags        map[string]string
 }
 
 // GetLastRunTime returns the time of the last cleanup run
@@ -236,7 +825,7 @@ func (s *StackCleanupService) GetLastRunTime() time.Time {
 }
 
 // UpdateCriteria updates the deletion criteria
-func (s *StackCleanupService) UpdateCriteria(criteria StackDeletionCriteria) {
+func (s *StackCleanupService) UpdateCriteria(criteria model.StackDeletionCriteria) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.deletionCriteria = criteria
@@ -0,0 +1,147 @@
+package cleanup
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestReplicas creates cleanup services of two replicas sharing a database
+func newTestReplicas(t *testing.T) (*StackCleanupService, *StackCleanupService, *repository.Repository) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.CleanupRun{}, &model.CleanupSettings{}, &model.CleanupNotification{}); err != nil {
+		t.Fatal(err)
+	}
+	repos := repository.NewRepository(db)
+
+	newReplica := func(id string) *StackCleanupService {
+		cfg := &config.Config{}
+		// A cron schedule does not run right away when the scheduler starts
+		cfg.Cleanup.Schedule = "0 0 1 1 *"
+		cfg.Leader.ID = id
+		s := NewStackCleanupService(cfg, repos, withDefaultRule(model.StackDeletionCriteria{}), log.New(io.Discard, "", 0))
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Stop)
+		return s
+	}
+	return newReplica("a"), newReplica("b"), repos
+}
+
+func TestSettingsChangesReachOtherReplicas(t *testing.T) {
+	ctx := context.Background()
+	follower, leader, _ := newTestReplicas(t)
+	leader.SetLeader(true)
+
+	if _, err := follower.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	schedule := "1h"
+	criteria := model.StackDeletionCriteria{DryRun: true}
+	if _, err := follower.UpdateSettings(ctx, &model.CleanupSettingsRequest{Schedule: &schedule, Criteria: &criteria}); err != nil {
+		t.Fatal(err)
+	}
+
+	status := leader.Status(ctx)
+	if !status.Paused || status.Schedule != "1h" || !status.Criteria.DryRun {
+		t.Fatalf("the leader did not take over the settings: %+v", status)
+	}
+	if !reflect.DeepEqual(status.Criteria.Rules, []model.StackDeletionRule{defaultDeletionRule}) {
+		t.Fatalf("the default rule was not kept: %+v", status.Criteria.Rules)
+	}
+
+	// A restarted replica starts with the stored settings
+	restarted := NewStackCleanupService(leader.cfg, &repository.Repository{Cleanup: leader.runs, CleanupState: leader.state},
+		leader.defaultCriteria, log.New(io.Discard, "", 0))
+	if err := restarted.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Stop()
+	if restarted.schedule != "1h" || !restarted.paused {
+		t.Fatalf("the restarted replica runs with schedule %s, paused %t", restarted.schedule, restarted.paused)
+	}
+}
+
+func TestScheduledRunAppliesChangedSchedule(t *testing.T) {
+	ctx := context.Background()
+	follower, leader, _ := newTestReplicas(t)
+	leader.SetLeader(true)
+
+	schedule := "1h"
+	if _, err := follower.UpdateSettings(ctx, &model.CleanupSettingsRequest{Schedule: &schedule}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run due under the previous schedule is skipped, the next one follows the new schedule
+	leader.runScheduledCleanup()
+	if leader.schedule != "1h" {
+		t.Fatalf("the leader still runs with schedule %s", leader.schedule)
+	}
+	if run, err := leader.runs.Latest(ctx); err == nil {
+		t.Fatalf("a run was started: %+v", run)
+	}
+}
+
+func TestUpdateSettingsRejectsInvalidSchedule(t *testing.T) {
+	s, _, repos := newTestReplicas(t)
+
+	for _, schedule := range []string{"100ms", "not a schedule"} {
+		if _, err := s.UpdateSettings(context.Background(), &model.CleanupSettingsRequest{Schedule: &schedule}); err == nil {
+			t.Fatalf("schedule %q was accepted", schedule)
+		}
+	}
+	if _, err := repos.CleanupState.Settings(context.Background()); err == nil {
+		t.Fatal("invalid settings were stored")
+	}
+}
+
+func TestNotifiedStacksAreShared(t *testing.T) {
+	ctx := context.Background()
+	_, _, repos := newTestReplicas(t)
+	state := repos.CleanupState
+
+	for _, key := range []string{"stale/acme/web/dev", "stale/acme/api/dev", "stale/acme/api/dev"} {
+		if err := state.MarkNotified(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.KeepNotified(ctx, []string{"stale/acme/api/dev"}); err != nil {
+		t.Fatal(err)
+	}
+
+	notified, err := state.Notified(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(notified, map[string]bool{"stale/acme/api/dev": true}) {
+		t.Fatalf("notified = %v", notified)
+	}
+
+	if err := state.KeepNotified(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if notified, _ := state.Notified(ctx); len(notified) != 0 {
+		t.Fatalf("notified = %v", notified)
+	}
+}
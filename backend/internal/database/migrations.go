@@ -1,11 +1,24 @@
 package database
 
 import (
+	"github.com/pulumi-idp/internal/model"
 	"gorm.io/gorm"
 )
 
 // RunMigrations performs database migrations
 func RunMigrations(db *gorm.DB) error {
 	// Auto-migrate models
-	return db.AutoMigrate()
+	return db.AutoMigrate(
+		&model.WorkloadRecord{},
+		&model.ProvisioningJob{},
+		&model.ProvisioningStep{},
+		&model.AuditEvent{},
+		&model.ApprovalRequest{},
+		&model.CleanupRun{},
+		&model.CleanupSettings{},
+		&model.CleanupNotification{},
+		&model.Lease{},
+		&model.CacheEntry{},
+		&model.CacheGeneration{},
+	)
 }
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// Approval request statuses
+const (
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+	// A failed request was approved, but its workload could not be provisioned
+	ApprovalStatusFailed = "failed"
+)
+
+// Approval decisions
+const (
+	ApprovalDecisionApprove = "approve"
+	ApprovalDecisionReject  = "reject"
+)
+
+// ApprovalRequest is a workload create, or an update moving a workload to another stage, that
+// waits for approval before anything is provisioned. Project and Stack are only set for updates.
+// Request holds the request without secret values; those wait in an ESC environment.
+// RequestedBy and the approvers of the decisions are identities, like github:alice.
+type ApprovalRequest struct {
+	ID           string             `gorm:"primaryKey" json:"id"`
+	Organization string             `json:"organization"`
+	Project      string             `json:"project,omitempty"`
+	Stack        string             `json:"stack,omitempty"`
+	Blueprint    string             `gorm:"index" json:"blueprint"`
+	Name         string             `json:"name"`
+	Stage        string             `json:"stage"`
+	Team         string             `json:"team"`
+	Status       string             `gorm:"index" json:"status"`
+	RequestedBy  string             `gorm:"index" json:"requestedBy"`
+	Request      WorkloadRequest    `gorm:"serializer:json" json:"request"`
+	Decisions    []ApprovalDecision `gorm:"serializer:json" json:"decisions"`
+	// SecretsEnvironment is the ESC environment holding the request config, if it had secrets
+	SecretsEnvironment string `json:"-"`
+	// JobID is the provisioning job started once the request was approved
+	JobID string `json:"jobId,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Version is incremented with every decision, so that concurrent decisions cannot overwrite each other
+	Version   int       `gorm:"not null;default:0" json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ApprovalDecision is a single approver's decision on an approval request
+type ApprovalDecision struct {
+	Approver  string    `json:"approver"`
+	Decision  string    `json:"decision"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ApprovalDecisionRequest is the body of an approve or reject call
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Audited actions
+const (
+	AuditActionWorkloadCreate         = "workload.create"
+	AuditActionWorkloadUpdate         = "workload.update"
+	AuditActionWorkloadDelete         = "workload.delete"
+	AuditActionWorkloadCreatePreview  = "workload.create-preview"
+	AuditActionWorkloadUpdatePreview  = "workload.update-preview"
+	AuditActionWorkloadUpgradePreview = "workload.upgrade-preview"
+	AuditActionWorkloadExtend         = "workload.extend"
+	AuditActionApprovalSubmit         = "approval.submit"
+	AuditActionApprovalApprove        = "approval.approve"
+	AuditActionApprovalReject         = "approval.reject"
+	AuditActionCleanupTrigger         = "cleanup.trigger"
+	AuditActionCleanupPause           = "cleanup.pause"
+	AuditActionCleanupResume          = "cleanup.resume"
+	AuditActionCleanupUpdate          = "cleanup.update"
+	AuditActionBlueprintCachePurge    = "blueprint.cache-purge"
+)
+
+// AuditEvent is an append-only record of a mutating IDP action. Before and After are
+// snapshots of the workload without secret values, Changes is the difference between them.
+type AuditEvent struct {
+	ID           uint                   `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time              `gorm:"index" json:"createdAt"`
+	Actor        string                 `gorm:"index" json:"actor"`
+	Action       string                 `gorm:"index" json:"action"`
+	Organization string                 `gorm:"index:idx_audit_stack" json:"organization"`
+	Project      string                 `gorm:"index:idx_audit_stack" json:"project"`
+	Stack        string                 `gorm:"index:idx_audit_stack" json:"stack"`
+	Blueprint    string                 `gorm:"index" json:"blueprint"`
+	JobID        string                 `json:"jobId,omitempty"`
+	DeploymentID string                 `json:"deploymentId,omitempty"`
+	Outcome      string                 `json:"outcome"`
+	Error        string                 `json:"error,omitempty"`
+	Before       map[string]interface{} `gorm:"serializer:json" json:"before,omitempty"`
+	After        map[string]interface{} `gorm:"serializer:json" json:"after,omitempty"`
+	Changes      []AuditChange          `gorm:"serializer:json" json:"changes"`
+}
+
+// AuditChange is a single changed field of a workload, addressed by a dotted path such as config.replicas
+type AuditChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// AuditFilter narrows down an audit log listing. Since and Until bound the event time.
+type AuditFilter struct {
+	Actor        string
+	Action       string
+	Organization string
+	Project      string
+	Stack        string
+	Blueprint    string
+	Since        *time.Time
+	Until        *time.Time
+	Limit        int
+	Offset       int
+}
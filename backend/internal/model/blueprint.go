@@ -10,6 +10,43 @@ type PropertyOverride struct {
 	Items     []interface{} `yaml:"items" json:"items"`
 }
 
+// ConfigProperty is a typed entry of the template.config section of a blueprint's Pulumi.yaml
+type ConfigProperty struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	HasDefault  bool        `json:"-"`
+	Required    bool        `json:"required"`
+	Secret      bool        `json:"secret,omitempty"`
+
+	Enum      []interface{} `json:"enum,omitempty"`
+	EnumNames []string      `json:"enumNames,omitempty"`
+	Format    string        `json:"format,omitempty"`
+	Pattern   string        `json:"pattern,omitempty"`
+	MinLength *int          `json:"minLength,omitempty"`
+	MaxLength *int          `json:"maxLength,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+
+	Items       *ConfigProperty `json:"items,omitempty"`
+	MinItems    *int            `json:"minItems,omitempty"`
+	MaxItems    *int            `json:"maxItems,omitempty"`
+	UniqueItems bool            `json:"uniqueItems,omitempty"`
+
+	Properties []ConfigProperty `json:"properties,omitempty"`
+
+	// DependentRequired lists sibling properties that become required once this property is set.
+	// DependentProperties only apply once this property is set.
+	DependentRequired   []string         `json:"dependentRequired,omitempty"`
+	DependentProperties []ConfigProperty `json:"dependentProperties,omitempty"`
+}
+
 type Stage struct {
 	Name         string   `yaml:"name" json:"name"`
 	Environments []string `yaml:"environments" json:"environments"`
@@ -41,3 +78,60 @@ type Environment struct {
 type EnvironmentsResponse struct {
 	Environments []Environment `json:"environments"`
 }
+
+// BlueprintLocation is the git location Pulumi Deployments fetches a blueprint from.
+// Without a Ref or Commit the default branch is deployed.
+type BlueprintLocation struct {
+	RepoURL string `json:"repoUrl,omitempty"`
+	RepoDir string `json:"repoDir,omitempty"`
+	Version string `json:"version,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// BlueprintVersion is a released version of a blueprint backed by a git tag
+type BlueprintVersion struct {
+	Version string `json:"version"`
+	Ref     string `json:"ref"`
+	Commit  string `json:"commit,omitempty"`
+	// Blueprint is set for tags of the form <blueprint>/<version> and empty for
+	// repository-wide tags that version every blueprint in the source
+	Blueprint string `json:"-"`
+}
+
+// OutdatedWorkload describes a workload that runs an older blueprint version than the latest one
+type OutdatedWorkload struct {
+	Organization   string `json:"organization"`
+	Project        string `json:"project"`
+	Stack          string `json:"stack"`
+	Name           string `json:"name"`
+	Blueprint      string `json:"blueprint"`
+	CurrentVersion string `json:"currentVersion"`
+	LatestVersion  string `json:"latestVersion"`
+}
+
+// RegistryTemplate represents a template published to the Pulumi Cloud registry
+type RegistryTemplate struct {
+	Name        string `json:"name"`
+	Publisher   string `json:"publisher"`
+	Source      string `json:"source"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	DownloadURL string `json:"downloadURL"`
+}
+
+// RegistryTemplatesResponse represents the response for the list registry templates endpoint
+type RegistryTemplatesResponse struct {
+	Templates         []RegistryTemplate `json:"templates"`
+	ContinuationToken string             `json:"continuationToken,omitempty"`
+}
+
+// BlueprintRefresh is what a push to the blueprint repository invalidated in the blueprint cache
+type BlueprintRefresh struct {
+	Ref string `json:"ref"`
+	// All is set when the push did not list its changes and the whole cache was purged
+	All bool `json:"all"`
+	// Blueprints are the blueprints whose directories changed
+	Blueprints []string `json:"blueprints"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// CacheEntry is a serialized value of the database-backed blueprint cache
+type CacheEntry struct {
+	Key       string    `gorm:"primaryKey;column:cache_key"`
+	Value     []byte    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (CacheEntry) TableName() string {
+	return "blueprint_cache"
+}
+
+// CacheGeneration counts the invalidations of the database blueprint cache. It is shared by all
+// replicas, so that none of them stores a value loaded before another one invalidated it.
+type CacheGeneration struct {
+	ID         uint   `gorm:"primaryKey"`
+	Generation uint64 `gorm:"not null"`
+}
+
+func (CacheGeneration) TableName() string {
+	return "blueprint_cache_generation"
+}
@@ -0,0 +1,126 @@
+package model
+
+import "time"
+
+// Cleanup run statuses
+const (
+	CleanupRunStatusRunning   = "running"
+	CleanupRunStatusSucceeded = "succeeded"
+	CleanupRunStatusFailed    = "failed"
+)
+
+// What started a cleanup run
+const (
+	CleanupTriggerSchedule = "schedule"
+	CleanupTriggerManual   = "manual"
+)
+
+// Actions of a stack deletion rule
+const (
+	// Queue the destroy of the matching stacks, a delete rule removes them once it has succeeded
+	DeletionActionDestroy = "destroy"
+	// Delete the matching stacks once their destroy has succeeded
+	DeletionActionDelete = "delete"
+	// Only notify the owners of the matching stacks
+	DeletionActionNotify = "notify"
+)
+
+// Outcomes of the destroy and notify rule actions, and of any rule in a dry run
+const (
+	CleanupOutcomeDestroyQueued = "destroy-queued"
+	CleanupOutcomeNotified      = "notified"
+	// The stack already is being destroyed or its owners were notified before
+	CleanupOutcomeSkipped = "skipped"
+	CleanupOutcomeDryRun  = "dry-run"
+)
+
+// StackDeletionRule matches the stacks that fulfil all of its conditions and applies its action to them
+type StackDeletionRule struct {
+	Name string `yaml:"name" json:"name"`
+	// Tags the stack has to carry with exactly these values
+	Tags map[string]string `yaml:"tags" json:"tags,omitempty"`
+	// Projects restricts the rule to the stacks of these projects
+	Projects []string `yaml:"projects" json:"projects,omitempty"`
+	// OlderThan is the Go duration since the last update of the stack, like 720h
+	OlderThan string `yaml:"olderThan" json:"olderThan,omitempty"`
+	// Empty only matches stacks without resources
+	Empty  bool   `yaml:"empty" json:"empty,omitempty"`
+	Action string `yaml:"action" json:"action"`
+}
+
+// StackDeletionCriteria defines the rules for which stacks are cleaned up. A stack is handled by the
+// first rule it matches. In a dry run the cleanup only logs what the rules would do.
+type StackDeletionCriteria struct {
+	Rules  []StackDeletionRule `yaml:"rules" json:"rules"`
+	DryRun bool                `yaml:"dryRun" json:"dryRun"`
+}
+
+// CleanupRun is the persisted outcome of a single run of the stack cleanup
+type CleanupRun struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Trigger string `json:"trigger"`
+	// TriggeredBy is the login of the admin that started a manual run
+	TriggeredBy     string               `json:"triggeredBy,omitempty"`
+	Status          string               `gorm:"index" json:"status"`
+	DryRun          bool                 `json:"dryRun"`
+	StacksFound     int                  `json:"stacksFound"`
+	StacksDestroyed int                  `json:"stacksDestroyed"`
+	StacksDeleted   int                  `json:"stacksDeleted"`
+	StacksRetried   int                  `json:"stacksRetried"`
+	StacksNotified  int                  `json:"stacksNotified"`
+	StacksFailed    int                  `json:"stacksFailed"`
+	Stacks          []CleanupStackResult `gorm:"serializer:json" json:"stacks"`
+	Error           string               `json:"error,omitempty"`
+	StartedAt       time.Time            `gorm:"index" json:"startedAt"`
+	FinishedAt      *time.Time           `json:"finishedAt,omitempty"`
+}
+
+// CleanupStackResult is what a cleanup run did with a single stack matched by a rule
+type CleanupStackResult struct {
+	Stack   string `json:"stack"`
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Outcome string `json:"outcome,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CleanupStatus is the state of the cleanup scheduler
+type CleanupStatus struct {
+	// Replica is the replica that answered, only the leader runs the cleanup
+	Replica  string                `json:"replica"`
+	Leader   bool                  `json:"leader"`
+	Running  bool                  `json:"running"`
+	Paused   bool                  `json:"paused"`
+	Schedule string                `json:"schedule"`
+	Criteria StackDeletionCriteria `json:"criteria"`
+	// RunInProgress is set while a run, scheduled or manual, is going on
+	RunInProgress bool        `json:"runInProgress"`
+	LastRunAt     *time.Time  `json:"lastRunAt,omitempty"`
+	NextRunAt     *time.Time  `json:"nextRunAt,omitempty"`
+	LastRun       *CleanupRun `json:"lastRun,omitempty"`
+}
+
+// CleanupSettingsRequest changes the schedule or the deletion criteria of the cleanup.
+// The schedule is a Go duration, like 5m, or a cron expression.
+type CleanupSettingsRequest struct {
+	Schedule *string                `json:"schedule,omitempty"`
+	Criteria *StackDeletionCriteria `json:"criteria,omitempty"`
+}
+
+// CleanupSettings are the cleanup settings admins changed at runtime. All replicas share the single
+// row, the leader reads it before each run. A nil schedule or criteria keeps the configured one.
+type CleanupSettings struct {
+	ID        uint                   `gorm:"primaryKey" json:"-"`
+	Schedule  *string                `json:"schedule,omitempty"`
+	Paused    bool                   `json:"paused"`
+	Criteria  *StackDeletionCriteria `gorm:"serializer:json" json:"criteria,omitempty"`
+	UpdatedAt time.Time              `json:"updatedAt"`
+}
+
+// CleanupNotification records that the owners of a stack matched by a notify rule were notified,
+// so that they are not notified again while the stack keeps matching the rule
+type CleanupNotification struct {
+	// Key is the rule name and the full stack name, as rule/org/project/stack
+	Key        string    `gorm:"column:notification_key;primaryKey" json:"key"`
+	NotifiedAt time.Time `json:"notifiedAt"`
+}
@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+// Provisioning job kinds
+const (
+	JobKindCreate         = "create"
+	JobKindUpdate         = "update"
+	JobKindUpgradePreview = "upgrade-preview"
+	// Dry runs of a create or update end with a preview instead of an update deployment
+	JobKindCreatePreview = "create-preview"
+	JobKindUpdatePreview = "update-preview"
+)
+
+// Provisioning job and step statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusSkipped   = "skipped"
+)
+
+// Rollback statuses of a failed provisioning job
+const (
+	RollbackStatusSucceeded = "succeeded"
+	RollbackStatusFailed    = "failed"
+)
+
+// Provisioning step names, in the order they run
+const (
+	JobStepStackCreated       = "stack-created"
+	JobStepTeamGranted        = "team-granted"
+	JobStepTagsSet            = "tags-set"
+	JobStepRepoCreated        = "repo-created"
+	JobStepEnvironmentWritten = "esc-environment-written"
+	JobStepDeploymentQueued   = "deployment-queued"
+	JobStepPreviewQueued      = "preview-queued"
+	JobStepPreviewCompleted   = "preview-completed"
+	JobStepEnvironmentRestore = "esc-environment-restored"
+)
+
+// ProvisioningJob tracks an asynchronous workload create or update
+type ProvisioningJob struct {
+	ID           string `gorm:"primaryKey" json:"id"`
+	Kind         string `json:"kind"`
+	Organization string `gorm:"index:idx_job_stack" json:"organization"`
+	Project      string `gorm:"index:idx_job_stack" json:"project"`
+	Stack        string `gorm:"index:idx_job_stack" json:"stack"`
+	Status       string `gorm:"index" json:"status"`
+	Error        string `json:"error,omitempty"`
+	// Worker is the replica running the job
+	Worker string `json:"worker,omitempty"`
+	// DeploymentID is the update deployment a create or update job queued
+	DeploymentID string `json:"deploymentId,omitempty"`
+	// RequestedBy is the login of the user whose request started the job
+	RequestedBy string             `json:"requestedBy,omitempty"`
+	Steps       []ProvisioningStep `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"steps"`
+	// RollbackStatus and Rollback are only set when a failed job had actions to undo
+	RollbackStatus string           `json:"rollbackStatus,omitempty"`
+	Rollback       []RollbackAction `gorm:"serializer:json" json:"rollback,omitempty"`
+	// Preview is set by jobs that run a preview deployment once it has finished
+	Preview    *PreviewResult `gorm:"serializer:json" json:"preview,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// ProvisioningStep is a single ordered step of a provisioning job
+type ProvisioningStep struct {
+	ID         uint       `gorm:"primaryKey" json:"-"`
+	JobID      string     `gorm:"index;not null" json:"-"`
+	Position   int        `json:"position"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// RollbackAction is the outcome of a single undo action run after a job failed
+type RollbackAction struct {
+	Step   string `json:"step"`
+	Action string `json:"action"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ResourceChange is a single resource operation planned by a preview
+type ResourceChange struct {
+	Type      string   `json:"type"`
+	Name      string   `json:"name"`
+	Operation string   `json:"operation"`
+	Diff      []string `json:"diff,omitempty"`
+}
+
+// PreviewResult is the resource diff reported by a preview deployment
+type PreviewResult struct {
+	DeploymentID string           `json:"deploymentId"`
+	Status       string           `json:"status"`
+	Changes      []ResourceChange `json:"changes"`
+	// Summary counts the planned changes by operation
+	Summary map[string]int `json:"summary"`
+}
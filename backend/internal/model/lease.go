@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// LeaseLeader is the lease held by the replica that runs the background work
+const LeaseLeader = "leader"
+
+// WorkerLeasePrefix prefixes the lease every replica holds while it is alive. Jobs of
+// replicas whose lease has expired are no longer running.
+const WorkerLeasePrefix = "worker:"
+
+// Lease is a named lock held by one replica until it expires. Holders renew their leases while they run.
+type Lease struct {
+	Name      string    `gorm:"primaryKey" json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
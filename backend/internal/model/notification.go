@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Notification events
+const (
+	NotificationWorkloadExpiring = "workload.expiring"
+	NotificationWorkloadExpired  = "workload.expired"
+	// The destroy of a deleted workload failed for good
+	NotificationWorkloadDestroyFailed = "workload.destroy-failed"
+	// A stack matched a cleanup rule that only notifies
+	NotificationStackCleanupMatched = "stack.cleanup-matched"
+)
+
+// Notification is a message about a workload sent to the notification webhook
+type Notification struct {
+	Event        string     `json:"event"`
+	Text         string     `json:"text"`
+	Organization string     `json:"organization"`
+	Project      string     `json:"project"`
+	Stack        string     `json:"stack"`
+	Name         string     `json:"name"`
+	Team         string     `json:"team,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
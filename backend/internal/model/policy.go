@@ -0,0 +1,43 @@
+package model
+
+// Operations a policy can apply to
+const (
+	PolicyOperationCreate = "create"
+	PolicyOperationUpdate = "update"
+)
+
+// PolicyFile is a file of policies in the policy directory
+type PolicyFile struct {
+	Policies []Policy `yaml:"policies" json:"policies"`
+}
+
+// Policy is a guardrail on workload requests. Rule is a CEL expression that has to evaluate to
+// true for a request to be admitted. It can refer to request, the workload request as JSON,
+// operation, user with its login, teams and role, and projectWorkloads, the number of other
+// workloads with the request's project ID.
+type Policy struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Operations the policy applies to, all of them if empty
+	Operations []string `yaml:"operations" json:"operations,omitempty"`
+	// When is an optional CEL condition, the policy only applies to requests it holds for
+	When    string `yaml:"when" json:"when,omitempty"`
+	Rule    string `yaml:"rule" json:"rule"`
+	Message string `yaml:"message" json:"message,omitempty"`
+	// Field is the request field a violation is reported on
+	Field string `yaml:"field" json:"field,omitempty"`
+}
+
+// PolicyViolation is a policy a workload request does not satisfy
+type PolicyViolation struct {
+	Policy  string `json:"policy"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationResult is the outcome of validating a workload request without provisioning it
+type ValidationResult struct {
+	Valid      bool              `json:"valid"`
+	Fields     []FieldError      `json:"fields"`
+	Violations []PolicyViolation `json:"violations"`
+}
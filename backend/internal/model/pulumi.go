@@ -49,6 +49,17 @@ type Stack struct {
 	Outputs          map[string]interface{} `json:"outputs,omitempty"`
 	Tags             map[string]string      `json:"tags,omitempty"`
 	Version          int                    `json:"version"`
+	// StatusError is set when the Pulumi status of a catalog stack could not be read
+	StatusError string `json:"statusError,omitempty"`
+}
+
+// StackStatus is the Pulumi Cloud status of a stack that the workload list shows
+type StackStatus struct {
+	Result           string
+	DeploymentID     string
+	ResourceCount    int
+	LastUpdate       int64
+	CurrentOperation *CurrentOperation
 }
 
 // CurrentOperation represents the current operation on a stack
@@ -258,11 +269,12 @@ type CreateDeploymentResponse struct {
 type StackPermission struct {
 	ProjectName string `json:"projectName"`
 	StackName   string `json:"stackName"`
-	Permission  int    `json:"permission"`
+	Permission  int    `json:"permission,omitempty"`
 }
 
 type RequestBody struct {
-	AddStackPermission StackPermission `json:"addStackPermission"`
+	AddStackPermission    *StackPermission `json:"addStackPermission,omitempty"`
+	RemoveStackPermission *StackPermission `json:"removeStackPermission,omitempty"`
 }
 
 type StackResourcesResponse struct {
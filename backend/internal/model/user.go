@@ -0,0 +1,59 @@
+package model
+
+import "strings"
+
+// Identity providers a user can be authenticated by
+const (
+	AuthProviderGitHub = "github"
+	AuthProviderOIDC   = "oidc"
+	AuthProviderPulumi = "pulumi"
+	// Requests are attributed to the anonymous user when authentication is disabled
+	AuthProviderNone = "none"
+)
+
+// Roles, in increasing order of privilege. Viewers can read the workloads of their teams,
+// operators can also provision and change them, and admins can act on every workload.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// AnonymousUser is the identity of requests when authentication is disabled
+const AnonymousUser = "anonymous"
+
+// SystemUser is the identity of actions the IDP takes on its own, like destroying expired workloads
+const SystemUser = "system"
+
+// User is the authenticated caller of an API request
+type User struct {
+	Login    string   `json:"login"`
+	Name     string   `json:"name,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Provider string   `json:"provider"`
+	Teams    []string `json:"teams"`
+	Role     string   `json:"role,omitempty"`
+}
+
+// Identity is the login qualified by the provider that authenticated it, like github:alice.
+// Logins of different providers can collide, identities cannot.
+func (u *User) Identity() string {
+	return u.Provider + ":" + u.Login
+}
+
+// UserFromIdentity returns the user an identity names, without their teams
+func UserFromIdentity(identity string) *User {
+	provider, login, _ := strings.Cut(identity, ":")
+	return &User{
+		Login:    login,
+		Provider: provider,
+		Teams:    []string{},
+	}
+}
+
+// PulumiUser is the Pulumi Cloud user an access token belongs to
+type PulumiUser struct {
+	GithubLogin string `json:"githubLogin"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+}
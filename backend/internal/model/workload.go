@@ -17,6 +17,11 @@ type WorkloadRequest struct {
 	Tags          []Tag                    `json:"tags"`
 	Advanced      []map[string]interface{} `json:"advanced"`
 	CookieCut     bool                     `json:"cookiecut"`
+	Version       string                   `json:"version,omitempty"`
+	DryRun        bool                     `json:"dryRun,omitempty"`
+	// TTL or ExpiresAt make the workload ephemeral, it is destroyed once it expires
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type WorkloadResponse struct {
@@ -29,18 +34,22 @@ type WorkloadResponse struct {
 	Tags          []Tag                    `json:"tags"`
 	Advanced      []map[string]interface{} `json:"advanced"`
 	CookieCut     bool                     `json:"cookiecut"`
+	Version       string                   `json:"version,omitempty"`
 	Stack         Stack                    `json:"stack"`
+	ExpiresAt     *time.Time               `json:"expiresAt,omitempty"`
 }
 
 // Blueprint represents the structure of a Pulumi blueprint
 // Blueprint represents the structure of a Pulumi blueprint
 type Blueprint struct {
-	Name        string            `json:"name"`
-	Author      string            `json:"author"`
-	DisplayName string            `json:"displayName"`
-	Description string            `json:"description"`
-	Runtime     string            `json:"runtime"`
-	Tags        map[string]string `json:"tags"`
+	Name          string            `json:"name"`
+	Author        string            `json:"author"`
+	DisplayName   string            `json:"displayName"`
+	Description   string            `json:"description"`
+	Runtime       string            `json:"runtime"`
+	Tags          map[string]string `json:"tags"`
+	Source        string            `json:"source"`
+	LatestVersion string            `json:"latestVersion,omitempty"`
 }
 
 // RuntimeObject represents the complex runtime structure
@@ -64,6 +73,29 @@ type PulumiYaml struct {
 	Config map[string]interface{} `yaml:"config"`
 }
 
+// FieldError is a validation failure of a single request field.
+// Path is a JSON pointer into the request body, e.g. /advanced/replicas.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// UpgradeRequest moves a workload to another blueprint version. Advanced carries values
+// for config the target version requires; an empty Version targets the latest release.
+type UpgradeRequest struct {
+	Version  string                   `json:"version,omitempty"`
+	Advanced []map[string]interface{} `json:"advanced,omitempty"`
+}
+
+// UpgradePlan describes the effect of upgrading a workload to another blueprint version.
+// The resource diff is attached to the preview job once its deployment has finished.
+type UpgradePlan struct {
+	CurrentVersion    string           `json:"currentVersion"`
+	TargetVersion     string           `json:"targetVersion"`
+	NewRequiredConfig []string         `json:"newRequiredConfig"`
+	Job               *ProvisioningJob `json:"job"`
+}
+
 type LogLine struct {
 	Header    string    `json:"header,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
@@ -95,6 +127,12 @@ type Team struct {
 	UserRole    string `json:"userRole"`
 }
 
+// TeamDetails is a team with the stacks it was granted access to
+type TeamDetails struct {
+	Name   string            `json:"name"`
+	Stacks []StackPermission `json:"stacks"`
+}
+
 // TeamsResponse represents the response for the list teams endpoint
 type TeamsResponse struct {
 	Teams []Team `json:"teams"`
@@ -114,3 +152,103 @@ type EnvironmentsResponse0 struct {
 	Environments      []Environment0 `json:"environments"`
 	ContinuationToken string         `json:"continuationToken,omitempty"`
 }
+
+// Workload statuses persisted on WorkloadRecord
+const (
+	WorkloadStatusProvisioning = "provisioning"
+	WorkloadStatusUpdating     = "updating"
+	WorkloadStatusDeleting     = "deleting"
+	WorkloadStatusActive       = "active"
+	WorkloadStatusFailed       = "failed"
+	// A previewed workload has its stack and environment but was never deployed
+	WorkloadStatusPreviewed = "previewed"
+	// The destroy of a deleted workload kept failing, its stack is left for an operator
+	WorkloadStatusDestroyFailed = "destroy-failed"
+)
+
+// WorkloadRecord is the persisted catalog entry for a workload
+type WorkloadRecord struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	Organization     string          `gorm:"uniqueIndex:idx_workload_stack;not null" json:"organization"`
+	Blueprint        string          `gorm:"uniqueIndex:idx_workload_stack;not null" json:"blueprint"`
+	Stack            string          `gorm:"uniqueIndex:idx_workload_stack;not null" json:"stack"`
+	Name             string          `gorm:"index" json:"name"`
+	BlueprintName    string          `json:"blueprintName"`
+	Stage            string          `json:"stage"`
+	Team             string          `json:"team"`
+	ProjectID        string          `gorm:"index" json:"projectId"`
+	RepoURL          string          `json:"repoUrl"`
+	BlueprintVersion string          `json:"blueprintVersion"`
+	BlueprintCommit  string          `json:"blueprintCommit"`
+	CreationRequest  WorkloadRequest `gorm:"serializer:json" json:"creationRequest"`
+	Status           string          `json:"status"`
+	LastJobID        string          `json:"lastJobId"`
+	// ExpiresAt is when an ephemeral workload is destroyed, ExpiryNotifiedAt when its owners were warned
+	ExpiresAt        *time.Time `gorm:"index" json:"expiresAt,omitempty"`
+	ExpiryNotifiedAt *time.Time `json:"expiryNotifiedAt,omitempty"`
+	// DestroyAttempts counts the retried destroys of a deleted workload, NextDestroyAt is when the next one is queued
+	DestroyAttempts int        `json:"destroyAttempts,omitempty"`
+	NextDestroyAt   *time.Time `json:"nextDestroyAt,omitempty"`
+	// CreatedBy and UpdatedBy are the logins of the users that requested the last create and change
+	CreatedBy string    `json:"createdBy,omitempty"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName overrides the GORM table name
+func (WorkloadRecord) TableName() string {
+	return "workloads"
+}
+
+// ToStack converts the record into the stack representation used by the catalog API
+func (w *WorkloadRecord) ToStack() Stack {
+	stack := Stack{
+		OrgName:     w.Organization,
+		ProjectName: w.Blueprint,
+		StackName:   w.Stack,
+		LastUpdate:  w.UpdatedAt.Unix(),
+		Result:      w.Status,
+		Tags: map[string]string{
+			"idp:workload":          w.Name,
+			"idp:projectid":         w.ProjectID,
+			"idp:stage":             w.Stage,
+			"idp:blueprint-version": w.BlueprintVersion,
+		},
+	}
+	if w.ExpiresAt != nil {
+		stack.Tags[ExpiresAtTag] = w.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return stack
+}
+
+// Outcomes of checking the destroy of a deleted workload during cleanup
+const (
+	// The destroy is still running or waits for its next retry
+	DestroyOutcomePending = "pending"
+	// The destroy failed and was queued again
+	DestroyOutcomeRetried = "retried"
+	// The destroy succeeded and the empty stack was deleted
+	DestroyOutcomeDeleted = "deleted"
+	// The destroy failed too often, the workload is marked destroy-failed
+	DestroyOutcomeFailed = "failed"
+)
+
+// ExpiresAtTag is the stack tag holding the RFC 3339 expiry of an ephemeral workload
+const ExpiresAtTag = "idp:expires-at"
+
+// ExtendRequest moves the expiry of an ephemeral workload, either by a TTL from its current
+// expiry or to a fixed time
+type ExtendRequest struct {
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// WorkloadFilter narrows down a workload listing
+type WorkloadFilter struct {
+	Organization string
+	Name         string
+	ProjectID    string
+	// Teams restricts the workloads to those owned by one of the teams, nil does not restrict them
+	Teams []string
+}
@@ -0,0 +1,282 @@
+package pulumi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+)
+
+// maxRetryWait caps the wait between two attempts, also when the API asks for a longer one
+const maxRetryWait = 30 * time.Second
+
+// Client sends requests to the Pulumi Cloud REST API. It sets the auth and API version
+// headers, retries rate limited and failed requests and decodes the JSON responses.
+type Client struct {
+	baseURL      string
+	token        string
+	apiVersion   string
+	maxRetries   int
+	retryBackoff time.Duration
+	httpClient   *http.Client
+}
+
+// NewClient creates a client for the configured Pulumi Cloud API and access token
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		baseURL:      strings.TrimSuffix(cfg.Pulumi.APIBaseURL, "/"),
+		token:        cfg.Pulumi.APIToken,
+		apiVersion:   cfg.Pulumi.APIVersion,
+		maxRetries:   cfg.Pulumi.MaxRetries,
+		retryBackoff: cfg.Pulumi.RetryBackoff,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// WithToken returns a client that sends requests with another access token, like the one of a user
+func (c *Client) WithToken(token string) *Client {
+	client := *c
+	client.token = token
+	return &client
+}
+
+// APIError is returned for responses with a non-2xx status code
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("pulumi API %s %s failed with status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// StatusCode returns the status code of an APIError in the chain of err, or 0 if there is none
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether the API answered with 404 Not Found
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// Get sends a GET request and decodes the response into out
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// Post sends body as JSON and decodes the response into out
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// Patch sends body as JSON and decodes the response into out
+func (c *Client) Patch(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPatch, path, nil, body, out)
+}
+
+// Delete sends a DELETE request
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// Do sends a request to the API path, relative to the base URL. A non-nil body is sent as JSON,
+// a non-nil out receives the decoded response. Requests rejected with 429 Too Many Requests are
+// retried, as are idempotent requests that failed with a server or network error.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshaling request body: %w", err)
+		}
+	}
+
+	reqURL := c.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, reqURL, payload)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent(method) || attempt >= c.maxRetries {
+				return fmt.Errorf("error sending request: %w", err)
+			}
+			if err := c.wait(ctx, attempt, ""); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return decode(resp, out)
+		}
+
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		apiErr := &APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(data),
+		}
+		if !retryable(method, resp.StatusCode) || attempt >= c.maxRetries {
+			return apiErr
+		}
+		if err := c.wait(ctx, attempt, resp.Header.Get("Retry-After")); err != nil {
+			return apiErr
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, reqURL string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", c.apiVersion)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+
+	return c.httpClient.Do(req)
+}
+
+// wait sleeps before the next attempt, for as long as Retry-After asks or with exponential backoff and jitter
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter string) error {
+	delay, ok := parseRetryAfter(retryAfter)
+	if !ok {
+		delay = c.retryBackoff << attempt
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	delay = min(delay, maxRetryWait)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// retryable reports whether a response with this status code can be retried. A rate limited
+// request was not processed, server errors are only retried for idempotent methods.
+func retryable(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage returns the message of a Pulumi API error response, or the raw body
+func errorMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		return body.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Paginate iterates over the items of a list endpoint that pages with continuation tokens.
+// fetch returns the items of the page for a token and the token of the next page. The
+// iteration stops at the first error, which is yielded with the zero item.
+func Paginate[T any](ctx context.Context, continuationToken string, fetch func(ctx context.Context, continuationToken string) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		token := continuationToken
+		for {
+			items, next, err := fetch(ctx, token)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if next == "" || next == token {
+				return
+			}
+			token = next
+		}
+	}
+}
+
+// Collect gathers all items of an iterator, stopping at the first error
+func Collect[T any](items iter.Seq2[T, error]) ([]T, error) {
+	var all []T
+	for item, err := range items {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, item)
+	}
+	return all, nil
+}
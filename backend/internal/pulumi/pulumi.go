@@ -1,21 +1,34 @@
 package pulumi
 
 import (
+	"context"
+	"iter"
+
 	"github.com/pulumi-idp/internal/model"
 )
 
 type Service interface {
-	SetStackTag(organization, project, stack string, tag model.Tag) error
-	CreateStack(organization, project, stackName string) (*model.StackCreationResponse, error)
-	DeleteStack(organization, project, stack string) error
-	GetStack(project, stack string) (*model.Stack, error)
-	ListStacks(options *model.ListStacksOptions) (*model.ListStacksResponse, error)
-	CreateStackSettings(organization, project, stack, cloneUrl string) error
-	DeleteDeployment(organization, project, stack string) error
-	CreateDeployment(organization, project, stack, cloneUrl string) (*model.CreateDeploymentResponse, error)
-	RunPulumiUp(pulumiProjectName, bluePrintName, cloneUrl string, pulumiConfig []map[string]interface{}, stage string)
-	RunPulumiNew(tempDir, template, projectName, projectDesc string) error
-	GetStackUpdates(params *model.ListStackUpdatesParams, project, stack string) (*model.StackDeploymentsResponse, error)
-	GetTeams(organization, accessToken string) (*model.TeamsResponse, error)
-	GrantStackAccessToTeam(organization, team, projectName, stackName string, permission int) error
+	SetStackTag(ctx context.Context, organization, project, stack string, tag model.Tag) error
+	CreateStack(ctx context.Context, organization, project, stackName string) (*model.StackCreationResponse, error)
+	DeleteStack(ctx context.Context, organization, project, stack string) error
+	GetStack(ctx context.Context, project, stack string) (*model.Stack, error)
+	ListStacks(ctx context.Context, options *model.ListStacksOptions) (*model.ListStacksResponse, error)
+	Stacks(ctx context.Context, options *model.ListStacksOptions) iter.Seq2[model.Stack, error]
+	CreateStackSettings(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) error
+	DeleteDeployment(ctx context.Context, organization, project, stack string) (*model.CreateDeploymentResponse, error)
+	CreateDeployment(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) (*model.CreateDeploymentResponse, error)
+	CreatePreviewDeployment(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) (*model.CreateDeploymentResponse, error)
+	GetDeployment(ctx context.Context, organization, project, stack, deploymentID string) (*model.Deployment, error)
+	GetDeploymentLogs(ctx context.Context, organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error)
+	CreateEnvironment(ctx context.Context, organization, project, environment string) error
+	UpdateEnvironment(ctx context.Context, organization, project, environment, stage string, pulumiConfig []map[string]interface{}) error
+	GetEnvironmentConfig(ctx context.Context, organization, project, environment string) (map[string]interface{}, error)
+	OpenEnvironmentConfig(ctx context.Context, organization, project, environment string) (map[string]interface{}, error)
+	DeleteEnvironment(ctx context.Context, organization, project, environment string) error
+	RunPulumiNew(ctx context.Context, tempDir, template, projectName, projectDesc string) error
+	GetStackUpdates(ctx context.Context, params *model.ListStackUpdatesParams, project, stack string) (*model.StackDeploymentsResponse, error)
+	GetTeams(ctx context.Context, organization, accessToken string) (*model.TeamsResponse, error)
+	GetTeam(ctx context.Context, organization, team string) (*model.TeamDetails, error)
+	GetUser(ctx context.Context, accessToken string) (*model.PulumiUser, error)
+	GrantStackAccessToTeam(ctx context.Context, organization, team, projectName, stackName string, permission int) error
 }
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+)
+
+// ApprovalRepository persists approval requests for workload creates
+type ApprovalRepository struct {
+	db *gorm.DB
+}
+
+// NewApprovalRepository creates a new ApprovalRepository
+func NewApprovalRepository(db *gorm.DB) *ApprovalRepository {
+	return &ApprovalRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new approval request
+func (r *ApprovalRepository) Create(ctx context.Context, approval *model.ApprovalRequest) error {
+	if err := r.db.WithContext(ctx).Create(approval).Error; err != nil {
+		return fmt.Errorf("failed to create approval request: %w", err)
+	}
+	return nil
+}
+
+// Save updates an approval request
+func (r *ApprovalRepository) Save(ctx context.Context, approval *model.ApprovalRequest) error {
+	if err := r.db.WithContext(ctx).Save(approval).Error; err != nil {
+		return fmt.Errorf("failed to save approval request: %w", err)
+	}
+	return nil
+}
+
+// Decide stores the status and decisions of an approval request, if it is still pending and
+// unchanged since it was read. It reports whether the decision was stored.
+func (r *ApprovalRepository) Decide(ctx context.Context, approval *model.ApprovalRequest) (bool, error) {
+	version := approval.Version
+	result := r.db.WithContext(ctx).Model(&model.ApprovalRequest{}).
+		Where("id = ? AND status = ? AND version = ?", approval.ID, model.ApprovalStatusPending, version).
+		Select("status", "decisions", "version", "updated_at").
+		Updates(&model.ApprovalRequest{
+			Status:    approval.Status,
+			Decisions: approval.Decisions,
+			Version:   version + 1,
+			UpdatedAt: time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to decide approval request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	approval.Version = version + 1
+	return true, nil
+}
+
+// FindByID returns the approval request with the given ID
+func (r *ApprovalRepository) FindByID(ctx context.Context, id string) (*model.ApprovalRequest, error) {
+	var approval model.ApprovalRequest
+	err := r.db.WithContext(ctx).First(&approval, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find approval request: %w", err)
+	}
+	return &approval, nil
+}
+
+// List returns the approval requests with the given status, or all of them if status is empty, newest first
+func (r *ApprovalRepository) List(ctx context.Context, status string) ([]model.ApprovalRequest, error) {
+	query := r.db.WithContext(ctx).Order("created_at desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var approvals []model.ApprovalRequest
+	if err := query.Find(&approvals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+	return approvals, nil
+}
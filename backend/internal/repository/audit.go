@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize is the number of events read at a time while exporting
+const auditExportBatchSize = 500
+
+// AuditRepository persists the audit log. Events can only be appended, never changed or removed.
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+// Append inserts a new audit event
+func (r *AuditRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// List returns the events matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	query := r.filter(ctx, filter).Order("id desc")
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var events []model.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// Each calls fn for every event matching the filter, oldest first. Events are read in batches,
+// so that exports of the whole log do not have to fit into memory.
+func (r *AuditRepository) Each(ctx context.Context, filter model.AuditFilter, fn func(event *model.AuditEvent) error) error {
+	var batch []model.AuditEvent
+	result := r.filter(ctx, filter).FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to read audit events: %w", result.Error)
+	}
+	return nil
+}
+
+func (r *AuditRepository) filter(ctx context.Context, filter model.AuditFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.AuditEvent{})
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Organization != "" {
+		query = query.Where("organization = ?", filter.Organization)
+	}
+	if filter.Project != "" {
+		query = query.Where("project = ?", filter.Project)
+	}
+	if filter.Stack != "" {
+		query = query.Where("stack = ?", filter.Stack)
+	}
+	if filter.Blueprint != "" {
+		query = query.Where("blueprint = ?", filter.Blueprint)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	return query
+}
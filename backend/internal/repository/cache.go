@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CacheRepository persists the entries of the database-backed blueprint cache
+type CacheRepository struct {
+	db *gorm.DB
+}
+
+// NewCacheRepository creates a new CacheRepository
+func NewCacheRepository(db *gorm.DB) *CacheRepository {
+	return &CacheRepository{
+		db: db,
+	}
+}
+
+// Find returns the entry of a key that has not expired
+func (r *CacheRepository) Find(ctx context.Context, key string) (*model.CacheEntry, error) {
+	var entries []model.CacheEntry
+	err := r.db.WithContext(ctx).
+		Where("cache_key = ? AND expires_at >= ?", key, time.Now()).
+		Limit(1).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cache entry %s: %w", key, err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+	return &entries[0], nil
+}
+
+// cacheGenerationID is the ID of the single row holding the cache generation
+const cacheGenerationID = 1
+
+// Generation returns the number of invalidations of the cache so far
+func (r *CacheRepository) Generation(ctx context.Context) (uint64, error) {
+	generation := model.CacheGeneration{ID: cacheGenerationID}
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&generation).Error
+	if err == nil {
+		err = r.db.WithContext(ctx).First(&generation, cacheGenerationID).Error
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache generation: %w", err)
+	}
+	return generation.Generation, nil
+}
+
+// Save creates or replaces the entry of a key, unless the cache was invalidated since the
+// generation was read. It reports whether the entry was saved.
+func (r *CacheRepository) Save(ctx context.Context, generation uint64, entry *model.CacheEntry) (bool, error) {
+	saved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.CacheGeneration{ID: cacheGenerationID}).Error
+		if err != nil {
+			return err
+		}
+
+		// Writing the generation row locks it, so that no invalidation runs until the entry is saved
+		result := tx.Model(&model.CacheGeneration{}).
+			Where("id = ? AND generation = ?", cacheGenerationID, generation).
+			Update("generation", generation)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(entry).Error; err != nil {
+			return err
+		}
+		saved = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save cache entry %s: %w", entry.Key, err)
+	}
+	return saved, nil
+}
+
+// DeletePrefix removes the entries whose key starts with the prefix, an empty prefix removes all
+// of them, and starts a new generation
+func (r *CacheRepository) DeletePrefix(ctx context.Context, prefix string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.CacheGeneration{ID: cacheGenerationID}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.CacheGeneration{}).
+			Where("id = ?", cacheGenerationID).
+			Update("generation", gorm.Expr("generation + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("cache_key LIKE ?", prefix+"%").Delete(&model.CacheEntry{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the entries that have expired
+func (r *CacheRepository) DeleteExpired(ctx context.Context) error {
+	err := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&model.CacheEntry{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete expired cache entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+)
+
+// CleanupRunRepository persists the history of stack cleanup runs
+type CleanupRunRepository struct {
+	db *gorm.DB
+}
+
+// NewCleanupRunRepository creates a new CleanupRunRepository
+func NewCleanupRunRepository(db *gorm.DB) *CleanupRunRepository {
+	return &CleanupRunRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new cleanup run
+func (r *CleanupRunRepository) Create(ctx context.Context, run *model.CleanupRun) error {
+	if err := r.db.WithContext(ctx).Create(run).Error; err != nil {
+		return fmt.Errorf("failed to create cleanup run: %w", err)
+	}
+	return nil
+}
+
+// Save updates an existing cleanup run
+func (r *CleanupRunRepository) Save(ctx context.Context, run *model.CleanupRun) error {
+	if err := r.db.WithContext(ctx).Save(run).Error; err != nil {
+		return fmt.Errorf("failed to save cleanup run: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns a single cleanup run
+func (r *CleanupRunRepository) FindByID(ctx context.Context, id uint) (*model.CleanupRun, error) {
+	var run model.CleanupRun
+	err := r.db.WithContext(ctx).First(&run, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cleanup run: %w", err)
+	}
+	return &run, nil
+}
+
+// Latest returns the most recent cleanup run
+func (r *CleanupRunRepository) Latest(ctx context.Context) (*model.CleanupRun, error) {
+	var run model.CleanupRun
+	err := r.db.WithContext(ctx).Order("id desc").First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest cleanup run: %w", err)
+	}
+	return &run, nil
+}
+
+// List returns cleanup runs, newest first
+func (r *CleanupRunRepository) List(ctx context.Context, limit, offset int) ([]model.CleanupRun, error) {
+	query := r.db.WithContext(ctx).Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var runs []model.CleanupRun
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list cleanup runs: %w", err)
+	}
+	return runs, nil
+}
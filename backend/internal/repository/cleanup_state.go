@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cleanupSettingsID is the primary key of the single row of cleanup settings
+const cleanupSettingsID = 1
+
+// CleanupStateRepository persists the cleanup state shared by the replicas: the settings changed
+// at runtime and the stacks whose owners were notified
+type CleanupStateRepository struct {
+	db *gorm.DB
+}
+
+// NewCleanupStateRepository creates a new CleanupStateRepository
+func NewCleanupStateRepository(db *gorm.DB) *CleanupStateRepository {
+	return &CleanupStateRepository{
+		db: db,
+	}
+}
+
+// Settings returns the cleanup settings, ErrNotFound if they were never changed
+func (r *CleanupStateRepository) Settings(ctx context.Context) (*model.CleanupSettings, error) {
+	var settings model.CleanupSettings
+	err := r.db.WithContext(ctx).First(&settings, cleanupSettingsID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cleanup settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveSettings creates or replaces the cleanup settings
+func (r *CleanupStateRepository) SaveSettings(ctx context.Context, settings *model.CleanupSettings) error {
+	settings.ID = cleanupSettingsID
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(settings).Error
+	if err != nil {
+		return fmt.Errorf("failed to save cleanup settings: %w", err)
+	}
+	return nil
+}
+
+// Notified returns the keys of the stacks whose owners were notified
+func (r *CleanupStateRepository) Notified(ctx context.Context) (map[string]bool, error) {
+	var keys []string
+	err := r.db.WithContext(ctx).Model(&model.CleanupNotification{}).Pluck("notification_key", &keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cleanup notifications: %w", err)
+	}
+
+	notified := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		notified[key] = true
+	}
+	return notified, nil
+}
+
+// MarkNotified records that the owners of a stack were notified
+func (r *CleanupStateRepository) MarkNotified(ctx context.Context, key string) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.CleanupNotification{Key: key, NotifiedAt: time.Now()}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record cleanup notification %s: %w", key, err)
+	}
+	return nil
+}
+
+// KeepNotified forgets the notifications of all stacks but the given ones
+func (r *CleanupStateRepository) KeepNotified(ctx context.Context, keys []string) error {
+	query := r.db.WithContext(ctx)
+	if len(keys) > 0 {
+		query = query.Where("notification_key NOT IN ?", keys)
+	} else {
+		query = query.Where("1 = 1")
+	}
+	if err := query.Delete(&model.CleanupNotification{}).Error; err != nil {
+		return fmt.Errorf("failed to delete cleanup notifications: %w", err)
+	}
+	return nil
+}
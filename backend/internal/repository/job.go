@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+)
+
+// JobRepository persists provisioning jobs and their steps
+type JobRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRepository creates a new JobRepository
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new job together with its steps
+func (r *JobRepository) Create(ctx context.Context, job *model.ProvisioningJob) error {
+	if err := r.db.WithContext(ctx).Create(job).Error; err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the job with its steps in execution order
+func (r *JobRepository) FindByID(ctx context.Context, id string) (*model.ProvisioningJob, error) {
+	var job model.ProvisioningJob
+	err := r.db.WithContext(ctx).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position asc")
+		}).
+		First(&job, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job: %w", err)
+	}
+	return &job, nil
+}
+
+// HasUnfinished reports whether a pending or running job exists for a stack
+func (r *JobRepository) HasUnfinished(ctx context.Context, organization, project, stack string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ProvisioningJob{}).
+		Where("organization = ? AND project = ? AND stack = ? AND status IN ?",
+			organization, project, stack, []string{model.JobStatusPending, model.JobStatusRunning}).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to count unfinished jobs: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListUnfinished returns the pending and running jobs with their steps
+func (r *JobRepository) ListUnfinished(ctx context.Context) ([]model.ProvisioningJob, error) {
+	var jobs []model.ProvisioningJob
+	err := r.db.WithContext(ctx).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position asc")
+		}).
+		Where("status IN ?", []string{model.JobStatusPending, model.JobStatusRunning}).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfinished jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// SaveJob updates the job's own fields without touching its steps
+func (r *JobRepository) SaveJob(ctx context.Context, job *model.ProvisioningJob) error {
+	if err := r.db.WithContext(ctx).Omit("Steps").Save(job).Error; err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// SaveStep updates a single job step
+func (r *JobRepository) SaveStep(ctx context.Context, step *model.ProvisioningStep) error {
+	if err := r.db.WithContext(ctx).Save(step).Error; err != nil {
+		return fmt.Errorf("failed to save job step: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaseRepository persists the leases replicas coordinate through
+type LeaseRepository struct {
+	db *gorm.DB
+}
+
+// NewLeaseRepository creates a new LeaseRepository
+func NewLeaseRepository(db *gorm.DB) *LeaseRepository {
+	return &LeaseRepository{
+		db: db,
+	}
+}
+
+// Acquire takes or renews a lease for the holder. It fails without an error while another
+// holder's lease has not expired.
+func (r *LeaseRepository) Acquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{
+			"holder":     holder,
+			"expires_at": now.Add(duration),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to renew lease %s: %w", name, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// Nobody held the lease yet, of two replicas creating it only one succeeds
+	result = r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Lease{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: now.Add(duration),
+	})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create lease %s: %w", name, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Release gives up a lease if the holder still holds it
+func (r *LeaseRepository) Release(ctx context.Context, name, holder string) error {
+	err := r.db.WithContext(ctx).
+		Where("name = ? AND holder = ?", name, holder).
+		Delete(&model.Lease{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// Find returns a lease, expired or not
+func (r *LeaseRepository) Find(ctx context.Context, name string) (*model.Lease, error) {
+	var leases []model.Lease
+	if err := r.db.WithContext(ctx).Where("name = ?", name).Limit(1).Find(&leases).Error; err != nil {
+		return nil, fmt.Errorf("failed to find lease %s: %w", name, err)
+	}
+	if len(leases) == 0 {
+		return nil, ErrNotFound
+	}
+	return &leases[0], nil
+}
+
+// ListActive returns the leases with the given name prefix that have not expired
+func (r *LeaseRepository) ListActive(ctx context.Context, prefix string) ([]model.Lease, error) {
+	var leases []model.Lease
+	err := r.db.WithContext(ctx).
+		Where("name LIKE ? AND expires_at >= ?", prefix+"%", time.Now()).
+		Find(&leases).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	return leases, nil
+}
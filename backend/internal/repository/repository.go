@@ -1,10 +1,36 @@
 package repository
 
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
 // Repository contains all repositories
 type Repository struct {
+	Workload     *WorkloadRepository
+	Job          *JobRepository
+	Audit        *AuditRepository
+	Approval     *ApprovalRepository
+	Cleanup      *CleanupRunRepository
+	CleanupState *CleanupStateRepository
+	Lease        *LeaseRepository
+	Cache        *CacheRepository
 }
 
 // NewRepository creates a new repository instance with all repositories
-func NewRepository() *Repository {
-	return &Repository{}
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{
+		Workload:     NewWorkloadRepository(db),
+		Job:          NewJobRepository(db),
+		Audit:        NewAuditRepository(db),
+		Approval:     NewApprovalRepository(db),
+		Cleanup:      NewCleanupRunRepository(db),
+		CleanupState: NewCleanupStateRepository(db),
+		Lease:        NewLeaseRepository(db),
+		Cache:        NewCacheRepository(db),
+	}
 }
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WorkloadRepository persists workload catalog entries
+type WorkloadRepository struct {
+	db *gorm.DB
+}
+
+// NewWorkloadRepository creates a new WorkloadRepository
+func NewWorkloadRepository(db *gorm.DB) *WorkloadRepository {
+	return &WorkloadRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new workload record
+func (r *WorkloadRepository) Create(ctx context.Context, workload *model.WorkloadRecord) error {
+	if err := r.db.WithContext(ctx).Create(workload).Error; err != nil {
+		return fmt.Errorf("failed to create workload: %w", err)
+	}
+	return nil
+}
+
+// CreateIfMissing inserts a workload record unless its stack already has one, and reports
+// whether it was inserted
+func (r *WorkloadRepository) CreateIfMissing(ctx context.Context, workload *model.WorkloadRecord) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(workload)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create workload: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Claim sets the status of a workload unless it has one of the busy statuses, and reports whether
+// it did. Of two concurrent claims only one succeeds.
+func (r *WorkloadRepository) Claim(ctx context.Context, workload *model.WorkloadRecord, status string, busy []string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.WorkloadRecord{}).
+		Where("id = ? AND status NOT IN ?", workload.ID, busy).
+		Update("status", status)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim workload: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Save updates all fields of an existing workload record
+func (r *WorkloadRepository) Save(ctx context.Context, workload *model.WorkloadRecord) error {
+	if err := r.db.WithContext(ctx).Save(workload).Error; err != nil {
+		return fmt.Errorf("failed to save workload: %w", err)
+	}
+	return nil
+}
+
+// FindByStack returns the workload backed by the given stack
+func (r *WorkloadRepository) FindByStack(ctx context.Context, organization, project, stack string) (*model.WorkloadRecord, error) {
+	var workload model.WorkloadRecord
+	err := r.db.WithContext(ctx).
+		Where("organization = ? AND blueprint = ? AND stack = ?", organization, project, stack).
+		First(&workload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workload: %w", err)
+	}
+	return &workload, nil
+}
+
+// List returns all workloads matching the filter, newest first
+func (r *WorkloadRepository) List(ctx context.Context, filter model.WorkloadFilter) ([]model.WorkloadRecord, error) {
+	query := r.db.WithContext(ctx).Model(&model.WorkloadRecord{})
+
+	if filter.Organization != "" {
+		query = query.Where("organization = ?", filter.Organization)
+	}
+
+	if filter.Name != "" {
+		query = query.Where("name = ?", filter.Name)
+	}
+
+	if filter.ProjectID != "" {
+		query = query.Where("project_id = ?", filter.ProjectID)
+	}
+
+	if filter.Teams != nil {
+		teams := make([]string, 0, len(filter.Teams))
+		for _, team := range filter.Teams {
+			teams = append(teams, strings.ToLower(team))
+		}
+		query = query.Where("LOWER(team) IN ?", teams)
+	}
+
+	var workloads []model.WorkloadRecord
+	if err := query.Order("created_at desc").Find(&workloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to list workloads: %w", err)
+	}
+	return workloads, nil
+}
+
+// ListExpiring returns the workloads that expire before the given time and are not deleted yet, soonest first
+func (r *WorkloadRepository) ListExpiring(ctx context.Context, until time.Time) ([]model.WorkloadRecord, error) {
+	var workloads []model.WorkloadRecord
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ? AND status NOT IN ?", until,
+			[]string{model.WorkloadStatusDeleting, model.WorkloadStatusDestroyFailed}).
+		Order("expires_at asc").
+		Find(&workloads).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring workloads: %w", err)
+	}
+	return workloads, nil
+}
+
+// UpdateStatus sets the status of the workload backed by the given stack
+func (r *WorkloadRepository) UpdateStatus(ctx context.Context, organization, project, stack, status string) error {
+	result := r.db.WithContext(ctx).Model(&model.WorkloadRecord{}).
+		Where("organization = ? AND blueprint = ? AND stack = ?", organization, project, stack).
+		Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update workload status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByStack removes the workload backed by the given stack
+func (r *WorkloadRepository) DeleteByStack(ctx context.Context, organization, project, stack string) error {
+	err := r.db.WithContext(ctx).
+		Where("organization = ? AND blueprint = ? AND stack = ?", organization, project, stack).
+		Delete(&model.WorkloadRecord{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete workload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// ErrApprovalClosed is returned when deciding on an approval request that is no longer pending
+var ErrApprovalClosed = errors.New("approval request is no longer pending")
+
+// ApprovalService holds workload creates for configured stages and blueprints, and updates moving
+// workloads to such stages, until approver teams have approved them. Nothing is provisioned before
+// the approval; a rejected request is dropped.
+type ApprovalService struct {
+	cfg              *config.Config
+	approvals        *repository.ApprovalRepository
+	workloadService  *WorkloadService
+	blueprintService *BlueprintService
+	pulumiService    *PulumiService
+	rbacService      *RBACService
+	auditService     *AuditService
+	logger           *log.Logger
+}
+
+// NewApprovalService creates a new ApprovalService instance
+func NewApprovalService(cfg *config.Config, approvals *repository.ApprovalRepository) *ApprovalService {
+	warnUnqualifiedUsers("APPROVAL_APPROVER_TEAMS", cfg.Approval.ApproverTeams)
+
+	return &ApprovalService{
+		cfg:       cfg,
+		approvals: approvals,
+		logger:    log.New(log.Writer(), "[Approval] ", log.LstdFlags),
+	}
+}
+
+func (s *ApprovalService) SetWorkloadService(service *WorkloadService) {
+	s.workloadService = service
+}
+
+func (s *ApprovalService) SetBlueprintService(service *BlueprintService) {
+	s.blueprintService = service
+}
+
+func (s *ApprovalService) SetPulumiService(service *PulumiService) {
+	s.pulumiService = service
+}
+
+func (s *ApprovalService) SetRBACService(service *RBACService) {
+	s.rbacService = service
+}
+
+func (s *ApprovalService) SetAuditService(service *AuditService) {
+	s.auditService = service
+}
+
+// Requires reports whether a create request has to be approved before it is provisioned.
+// Dry runs never do, they only preview the workload.
+func (s *ApprovalService) Requires(req *model.WorkloadRequest) bool {
+	if req.DryRun {
+		return false
+	}
+	return containsFold(s.cfg.Approval.Stages, req.Stage) || containsFold(s.cfg.Approval.Blueprints, req.BlueprintName)
+}
+
+// RequiresUpdate reports whether an update moves a workload to another stage that a create would
+// need approval for. Updates within a stage never do, the workload was approved for it already.
+func (s *ApprovalService) RequiresUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (bool, error) {
+	if req.DryRun || req.Stage == "" {
+		return false, nil
+	}
+
+	record, err := s.workloadService.workloads.FindByStack(ctx, organization, project, stack)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Workloads adopted by the update have no known stage
+		record = &model.WorkloadRecord{BlueprintName: project}
+	} else if err != nil {
+		return false, err
+	}
+	if strings.EqualFold(record.Stage, req.Stage) {
+		return false, nil
+	}
+
+	blueprint := record.BlueprintName
+	if blueprint == "" {
+		blueprint = project
+	}
+	return s.Requires(&model.WorkloadRequest{Stage: req.Stage, BlueprintName: blueprint}), nil
+}
+
+// Submit stores a create request for approval. The blueprint version is resolved now, so that
+// the approvers decide on the workload that is eventually provisioned. Secret values are kept
+// out of the database, in an ESC environment that is removed once the request is decided.
+func (s *ApprovalService) Submit(ctx context.Context, req *model.WorkloadRequest) (*model.ApprovalRequest, error) {
+	version, err := s.workloadService.createVersion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &model.ApprovalRequest{
+		ID:           uuid.NewString(),
+		Organization: s.cfg.Pulumi.Organization,
+		Blueprint:    req.BlueprintName,
+		Name:         req.Name,
+		Stage:        req.Stage,
+		Team:         req.Team,
+	}
+	return s.submit(ctx, approval, req, version)
+}
+
+// SubmitUpdate stores an update moving a workload to another stage for approval. The update keeps
+// the pinned blueprint version of the workload unless the request moves it, like any update.
+func (s *ApprovalService) SubmitUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (*model.ApprovalRequest, error) {
+	record, err := s.workloadService.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	blueprint := record.BlueprintName
+	if blueprint == "" {
+		blueprint = project
+	}
+	version := req.Version
+	if version == "" {
+		version = record.BlueprintVersion
+	}
+	team := record.Team
+	if req.Team != "" {
+		team = req.Team
+	}
+
+	approval := &model.ApprovalRequest{
+		ID:           uuid.NewString(),
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Blueprint:    blueprint,
+		Name:         record.Name,
+		Stage:        req.Stage,
+		Team:         team,
+	}
+	return s.submit(ctx, approval, req, version)
+}
+
+// submit stores a request for approval with its secrets held back
+func (s *ApprovalService) submit(ctx context.Context, approval *model.ApprovalRequest, req *model.WorkloadRequest, version string) (*model.ApprovalRequest, error) {
+	location, err := s.blueprintService.GetBlueprintLocation(ctx, approval.Blueprint, version)
+	if err != nil {
+		return nil, err
+	}
+	_, properties, err := s.blueprintService.readConfigSchema(ctx, approval.Blueprint, location.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	approval.Status = model.ApprovalStatusPending
+	approval.RequestedBy = identity(ctx)
+	approval.Request = *req
+	approval.Decisions = []model.ApprovalDecision{}
+	approval.Request.Version = version
+	approval.Request.Advanced = redactAdvanced(properties, req.Advanced)
+
+	config := mergeConfig(req.Advanced)
+	if !reflect.DeepEqual(config, mergeConfig(approval.Request.Advanced)) {
+		if err := s.holdSecrets(ctx, approval, secretConfig(properties, config, nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.approvals.Create(ctx, approval); err != nil {
+		s.releaseSecrets(ctx, approval)
+		return nil, err
+	}
+
+	s.audit(ctx, model.AuditActionApprovalSubmit, approval, nil)
+	return approval, nil
+}
+
+// Approve records the caller's approval. Once the request has enough approvals, the workload
+// is created on behalf of the requester and the provisioning job is returned with the request.
+func (s *ApprovalService) Approve(ctx context.Context, id, comment string) (*model.ApprovalRequest, error) {
+	approval, before, err := s.decide(ctx, id, model.ApprovalDecisionApprove, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := 0
+	for _, decision := range approval.Decisions {
+		if decision.Decision == model.ApprovalDecisionApprove {
+			approvals++
+		}
+	}
+	if approvals >= s.cfg.Approval.RequiredApprovals {
+		approval.Status = model.ApprovalStatusApproved
+	}
+	if err := s.store(ctx, approval); err != nil {
+		return nil, err
+	}
+
+	// Only the decision that moved the request out of pending provisions it
+	if approval.Status == model.ApprovalStatusApproved {
+		job, err := s.provision(ctx, approval)
+		if err != nil {
+			approval.Status = model.ApprovalStatusFailed
+			approval.Error = err.Error()
+		} else {
+			approval.JobID = job.ID
+		}
+		s.releaseSecrets(ctx, approval)
+
+		if err := s.approvals.Save(ctx, approval); err != nil {
+			return nil, err
+		}
+	}
+	s.audit(ctx, model.AuditActionApprovalApprove, approval, before)
+	return approval, nil
+}
+
+// Reject records the caller's rejection. A single rejection closes the request.
+func (s *ApprovalService) Reject(ctx context.Context, id, comment string) (*model.ApprovalRequest, error) {
+	approval, before, err := s.decide(ctx, id, model.ApprovalDecisionReject, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	approval.Status = model.ApprovalStatusRejected
+	if err := s.store(ctx, approval); err != nil {
+		return nil, err
+	}
+	s.releaseSecrets(ctx, approval)
+
+	if err := s.approvals.Save(ctx, approval); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, model.AuditActionApprovalReject, approval, before)
+	return approval, nil
+}
+
+// GetApproval returns an approval request. Approvers can read every request, other users only their own.
+func (s *ApprovalService) GetApproval(ctx context.Context, id string) (*model.ApprovalRequest, error) {
+	approval, err := s.approvals.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.isApprover(ctx) && approval.RequestedBy != identity(ctx) {
+		return nil, fmt.Errorf("%w: approval request %s was not submitted by %s", ErrForbidden, id, identity(ctx))
+	}
+	return approval, nil
+}
+
+// ListApprovals returns the approval requests with the given status, newest first.
+// Approvers see every request, other users only their own.
+func (s *ApprovalService) ListApprovals(ctx context.Context, status string) ([]model.ApprovalRequest, error) {
+	approvals, err := s.approvals.List(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []model.ApprovalRequest{}
+	approver := s.isApprover(ctx)
+	for _, approval := range approvals {
+		if approver || approval.RequestedBy == identity(ctx) {
+			visible = append(visible, approval)
+		}
+	}
+	return visible, nil
+}
+
+// decide loads a pending request and appends the caller's decision to it.
+// The snapshot of the request before the decision is returned for the audit log.
+func (s *ApprovalService) decide(ctx context.Context, id, decision, comment string) (*model.ApprovalRequest, map[string]interface{}, error) {
+	if !s.isApprover(ctx) {
+		return nil, nil, fmt.Errorf("%w: %s is not an approver", ErrForbidden, actor(ctx))
+	}
+
+	approval, err := s.approvals.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if approval.Status != model.ApprovalStatusPending {
+		return nil, nil, fmt.Errorf("%w: request %s is %s", ErrApprovalClosed, id, approval.Status)
+	}
+
+	approver := identity(ctx)
+	// Everyone is the anonymous user when authentication is disabled
+	if s.cfg.Auth.Enabled {
+		if strings.EqualFold(approver, approval.RequestedBy) {
+			return nil, nil, fmt.Errorf("%w: requests cannot be decided by their requester", ErrForbidden)
+		}
+		for _, existing := range approval.Decisions {
+			if strings.EqualFold(existing.Approver, approver) {
+				return nil, nil, fmt.Errorf("%w: %s has already decided on request %s", ErrForbidden, approver, id)
+			}
+		}
+	}
+
+	before := approvalSnapshot(approval)
+	approval.Decisions = append(approval.Decisions, model.ApprovalDecision{
+		Approver:  approver,
+		Decision:  decision,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	})
+	return approval, before, nil
+}
+
+// store writes a decision on a request. It fails with ErrApprovalClosed when another decision
+// was stored since the request was loaded, also by another replica.
+func (s *ApprovalService) store(ctx context.Context, approval *model.ApprovalRequest) error {
+	stored, err := s.approvals.Decide(ctx, approval)
+	if err != nil {
+		return err
+	}
+	if !stored {
+		return fmt.Errorf("%w: request %s was decided concurrently", ErrApprovalClosed, approval.ID)
+	}
+	return nil
+}
+
+// provision creates or updates the approved workload with its secrets restored, attributed to the requester
+func (s *ApprovalService) provision(ctx context.Context, approval *model.ApprovalRequest) (*model.ProvisioningJob, error) {
+	req := approval.Request
+	if approval.SecretsEnvironment != "" {
+		config, err := s.pulumiService.OpenEnvironmentConfig(ctx, approval.Organization, s.cfg.Approval.SecretsProject, approval.SecretsEnvironment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the secrets of the request: %w", err)
+		}
+		req.Advanced = []map[string]interface{}{config}
+	}
+
+	requester := model.UserFromIdentity(approval.RequestedBy)
+	if approval.Stack != "" {
+		return s.workloadService.UpdateWorkload(WithUser(ctx, requester), approval.Organization, approval.Project, approval.Stack, &req)
+	}
+	return s.workloadService.CreateWorkload(WithUser(ctx, requester), &req)
+}
+
+// holdSecrets writes the config of a request, secrets included, into its holding environment
+func (s *ApprovalService) holdSecrets(ctx context.Context, approval *model.ApprovalRequest, config map[string]interface{}) error {
+	project := s.cfg.Approval.SecretsProject
+	if err := s.pulumiService.CreateEnvironment(ctx, approval.Organization, project, approval.ID); err != nil {
+		return fmt.Errorf("failed to store the secrets of the request: %w", err)
+	}
+	approval.SecretsEnvironment = approval.ID
+
+	if err := s.pulumiService.UpdateEnvironment(ctx, approval.Organization, project, approval.ID, "", []map[string]interface{}{config}); err != nil {
+		s.releaseSecrets(ctx, approval)
+		return fmt.Errorf("failed to store the secrets of the request: %w", err)
+	}
+	return nil
+}
+
+// releaseSecrets deletes the holding environment of a request. Failures are logged,
+// the request has been decided either way.
+func (s *ApprovalService) releaseSecrets(ctx context.Context, approval *model.ApprovalRequest) {
+	if approval.SecretsEnvironment == "" {
+		return
+	}
+	if err := s.pulumiService.DeleteEnvironment(ctx, approval.Organization, s.cfg.Approval.SecretsProject, approval.SecretsEnvironment); err != nil {
+		s.logger.Printf("Failed to delete the secrets environment of approval request %s: %v", approval.ID, err)
+		return
+	}
+	approval.SecretsEnvironment = ""
+}
+
+// isApprover reports whether the caller may decide on approval requests
+func (s *ApprovalService) isApprover(ctx context.Context) bool {
+	user, role, err := s.rbacService.caller(ctx)
+	if err != nil {
+		return false
+	}
+	return role == model.RoleAdmin || matchesAny(user, s.cfg.Approval.ApproverTeams)
+}
+
+func (s *ApprovalService) audit(ctx context.Context, action string, approval *model.ApprovalRequest, before map[string]interface{}) {
+	event := &model.AuditEvent{
+		Actor:        actor(ctx),
+		Action:       action,
+		Organization: approval.Organization,
+		Project:      approval.Request.Blueprint,
+		Stack:        approval.Name,
+		Blueprint:    approval.Blueprint,
+		JobID:        approval.JobID,
+		Outcome:      approval.Status,
+		Error:        approval.Error,
+		Before:       before,
+		After:        approvalSnapshot(approval),
+	}
+	// Updates name the stack they move
+	if approval.Stack != "" {
+		event.Project = approval.Project
+		event.Stack = approval.Stack
+	}
+	s.auditService.Record(ctx, event)
+}
+
+// approvalSnapshot is the audited state of an approval request. The stored request never holds secret values.
+func approvalSnapshot(approval *model.ApprovalRequest) map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"status":           approval.Status,
+		"stage":            approval.Stage,
+		"team":             approval.Team,
+		"blueprintVersion": approval.Request.Version,
+		"decisions":        len(approval.Decisions),
+		"config":           mergeConfig(approval.Request.Advanced),
+	}
+
+	converted, err := toJSONValue(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	return converted.(map[string]interface{})
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+func TestDecideStoresOnlyOneOfConcurrentDecisions(t *testing.T) {
+	ctx := context.Background()
+	approvals := repository.NewApprovalRepository(newTestDB(t, &model.ApprovalRequest{}))
+	if err := approvals.Create(ctx, &model.ApprovalRequest{ID: "a1", Status: model.ApprovalStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two replicas load the same pending request before either decides
+	first, err := approvals.FindByID(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := approvals.FindByID(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Status = model.ApprovalStatusApproved
+	first.Decisions = append(first.Decisions, model.ApprovalDecision{Approver: "github:alice", Decision: model.ApprovalDecisionApprove})
+	if stored, err := approvals.Decide(ctx, first); err != nil || !stored {
+		t.Fatalf("the first decision was not stored: %t, %v", stored, err)
+	}
+
+	second.Status = model.ApprovalStatusApproved
+	second.Decisions = append(second.Decisions, model.ApprovalDecision{Approver: "github:bob", Decision: model.ApprovalDecisionApprove})
+	if stored, err := approvals.Decide(ctx, second); err != nil || stored {
+		t.Fatalf("the second decision was stored: %t, %v", stored, err)
+	}
+
+	approval, err := approvals.FindByID(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approval.Status != model.ApprovalStatusApproved || len(approval.Decisions) != 1 || approval.Decisions[0].Approver != "github:alice" {
+		t.Fatalf("unexpected request after the decisions: %+v", approval)
+	}
+}
+
+func TestDecideKeepsPendingRequestsVersioned(t *testing.T) {
+	ctx := context.Background()
+	approvals := repository.NewApprovalRepository(newTestDB(t, &model.ApprovalRequest{}))
+	if err := approvals.Create(ctx, &model.ApprovalRequest{ID: "a1", Status: model.ApprovalStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := approvals.FindByID(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := approvals.FindByID(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A decision that leaves the request pending still outdates copies read before it
+	current.Decisions = append(current.Decisions, model.ApprovalDecision{Approver: "github:alice", Decision: model.ApprovalDecisionApprove})
+	if stored, err := approvals.Decide(ctx, current); err != nil || !stored {
+		t.Fatalf("the decision was not stored: %t, %v", stored, err)
+	}
+	stale.Decisions = append(stale.Decisions, model.ApprovalDecision{Approver: "github:bob", Decision: model.ApprovalDecisionApprove})
+	if stored, err := approvals.Decide(ctx, stale); err != nil || stored {
+		t.Fatalf("a decision on an outdated request was stored: %t, %v", stored, err)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// AuditService records mutating IDP actions in the append-only audit log
+type AuditService struct {
+	cfg    *config.Config
+	events *repository.AuditRepository
+	logger *log.Logger
+}
+
+// NewAuditService creates a new AuditService instance
+func NewAuditService(cfg *config.Config, events *repository.AuditRepository) *AuditService {
+	return &AuditService{
+		cfg:    cfg,
+		events: events,
+		logger: log.New(log.Writer(), "[Audit] ", log.LstdFlags),
+	}
+}
+
+// Record appends an event to the audit log. The changes are derived from the before and after snapshots.
+// Failures are logged rather than returned, the audited action has already taken place.
+func (s *AuditService) Record(ctx context.Context, event *model.AuditEvent) {
+	event.Changes = diffSnapshots(event.Before, event.After)
+	if err := s.events.Append(ctx, event); err != nil {
+		s.logger.Printf("Failed to record %s of %s by %q: %v", event.Action, event.Stack, event.Actor, err)
+	}
+}
+
+// ListEvents returns the audit events matching the filter, newest first
+func (s *AuditService) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	events, err := s.events.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []model.AuditEvent{}
+	}
+	return events, nil
+}
+
+// ExportEvents writes the audit events matching the filter to w as JSON lines, oldest first
+func (s *AuditService) ExportEvents(ctx context.Context, filter model.AuditFilter, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return s.events.Each(ctx, filter, func(event *model.AuditEvent) error {
+		return encoder.Encode(event)
+	})
+}
+
+// workloadSnapshot is the audited state of a workload: its placement, ownership and config.
+// The config in the catalog never holds secret values.
+func workloadSnapshot(record *model.WorkloadRecord) map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"stage":            record.Stage,
+		"team":             record.Team,
+		"projectId":        record.ProjectID,
+		"blueprintVersion": record.BlueprintVersion,
+		"config":           mergeConfig(record.CreationRequest.Advanced),
+	}
+	if record.ExpiresAt != nil {
+		snapshot["expiresAt"] = record.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	// Store the snapshot the way it reads back from the database
+	converted, err := toJSONValue(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	return converted.(map[string]interface{})
+}
+
+// diffSnapshots lists the fields that differ between two snapshots, sorted by path.
+// Nested objects are compared field by field, any other value as a whole.
+func diffSnapshots(before, after map[string]interface{}) []model.AuditChange {
+	changes := []model.AuditChange{}
+	diffValues("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(path string, before, after interface{}, changes *[]model.AuditChange) {
+	beforeObject, beforeIsObject := before.(map[string]interface{})
+	afterObject, afterIsObject := after.(map[string]interface{})
+	if (beforeIsObject || before == nil) && (afterIsObject || after == nil) && (beforeIsObject || afterIsObject) {
+		keys := make(map[string]bool)
+		for key := range beforeObject {
+			keys[key] = true
+		}
+		for key := range afterObject {
+			keys[key] = true
+		}
+		for key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			diffValues(child, beforeObject[key], afterObject[key], changes)
+		}
+		return
+	}
+
+	if isEmptyValue(before) && isEmptyValue(after) {
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, model.AuditChange{
+			Path:   path,
+			Before: before,
+			After:  after,
+		})
+	}
+}
+
+// isEmptyValue reports whether a snapshot value is unset
+func isEmptyValue(value interface{}) bool {
+	return value == nil || value == ""
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	pulumiapi "github.com/pulumi-idp/internal/pulumi"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnauthenticated is returned when a token is missing, malformed, expired or rejected by its issuer
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// pulumiTokenPrefix marks Pulumi access tokens
+const pulumiTokenPrefix = "pul-"
+
+// cachedIdentity is a resolved token identity and the time it stops being reused
+type cachedIdentity struct {
+	user    *model.User
+	expires time.Time
+}
+
+// AuthService resolves API tokens to user identities. GitHub OAuth tokens, OIDC JWTs and
+// Pulumi access tokens are accepted. Resolved identities are cached by token hash so that
+// the issuers are not called on every request.
+type AuthService struct {
+	cfg           *config.Config
+	httpClient    *http.Client
+	pulumiService *PulumiService
+	oidc          *oidcVerifier
+	// githubAPIURL overrides the GitHub API endpoint, nil uses api.github.com
+	githubAPIURL *url.URL
+
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+// NewAuthService creates a new AuthService instance
+func NewAuthService(cfg *config.Config) *AuthService {
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	service := &AuthService{
+		cfg:        cfg,
+		httpClient: httpClient,
+		cache:      make(map[string]cachedIdentity),
+	}
+	if cfg.Auth.OIDCIssuer != "" {
+		// Without an audience no OIDC token is accepted, Validate stops the server at startup
+		service.oidc, _ = newOIDCVerifier(cfg.Auth.OIDCIssuer, cfg.Auth.OIDCAudience, cfg.Auth.OIDCGroupsClaim, httpClient)
+	}
+	return service
+}
+
+// Validate checks the authentication settings
+func (s *AuthService) Validate() error {
+	if s.cfg.Auth.OIDCIssuer == "" {
+		return nil
+	}
+	_, err := newOIDCVerifier(s.cfg.Auth.OIDCIssuer, s.cfg.Auth.OIDCAudience, s.cfg.Auth.OIDCGroupsClaim, s.httpClient)
+	return err
+}
+
+// SetPulumiService sets the Pulumi service used to resolve Pulumi access tokens
+func (s *AuthService) SetPulumiService(service *PulumiService) {
+	s.pulumiService = service
+}
+
+// Authenticate resolves a token to the user it was issued to, including their team memberships
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*model.User, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if user, ok := s.cached(key); ok {
+		return user, nil
+	}
+
+	var user *model.User
+	var err error
+	switch {
+	case strings.HasPrefix(token, pulumiTokenPrefix):
+		user, err = s.authenticatePulumi(ctx, token)
+	case strings.Count(token, ".") == 2:
+		if s.oidc == nil {
+			return nil, fmt.Errorf("%w: OIDC tokens are not accepted", ErrUnauthenticated)
+		}
+		user, err = s.oidc.verify(ctx, token)
+	default:
+		user, err = s.authenticateGitHub(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Teams == nil {
+		user.Teams = []string{}
+	}
+	s.store(key, user)
+	return user, nil
+}
+
+func (s *AuthService) cached(key string) (*model.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.user, true
+}
+
+func (s *AuthService) store(key string, user *model.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for cachedKey, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, cachedKey)
+		}
+	}
+	s.cache[key] = cachedIdentity{
+		user:    user,
+		expires: now.Add(s.cfg.Auth.IdentityCacheTTL),
+	}
+}
+
+// authenticateGitHub resolves a GitHub OAuth token to the GitHub user and the slugs of their teams.
+// Only members of the configured GitHub organization are accepted, and only the teams of that
+// organization count, since anyone can create an organization with a team of the same name.
+func (s *AuthService) authenticateGitHub(ctx context.Context, token string) (*model.User, error) {
+	organization := s.cfg.GitHub.Organization
+	if organization == "" {
+		return nil, fmt.Errorf("%w: no GitHub organization is configured", ErrUnauthenticated)
+	}
+
+	client := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	if s.githubAPIURL != nil {
+		client.BaseURL = s.githubAPIURL
+	}
+
+	githubUser, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("%w: GitHub rejected the token", ErrUnauthenticated)
+		}
+		return nil, fmt.Errorf("failed to get GitHub user: %w", err)
+	}
+
+	// Reading the membership needs the read:org scope, tokens without it are rejected as well
+	membership, resp, err := client.Organizations.GetOrgMembership(ctx, "", organization)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			return nil, fmt.Errorf("%w: %s is not a member of the GitHub organization %s", ErrUnauthenticated, githubUser.GetLogin(), organization)
+		}
+		return nil, fmt.Errorf("failed to get GitHub organization membership: %w", err)
+	}
+	if membership.GetState() != "active" {
+		return nil, fmt.Errorf("%w: %s is not a member of the GitHub organization %s", ErrUnauthenticated, githubUser.GetLogin(), organization)
+	}
+
+	user := &model.User{
+		Login:    githubUser.GetLogin(),
+		Name:     githubUser.GetName(),
+		Email:    githubUser.GetEmail(),
+		Provider: model.AuthProviderGitHub,
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		teams, resp, err := client.Teams.ListUserTeams(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub teams: %w", err)
+		}
+		for _, team := range teams {
+			if strings.EqualFold(team.GetOrganization().GetLogin(), organization) {
+				user.Teams = append(user.Teams, team.GetSlug())
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return user, nil
+}
+
+// authenticatePulumi resolves a Pulumi access token to the Pulumi user and the teams they are a member of
+func (s *AuthService) authenticatePulumi(ctx context.Context, token string) (*model.User, error) {
+	pulumiUser, err := s.pulumiService.GetUser(ctx, token)
+	if pulumiapi.StatusCode(err) == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: Pulumi rejected the token", ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Login:    pulumiUser.GithubLogin,
+		Name:     pulumiUser.Name,
+		Email:    pulumiUser.Email,
+		Provider: model.AuthProviderPulumi,
+	}
+
+	teams, err := s.pulumiService.GetTeams(ctx, s.cfg.Pulumi.Organization, token)
+	switch pulumiapi.StatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		// Pulumi hides the organizations a user is not a member of
+		return nil, fmt.Errorf("%w: %s is not a member of the Pulumi organization %s",
+			ErrUnauthenticated, user.Login, s.cfg.Pulumi.Organization)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Pulumi teams: %w", err)
+	}
+	for _, team := range teams.Teams {
+		if team.UserRole != "" && team.UserRole != "none" {
+			user.Teams = append(user.Teams, team.Name)
+		}
+	}
+
+	return user, nil
+}
+
+// userContextKey is the request context key of the authenticated user
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx that carries the authenticated user
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of a request, or nil outside of a request
+func UserFromContext(ctx context.Context) *model.User {
+	user, _ := ctx.Value(userContextKey{}).(*model.User)
+	return user
+}
+
+// withSystemUser attributes the work done with a context to the IDP itself
+func withSystemUser(ctx context.Context) context.Context {
+	return WithUser(ctx, &model.User{
+		Login:    model.SystemUser,
+		Provider: model.AuthProviderNone,
+		Teams:    []string{},
+	})
+}
+
+// identity returns the provider qualified login of the user a context belongs to, or an empty
+// string for background work. Authorization compares identities, never bare logins.
+func identity(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != nil {
+		return user.Identity()
+	}
+	return ""
+}
+
+// actor returns the login of the user a context belongs to, or an empty string for background work
+func actor(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != nil {
+		return user.Login
+	}
+	return ""
+}
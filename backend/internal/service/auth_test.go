@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+)
+
+// newTestGitHub serves the GitHub API endpoints used to resolve a user. memberships maps the
+// organizations to the membership state of the user, teams lists the teams of all organizations.
+func newTestGitHub(t *testing.T, memberships map[string]string, teams []map[string]interface{}) *url.URL {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"login": "alice"})
+	})
+	mux.HandleFunc("/user/memberships/orgs/{org}", func(w http.ResponseWriter, r *http.Request) {
+		state, ok := memberships[r.PathValue("org")]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"state": state})
+	})
+	mux.HandleFunc("/user/teams", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(teams)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baseURL
+}
+
+func newTestAuthService(organization string, githubAPIURL *url.URL) *AuthService {
+	cfg := &config.Config{}
+	cfg.GitHub.Organization = organization
+	service := NewAuthService(cfg)
+	service.githubAPIURL = githubAPIURL
+	return service
+}
+
+func team(slug, organization string) map[string]interface{} {
+	return map[string]interface{}{
+		"slug":         slug,
+		"organization": map[string]interface{}{"login": organization},
+	}
+}
+
+func TestAuthenticateGitHubKeepsOnlyTeamsOfTheOrganization(t *testing.T) {
+	githubAPI := newTestGitHub(t,
+		map[string]string{"acme": "active", "evil": "active"},
+		[]map[string]interface{}{team("developers", "acme"), team("admins", "evil"), team("platform", "Acme")},
+	)
+
+	user, err := newTestAuthService("acme", githubAPI).authenticateGitHub(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("authenticateGitHub failed: %v", err)
+	}
+	if len(user.Teams) != 2 || user.Teams[0] != "developers" || user.Teams[1] != "platform" {
+		t.Fatalf("expected the teams of acme only, got %v", user.Teams)
+	}
+	if user.Identity() != "github:alice" {
+		t.Fatalf("unexpected identity %s", user.Identity())
+	}
+}
+
+func TestAuthenticateGitHubRejectsNonMembers(t *testing.T) {
+	tests := map[string]map[string]string{
+		"no membership":      {"evil": "active"},
+		"pending invitation": {"acme": "pending"},
+	}
+	for name, memberships := range tests {
+		t.Run(name, func(t *testing.T) {
+			githubAPI := newTestGitHub(t, memberships, []map[string]interface{}{team("admins", "evil")})
+
+			_, err := newTestAuthService("acme", githubAPI).authenticateGitHub(context.Background(), "token")
+			if !errors.Is(err, ErrUnauthenticated) {
+				t.Fatalf("expected ErrUnauthenticated, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateGitHubRequiresOrganization(t *testing.T) {
+	githubAPI := newTestGitHub(t, map[string]string{"acme": "active"}, nil)
+
+	_, err := newTestAuthService("", githubAPI).authenticateGitHub(context.Background(), "token")
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+}
+
+func TestAuthenticatePulumiRejectsNonMembers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"githubLogin": "alice"})
+	})
+	mux.HandleFunc("/orgs/acme/teams", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":404,"message":"Organization 'acme' not found"}`, http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Pulumi.APIBaseURL = server.URL
+	cfg.Pulumi.Organization = "acme"
+	auth := NewAuthService(cfg)
+	auth.SetPulumiService(NewPulumiService(cfg))
+
+	_, err := auth.authenticatePulumi(context.Background(), "pul-token")
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+}
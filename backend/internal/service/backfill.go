@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// BackfillWorkloads adds the workload stacks created before the catalog existed to it and returns
+// how many were added. The stacks are found by their idp:workload tag, their owning team is the
+// team with the highest permission on the stack. Stacks that already have a record or are being
+// deleted are skipped, so the backfill can run at every start and on every replica.
+func (s *WorkloadService) BackfillWorkloads(ctx context.Context) (int, error) {
+	organization := s.cfg.Pulumi.Organization
+	owners, err := s.stackOwners(ctx, organization)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for stack, err := range s.pulumiService.Stacks(ctx, &model.ListStacksOptions{
+		Organization: organization,
+		TagName:      "idp:workload",
+	}) {
+		if err != nil {
+			return added, fmt.Errorf("failed to list workload stacks: %w", err)
+		}
+
+		_, err := s.workloads.FindByStack(ctx, organization, stack.ProjectName, stack.StackName)
+		if err == nil {
+			continue
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return added, err
+		}
+
+		// The stack list does not carry the tags
+		details, err := s.pulumiService.GetStack(ctx, stack.ProjectName, stack.StackName)
+		if err != nil {
+			s.logger.Printf("Failed to read stack %s/%s for the catalog backfill: %v", stack.ProjectName, stack.StackName, err)
+			continue
+		}
+		if details.Tags["idp:workload"] == "" || details.Tags["idp:auto-delete"] == "true" {
+			continue
+		}
+
+		record := backfilledRecord(organization, stack.ProjectName, stack.StackName, details.Tags,
+			owners[stack.ProjectName+"/"+stack.StackName])
+		created, err := s.workloads.CreateIfMissing(ctx, record)
+		if err != nil {
+			return added, err
+		}
+		if created {
+			added++
+			s.logger.Printf("Added workload %s/%s of team %q to the catalog", stack.ProjectName, stack.StackName, record.Team)
+		}
+	}
+	return added, nil
+}
+
+// stackOwners maps project/stack to the team with the highest permission on the stack
+func (s *WorkloadService) stackOwners(ctx context.Context, organization string) (map[string]string, error) {
+	teams, err := s.pulumiService.GetTeams(ctx, organization, s.cfg.Pulumi.APIToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	owners := make(map[string]string)
+	permissions := make(map[string]int)
+	for _, team := range teams.Teams {
+		details, err := s.pulumiService.GetTeam(ctx, organization, team.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read team %s: %w", team.Name, err)
+		}
+		for _, stack := range details.Stacks {
+			key := stack.ProjectName + "/" + stack.StackName
+			if stack.Permission > permissions[key] {
+				owners[key] = team.Name
+				permissions[key] = stack.Permission
+			}
+		}
+	}
+	return owners, nil
+}
+
+// backfilledRecord builds the catalog record of a workload stack from its idp:* tags
+func backfilledRecord(organization, project, stack string, tags map[string]string, team string) *model.WorkloadRecord {
+	record := &model.WorkloadRecord{
+		Organization:     organization,
+		Blueprint:        project,
+		Stack:            stack,
+		Name:             tags["idp:workload"],
+		BlueprintName:    project,
+		Stage:            tags["idp:stage"],
+		Team:             team,
+		ProjectID:        tags["idp:projectid"],
+		BlueprintVersion: tags["idp:blueprint-version"],
+		Status:           model.WorkloadStatusActive,
+		CreatedBy:        model.SystemUser,
+		CreationRequest: model.WorkloadRequest{
+			BlueprintName: project,
+			Blueprint:     project,
+			Name:          tags["idp:workload"],
+			ProjectID:     tags["idp:projectid"],
+			Stage:         tags["idp:stage"],
+			Team:          team,
+			Version:       tags["idp:blueprint-version"],
+		},
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, strings.TrimSpace(tags[model.ExpiresAtTag])); err == nil {
+		record.ExpiresAt = &expiresAt
+		record.CreationRequest.ExpiresAt = &expiresAt
+	}
+	return record
+}
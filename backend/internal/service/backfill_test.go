@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+func TestBackfillWorkloadsAddsTaggedStacks(t *testing.T) {
+	stacks := map[string]map[string]string{
+		"web/dev":     {"idp:workload": "dev", "idp:stage": "development", "idp:projectid": "123"},
+		"web/old":     {"idp:workload": "old", "idp:auto-delete": "true"},
+		"api/catalog": {"idp:workload": "catalog"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/stacks", func(w http.ResponseWriter, r *http.Request) {
+		list := []model.Stack{}
+		for _, key := range []string{"web/dev", "web/old", "api/catalog"} {
+			list = append(list, model.Stack{OrgName: "acme", ProjectName: key[:3], StackName: key[4:]})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"stacks": list})
+	})
+	mux.HandleFunc("/stacks/acme/{project}/{stack}", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(model.Stack{Tags: stacks[r.PathValue("project")+"/"+r.PathValue("stack")]})
+	})
+	mux.HandleFunc("/orgs/acme/teams", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(model.TeamsResponse{Teams: []model.Team{{Name: "readers"}, {Name: "payments"}}})
+	})
+	mux.HandleFunc("/orgs/acme/teams/{team}", func(w http.ResponseWriter, r *http.Request) {
+		permission := 101
+		if r.PathValue("team") == "payments" {
+			permission = 103
+		}
+		_ = json.NewEncoder(w).Encode(model.TeamDetails{
+			Name:   r.PathValue("team"),
+			Stacks: []model.StackPermission{{ProjectName: "web", StackName: "dev", Permission: permission}},
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Pulumi.APIBaseURL = server.URL
+	cfg.Pulumi.Organization = "acme"
+	workloads := repository.NewWorkloadRepository(newTestDB(t, &model.WorkloadRecord{}))
+	s := NewWorkloadService(cfg, workloads)
+	s.SetPulumiService(NewPulumiService(cfg))
+
+	ctx := context.Background()
+	existing := &model.WorkloadRecord{Organization: "acme", Blueprint: "api", Stack: "catalog", Team: "platform"}
+	if err := workloads.Create(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := s.BackfillWorkloads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Fatalf("expected one workload to be added, got %d", added)
+	}
+
+	record, err := workloads.FindByStack(ctx, "acme", "web", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Team != "payments" || record.Name != "dev" || record.Stage != "development" || record.Status != model.WorkloadStatusActive {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if _, err := workloads.FindByStack(ctx, "acme", "web", "old"); err == nil {
+		t.Fatal("a stack that is being deleted was added")
+	}
+	if record, _ := workloads.FindByStack(ctx, "acme", "api", "catalog"); record.Team != "platform" {
+		t.Fatalf("the existing record was changed: %+v", record)
+	}
+
+	if added, err := s.BackfillWorkloads(ctx); err != nil || added != 0 {
+		t.Fatalf("the second backfill added %d, %v", added, err)
+	}
+}
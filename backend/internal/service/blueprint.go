@@ -3,686 +3,639 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"reflect"
+	"log"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/github"
-	"github.com/labstack/echo/v4"
 	"github.com/pulumi-idp/internal/config"
 	"github.com/pulumi-idp/internal/model"
-	"golang.org/x/oauth2"
+	pulumiapi "github.com/pulumi-idp/internal/pulumi"
+	"github.com/pulumi-idp/internal/repository"
+	"golang.org/x/sync/singleflight"
 	"gopkg.in/yaml.v3"
 )
 
+// ErrInvalidWebhookPayload is returned for webhook deliveries that cannot be parsed
+var ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+
+const (
+	// maxPushEventCommits is the number of commits GitHub lists in a push event at most
+	maxPushEventCommits = 20
+	// catalogRebuildTimeout bounds rebuilding the catalog after a push
+	catalogRebuildTimeout = 2 * time.Minute
+	// blueprintLoadTimeout bounds a shared load of a cache entry, which no caller can cancel
+	blueprintLoadTimeout = time.Minute
+)
+
 // BlueprintService implements BlueprintServiceInterface
 type BlueprintService struct {
-	cfg        *config.Config
-	httpClient *http.Client
+	cfg          *config.Config
+	client       *pulumiapi.Client
+	sources      []BlueprintSource
+	cache        BlueprintCache
+	loads        singleflight.Group
+	auditService *AuditService
+	logger       *log.Logger
+
+	// rebuilds tracks the catalog rebuilds after pushes, so that shutdown can wait for them
+	rebuilds sync.WaitGroup
 }
 
 // NewBlueprintService creates a new BlueprintService instance
-func NewBlueprintService(cfg *config.Config) *BlueprintService {
+func NewBlueprintService(cfg *config.Config, cacheEntries *repository.CacheRepository) *BlueprintService {
 	return &BlueprintService{
-		cfg: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		cfg:     cfg,
+		client:  pulumiapi.NewClient(cfg),
+		sources: NewBlueprintSources(cfg),
+		cache:   NewBlueprintCache(cfg, cacheEntries),
+		logger:  log.New(log.Writer(), "[Blueprint] ", log.LstdFlags),
 	}
 }
 
-// GetBlueprints retrieves all available blueprints
-func (s *BlueprintService) GetBlueprints(c echo.Context) ([]model.Blueprint, error) {
-	cacheFilePath := "blueprints_cache.json"
-	cacheExpiration := 24 * time.Hour // Cache expires after 24 hours
-
-	// Try to read from cache first
-	blueprints, cacheValid := readFromCache(c, cacheFilePath, cacheExpiration)
-	if cacheValid {
-		c.Logger().Infof("Using cached blueprints data")
-		return blueprints, nil
-	}
-
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: s.cfg.GitHub.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	// Get repository content (top-level directories)
-
-	blueprintsGitHubLocation := s.cfg.Pulumi.BlueprintGithubLocation
-	owner := strings.Split(blueprintsGitHubLocation, "/")[0]
-	repo := strings.Split(blueprintsGitHubLocation, "/")[1]
-
-	var path string
-	if len(strings.Split(blueprintsGitHubLocation, "/")) > 2 {
-		path = strings.Split(blueprintsGitHubLocation, "/")[2]
-	} else {
-		path = ""
-	}
+func (s *BlueprintService) SetAuditService(service *AuditService) {
+	s.auditService = service
+}
 
-	_, directoryContent, _, err := client.Repositories.GetContents(
-		ctx,
-		owner,
-		repo,
-		path,
-		&github.RepositoryContentGetOptions{},
-	)
-	if err != nil {
-		c.Logger().Errorf("Failed to get repository contents: %v", err)
-		return nil, fmt.Errorf("failed to retrieve repository contents: %w", err)
-	}
+// GetBlueprints retrieves all available blueprints merged from the configured sources.
+// When several sources provide a blueprint with the same name, the first source wins.
+func (s *BlueprintService) GetBlueprints(ctx context.Context) ([]model.Blueprint, error) {
+	return cached(ctx, s, blueprintCatalogKey, s.cfg.Cache.TTL, s.loadBlueprints)
+}
 
-	blueprints = []model.Blueprint{}
+// loadBlueprints builds the catalog from the sources
+func (s *BlueprintService) loadBlueprints(ctx context.Context) ([]model.Blueprint, error) {
+	blueprints := []model.Blueprint{}
+	seen := make(map[string]bool)
+	failedSources := 0
+
+	for _, source := range s.sources {
+		names, err := source.ListBlueprints(ctx)
+		if err != nil {
+			s.logger.Printf("Failed to list blueprints of source %s: %v", source.Name(), err)
+			failedSources++
+			continue
+		}
 
-	// Iterate through directories
-	for _, content := range directoryContent {
-		if *content.Type == "dir" {
-			// Skip directories that typically don't contain Pulumi.yaml
-			if *content.Name == "scripts" {
+		for _, name := range names {
+			if seen[name] {
+				s.logger.Printf("Blueprint %s from %s is shadowed by another source", name, source.Name())
 				continue
 			}
 
-			// For each directory, try to get the Pulumi.yaml file
-			fileContent, _, _, err := client.Repositories.GetContents(
-				ctx,
-				owner,
-				repo,
-				fmt.Sprintf("%s/Pulumi.yaml", *content.Path),
-				&github.RepositoryContentGetOptions{},
-			)
-
+			content, err := s.readSourcePulumiYaml(ctx, source, name, "")
 			if err != nil {
-				c.Logger().Debugf("No Pulumi.yaml found in directory %s: %v", *content.Name, err)
+				s.logger.Printf("No Pulumi.yaml found for %s in %s: %v", name, source.Name(), err)
 				continue
 			}
 
-			// Decode file content
-			contentStr, err := fileContent.GetContent()
+			blueprint, err := parseBlueprint(name, content)
 			if err != nil {
-				c.Logger().Debugf("Failed to decode Pulumi.yaml content in %s: %v", *content.Name, err)
+				s.logger.Printf("Failed to parse Pulumi.yaml in %s: %v", name, err)
 				continue
 			}
+			blueprint.Source = source.Name()
 
-			// Parse YAML content
-			var pulumiYaml model.PulumiYaml
-			err = yaml.Unmarshal([]byte(contentStr), &pulumiYaml)
+			versions, err := s.sourceVersions(ctx, source, name)
 			if err != nil {
-				c.Logger().Debugf("Failed to parse Pulumi.yaml in %s: %v", *content.Name, err)
-				continue
-			}
-
-			// Log the actual structure of the runtime field for debugging
-			c.Logger().Debugf("Runtime field type: %v in %s", reflect.TypeOf(pulumiYaml.Runtime), *content.Name)
-
-			// Extract runtime using helper function
-			runtime := getRuntime(pulumiYaml.Runtime)
-
-			// Add blueprint to the list using the folder name and template description
-			blueprint := model.Blueprint{
-				Name:        *content.Name,
-				Author:      pulumiYaml.Author,
-				DisplayName: pulumiYaml.Template.DisplayName,
-				Description: pulumiYaml.Template.Description,
-				Runtime:     runtime,
+				s.logger.Printf("Failed to list versions of blueprint %s: %v", name, err)
+			} else if len(versions) > 0 {
+				blueprint.LatestVersion = versions[0].Version
 			}
 
-			tags := pulumiYaml.Config["pulumi:tags"]
-			blueprint.Tags = make(map[string]string)
-			if tags != nil {
-				for _, v := range tags.(map[string]interface{}) {
-					for k2, tag2 := range v.(map[string]interface{}) {
-						blueprint.Tags[k2] = tag2.(string)
-					}
-				}
-			}
-
-			c.Logger().Debugf("Successfully parsed blueprint: %s with runtime: %s", blueprint.Name, blueprint.Runtime)
+			s.logger.Printf("Successfully parsed blueprint: %s with runtime: %s", blueprint.Name, blueprint.Runtime)
+			seen[name] = true
 			blueprints = append(blueprints, blueprint)
 		}
 	}
 
-	// Save results to cache
-	saveToCache(c, cacheFilePath, blueprints)
+	if len(s.sources) > 0 && failedSources == len(s.sources) {
+		return nil, fmt.Errorf("failed to retrieve blueprints from any source")
+	}
 
 	return blueprints, nil
 }
 
-// getRuntime extracts the runtime name from different possible formats
-func getRuntime(runtimeField interface{}) string {
-	// If it's a string, return directly
-	if runtimeStr, ok := runtimeField.(string); ok {
-		return runtimeStr
+// InvalidateCache drops the cached catalog and versions together with the cached Pulumi.yaml
+// files and schemas of the named blueprints. Without names the whole cache is purged.
+func (s *BlueprintService) InvalidateCache(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return s.invalidatePrefixes(ctx, "")
 	}
 
-	// If it's a map, try to extract the name
-	if runtimeMap, ok := runtimeField.(map[string]interface{}); ok {
-		if name, exists := runtimeMap["name"]; exists {
-			if nameStr, ok := name.(string); ok {
-				return nameStr
-			}
-		}
+	prefixes := []string{blueprintCatalogKey, blueprintVersionsKey("")}
+	for _, name := range names {
+		prefixes = append(prefixes, blueprintKeyPrefix(name))
 	}
+	return s.invalidatePrefixes(ctx, prefixes...)
+}
 
-	// Try to marshal/unmarshal to handle complex types
-	if runtimeField != nil {
-		var runtimeObj model.RuntimeObject
-		// Convert to YAML and back to structured object
-		yamlBytes, err := yaml.Marshal(runtimeField)
-		if err == nil {
-			err = yaml.Unmarshal(yamlBytes, &runtimeObj)
-			if err == nil && runtimeObj.Name != "" {
-				return runtimeObj.Name
-			}
+// PurgeCache invalidates the cache on behalf of an admin and records it in the audit log
+func (s *BlueprintService) PurgeCache(ctx context.Context, names ...string) error {
+	err := s.InvalidateCache(ctx, names...)
+
+	if s.auditService != nil {
+		event := &model.AuditEvent{
+			Actor:        actor(ctx),
+			Action:       model.AuditActionBlueprintCachePurge,
+			Organization: s.cfg.Pulumi.Organization,
+			Outcome:      model.JobStatusSucceeded,
+			After: map[string]interface{}{
+				"blueprints": names,
+			},
 		}
+		if len(names) == 1 {
+			event.Blueprint = names[0]
+		}
+		if err != nil {
+			event.Outcome = model.JobStatusFailed
+			event.Error = err.Error()
+		}
+		s.auditService.Record(ctx, event)
 	}
 
-	return "unknown"
+	return err
 }
 
-// GetBlueprintSchema retrieves the JSON schema for a specific blueprint
-func (s *BlueprintService) GetBlueprintSchema(c echo.Context, name string) (map[string]interface{}, error) {
-	// Fetch from GitHub
-	ctx := context.Background()
-
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: s.cfg.GitHub.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	blueprintsGitHubLocation := s.cfg.Pulumi.BlueprintGithubLocation
-	owner := strings.Split(blueprintsGitHubLocation, "/")[0]
-	repo := strings.Split(blueprintsGitHubLocation, "/")[1]
-
-	// Get the Pulumi.yaml file for the specified blueprint
-	fileContent, _, _, err := client.Repositories.GetContents(
-		ctx,
-		owner,
-		repo,
-		fmt.Sprintf("%s/Pulumi.yaml", name),
-		&github.RepositoryContentGetOptions{},
-	)
-
-	if err != nil {
-		c.Logger().Errorf("Failed to get Pulumi.yaml for blueprint %s: %v", name, err)
-		return nil, fmt.Errorf("blueprint %s not found: %w", name, err)
+// RefreshFromPush invalidates the cache for a push event to the PULUMI_BLUEPRINT_GITHUB_LOCATION
+// repository and rebuilds the catalog in the background. A push to the default branch invalidates
+// the blueprints whose directories changed, a tag push the versions. It returns nil for pushes
+// that do not concern the catalog.
+func (s *BlueprintService) RefreshFromPush(ctx context.Context, payload []byte) (*model.BlueprintRefresh, error) {
+	var event github.PushEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
 	}
 
-	// Decode file content
-	contentStr, err := fileContent.GetContent()
+	location, err := NewGitHubBlueprintSource("", s.cfg.Pulumi.BlueprintGithubLocation)
 	if err != nil {
-		c.Logger().Errorf("Failed to decode Pulumi.yaml content for %s: %v", name, err)
-		return nil, fmt.Errorf("failed to read blueprint configuration: %w", err)
+		return nil, nil
+	}
+	if !strings.EqualFold(event.GetRepo().GetFullName(), location.owner+"/"+location.repo) {
+		return nil, nil
+	}
+
+	refresh := &model.BlueprintRefresh{
+		Ref:        event.GetRef(),
+		Blueprints: []string{},
+	}
+	defaultBranch := event.GetRepo().GetDefaultBranch()
+	if defaultBranch == "" {
+		defaultBranch = event.GetRepo().GetMasterBranch()
+	}
+
+	switch {
+	case strings.HasPrefix(refresh.Ref, "refs/tags/"):
+		// A tag adds or moves a version, the content at the other refs stays the same
+		err = s.invalidatePrefixes(ctx, blueprintCatalogKey, blueprintVersionsKey(""))
+	case defaultBranch != "" && refresh.Ref != "refs/heads/"+defaultBranch:
+		// The catalog is read from the default branch only
+		return nil, nil
+	case event.GetForced() || len(event.Commits) == 0 || len(event.Commits) >= maxPushEventCommits:
+		// The commits of the push are not all listed, so the changed directories are unknown
+		refresh.All = true
+		err = s.InvalidateCache(ctx)
+	default:
+		var files []string
+		for _, commit := range event.Commits {
+			files = append(files, commit.Added...)
+			files = append(files, commit.Removed...)
+			files = append(files, commit.Modified...)
+		}
+		refresh.Blueprints = location.changedBlueprints(files)
+		if len(refresh.Blueprints) == 0 {
+			return refresh, nil
+		}
+		prefixes := []string{blueprintCatalogKey}
+		for _, name := range refresh.Blueprints {
+			prefixes = append(prefixes, blueprintKeyPrefix(name))
+		}
+		err = s.invalidatePrefixes(ctx, prefixes...)
 	}
-
-	// Parse YAML content
-	var pulumiYaml model.PulumiYaml
-	err = yaml.Unmarshal([]byte(contentStr), &pulumiYaml)
 	if err != nil {
-		c.Logger().Errorf("Failed to parse Pulumi.yaml for %s: %v", name, err)
-		return nil, fmt.Errorf("failed to parse blueprint configuration: %w", err)
+		return nil, fmt.Errorf("failed to invalidate the blueprint cache: %w", err)
 	}
 
-	// Convert the config to property overrides
-	var propertyOverrides []model.PropertyOverride
-
-	for propName, propConfigRaw := range pulumiYaml.Template.Config {
-		po := model.PropertyOverride{
-			Name: propName,
-			Type: "string", // Default type
-		}
-
-		// Process the config property based on its actual type
-		switch config := propConfigRaw.(type) {
-		case map[interface{}]interface{}:
-			// This is typically how YAML parses nested maps
-			if descVal, ok := config["displayName"]; ok {
-				if desc, ok := descVal.(string); ok {
-					po.Title = desc
-				}
-			}
+	s.logger.Printf("Push to %s invalidated blueprints %v (all: %t)", refresh.Ref, refresh.Blueprints, refresh.All)
+	s.rebuilds.Add(1)
+	go func() {
+		defer s.rebuilds.Done()
+		s.rebuildCatalog(context.WithoutCancel(ctx))
+	}()
 
-			if typeVal, ok := config["type"]; ok {
-				if typeStr, ok := typeVal.(string); ok {
-					po.Type = typeStr
-				}
-			}
+	return refresh, nil
+}
 
-			if defaultVal, ok := config["default"]; ok {
-				po.Default = fmt.Sprintf("%v", defaultVal)
-			}
+// Shutdown waits for the catalog rebuilds in flight until the context is done
+func (s *BlueprintService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.rebuilds.Wait()
+		close(done)
+	}()
 
-			if secretVal, ok := config["secret"]; ok {
-				if boolVal, ok := secretVal.(bool); ok && boolVal {
-					c.Logger().Debugf("Config %s is marked as secret", propName)
-				}
-			}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("catalog rebuild still running: %w", ctx.Err())
+	}
+}
 
-			if enumVal, ok := config["enum"]; ok {
-				if enumArr, ok := enumVal.([]interface{}); ok {
-					enum := make([]string, len(enumArr))
-					for i, e := range enumArr {
-						enum[i] = fmt.Sprintf("%v", e)
-					}
-					po.Enum = enum
-				}
-			}
+// rebuildCatalog fills the cache with the catalog again after an invalidation
+func (s *BlueprintService) rebuildCatalog(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, catalogRebuildTimeout)
+	defer cancel()
 
-			if enumNamesVal, ok := config["enumNames"]; ok {
-				if enumArr, ok := enumNamesVal.([]interface{}); ok {
-					enumNames := make([]string, len(enumArr))
-					for i, e := range enumArr {
-						enumNames[i] = fmt.Sprintf("%v", e)
-					}
-					po.EnumNames = enumNames
-				}
-			}
+	if _, err := s.GetBlueprints(ctx); err != nil {
+		s.logger.Printf("Failed to rebuild the blueprint catalog: %v", err)
+	}
+}
 
-		case map[string]interface{}:
-			// This could happen if the YAML parser already converted the keys to strings
-			if descVal, ok := config["displayName"]; ok {
-				if desc, ok := descVal.(string); ok {
-					po.Title = desc
-				}
-			}
+// invalidatePrefixes removes the keys with the prefixes. Every removal starts a new cache
+// generation, which keeps loads that are in flight from storing what they read before.
+func (s *BlueprintService) invalidatePrefixes(ctx context.Context, prefixes ...string) error {
+	for _, prefix := range prefixes {
+		if err := s.cache.DeletePrefix(ctx, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			if typeVal, ok := config["type"]; ok {
-				if typeStr, ok := typeVal.(string); ok {
-					po.Type = typeStr
-				}
-			}
+// readSourcePulumiYaml returns the cached Pulumi.yaml of a blueprint in a source at a git ref
+func (s *BlueprintService) readSourcePulumiYaml(ctx context.Context, source BlueprintSource, name, ref string) ([]byte, error) {
+	return cached(ctx, s, pulumiYamlKey(name, source.Name(), ref), s.cfg.Cache.TTL, func(ctx context.Context) ([]byte, error) {
+		return source.ReadPulumiYaml(ctx, name, ref)
+	})
+}
 
-			if defaultVal, ok := config["default"]; ok {
-				po.Default = fmt.Sprintf("%v", defaultVal)
-			}
+// parseBlueprint builds the catalog entry for a blueprint from its Pulumi.yaml
+func parseBlueprint(name string, content []byte) (model.Blueprint, error) {
+	var pulumiYaml model.PulumiYaml
+	if err := yaml.Unmarshal(content, &pulumiYaml); err != nil {
+		return model.Blueprint{}, err
+	}
 
-			if secretVal, ok := config["secret"]; ok {
-				if boolVal, ok := secretVal.(bool); ok && boolVal {
-					c.Logger().Debugf("Config %s is marked as secret", propName)
-				}
-			}
+	// Add blueprint to the list using the folder name and template description
+	blueprint := model.Blueprint{
+		Name:        name,
+		Author:      pulumiYaml.Author,
+		DisplayName: pulumiYaml.Template.DisplayName,
+		Description: pulumiYaml.Template.Description,
+		Runtime:     getRuntime(pulumiYaml.Runtime),
+		Tags:        make(map[string]string),
+	}
 
-			if enumVal, ok := config["enum"]; ok {
-				if enumArr, ok := enumVal.([]interface{}); ok {
-					enum := make([]string, len(enumArr))
-					for i, e := range enumArr {
-						enum[i] = fmt.Sprintf("%v", e)
-					}
-					po.Enum = enum
+	if tags, ok := pulumiYaml.Config["pulumi:tags"].(map[string]interface{}); ok {
+		for _, v := range tags {
+			if values, ok := v.(map[string]interface{}); ok {
+				for k2, tag2 := range values {
+					blueprint.Tags[k2] = fmt.Sprintf("%v", tag2)
 				}
 			}
+		}
+	}
 
-			if enumNamesVal, ok := config["enumNames"]; ok {
-				if enumArr, ok := enumNamesVal.([]interface{}); ok {
-					enumNames := make([]string, len(enumArr))
-					for i, e := range enumArr {
-						enumNames[i] = fmt.Sprintf("%v", e)
-					}
-					po.EnumNames = enumNames
-				}
-			}
+	return blueprint, nil
+}
 
-		case string:
-			// If config is just a string, use it as the description/title
-			po.Title = config
-
-		default:
-			// For any other type, try to get a string representation
-			c.Logger().Debugf("Unexpected config type for %s: %T", propName, propConfigRaw)
-			if str, ok := propConfigRaw.(string); ok {
-				po.Title = str
-			} else {
-				po.Title = fmt.Sprintf("%v", propConfigRaw)
-			}
-		}
+// readPulumiYaml returns the parsed Pulumi.yaml of a blueprint from the first source providing it.
+// An empty ref reads the default branch.
+func (s *BlueprintService) readPulumiYaml(ctx context.Context, name, ref string) (*model.PulumiYaml, BlueprintSource, error) {
+	content, source, err := s.readPulumiYamlContent(ctx, name, ref)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		propertyOverrides = append(propertyOverrides, po)
+	var pulumiYaml model.PulumiYaml
+	if err := yaml.Unmarshal(content, &pulumiYaml); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse blueprint configuration: %w", err)
 	}
+	return &pulumiYaml, source, nil
+}
 
-	// Create blueprint object
-	blueprintConfig := model.BlueprintO{
-		DisplayName:       pulumiYaml.Template.DisplayName,
-		Description:       pulumiYaml.Template.Description,
-		PropertyOverrides: propertyOverrides,
+func (s *BlueprintService) readPulumiYamlContent(ctx context.Context, name, ref string) ([]byte, BlueprintSource, error) {
+	// The default branch decides which source owns the blueprint
+	source, content, err := s.findSource(ctx, name)
+	if err != nil || ref == "" {
+		return content, source, err
 	}
 
-	// If there's metadata in the template, you might want to handle it here
-	if len(pulumiYaml.Template.Metadata) > 0 {
-		c.Logger().Debugf("Blueprint %s has metadata: %v", name, pulumiYaml.Template.Metadata)
-		// Optionally add metadata to the blueprint or schema
+	content, err = s.readSourcePulumiYaml(ctx, source, name, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read blueprint %s at %s: %w", name, ref, err)
 	}
+	return content, source, nil
+}
 
-	// Get the ESC tag value safely
-	var escTag string
-	if escTagVal, ok := pulumiYaml.Config["esc:tag"]; ok {
-		if escTagStr, ok := escTagVal.(string); ok {
-			escTag = escTagStr
+// findSource returns the first source providing the blueprint on its default branch,
+// together with the Pulumi.yaml found there
+func (s *BlueprintService) findSource(ctx context.Context, name string) (BlueprintSource, []byte, error) {
+	for _, source := range s.sources {
+		if content, err := s.readSourcePulumiYaml(ctx, source, name, ""); err == nil {
+			return source, content, nil
 		}
 	}
 
-	schema := s.convertToJSONSchema(blueprintConfig.PropertyOverrides, escTag, blueprintConfig.DisplayName, blueprintConfig.Description)
-
-	return schema, nil
+	return nil, nil, fmt.Errorf("blueprint %s not found", name)
 }
 
-// GetBlueprintUISchema retrieves the UI schema for a specific blueprint
-func (s *BlueprintService) GetBlueprintUISchema(c echo.Context, name string) (map[string]map[string]interface{}, error) {
-	// Fetch from GitHub
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: s.cfg.GitHub.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	blueprintsGitHubLocation := s.cfg.Pulumi.BlueprintGithubLocation
-	owner := strings.Split(blueprintsGitHubLocation, "/")[0]
-	repo := strings.Split(blueprintsGitHubLocation, "/")[1]
-
-	// Get the Pulumi.yaml file for the specified blueprint
-	fileContent, _, _, err := client.Repositories.GetContents(
-		ctx,
-		owner,
-		repo,
-		fmt.Sprintf("%s/Pulumi.yaml", name),
-		&github.RepositoryContentGetOptions{},
-	)
-
+// sourceVersions returns the versions a source provides for a blueprint, newest first
+func (s *BlueprintService) sourceVersions(ctx context.Context, source BlueprintSource, name string) ([]model.BlueprintVersion, error) {
+	versions, err := cached(ctx, s, blueprintVersionsKey(source.Name()), s.cfg.Cache.TTL, source.ListVersions)
 	if err != nil {
-		c.Logger().Errorf("Failed to get Pulumi.yaml for blueprint %s: %v", name, err)
-		return nil, fmt.Errorf("blueprint %s not found: %w", name, err)
+		return nil, err
 	}
+	return versionsForBlueprint(name, versions), nil
+}
 
-	// Decode file content
-	contentStr, err := fileContent.GetContent()
+// GetBlueprintVersions returns the released versions of a blueprint, newest first
+func (s *BlueprintService) GetBlueprintVersions(ctx context.Context, name string) ([]model.BlueprintVersion, error) {
+	source, _, err := s.findSource(ctx, name)
 	if err != nil {
-		c.Logger().Errorf("Failed to decode Pulumi.yaml content for %s: %v", name, err)
-		return nil, fmt.Errorf("failed to read blueprint configuration: %w", err)
+		return nil, err
 	}
+	return s.sourceVersions(ctx, source, name)
+}
 
-	// Parse YAML content
-	var pulumiYaml model.PulumiYaml
-	err = yaml.Unmarshal([]byte(contentStr), &pulumiYaml)
+// LatestBlueprintVersion returns the newest released version of a blueprint,
+// or "" if the blueprint has no versions and is deployed from the default branch.
+func (s *BlueprintService) LatestBlueprintVersion(ctx context.Context, name string) (string, error) {
+	versions, err := s.GetBlueprintVersions(ctx, name)
 	if err != nil {
-		c.Logger().Errorf("Failed to parse Pulumi.yaml for %s: %v", name, err)
-		return nil, fmt.Errorf("failed to parse blueprint configuration: %w", err)
+		return "", err
 	}
-
-	// Create UI Schema JSON
-	uiSchema := map[string]map[string]interface{}{
-		"advanced": {
-			"items": make(map[string]interface{}),
-		},
+	if len(versions) == 0 {
+		return "", nil
 	}
+	return versions[0].Version, nil
+}
 
-	// Process each config property
-	for propName, propConfigRaw := range pulumiYaml.Template.Config {
-		uiFieldSchema := make(map[string]interface{})
-
-		// Extract property details based on the type of the config
-		switch config := propConfigRaw.(type) {
-		case map[interface{}]interface{}:
-			// Process description
-			if descVal, ok := config["description"]; ok {
-				if desc, ok := descVal.(string); ok && desc != "" {
-					uiFieldSchema["ui:description"] = desc
-					uiFieldSchema["ui:enableMarkdownInDescription"] = true
-				}
-			}
+// GetBlueprintLocation returns where the given version of a blueprint is deployed from.
+// An empty version deploys the default branch. Blueprints from sources without git backing
+// fall back to the PULUMI_BLUEPRINT_GITHUB_LOCATION repository.
+func (s *BlueprintService) GetBlueprintLocation(ctx context.Context, name, version string) (model.BlueprintLocation, error) {
+	source, _, err := s.findSource(ctx, name)
+	if err != nil {
+		if version != "" {
+			return model.BlueprintLocation{}, err
+		}
+		return s.fallbackLocation(name), nil
+	}
 
-			// Check if it's a secret
-			if secretVal, ok := config["secret"]; ok {
-				if boolVal, ok := secretVal.(bool); ok && boolVal {
-					uiFieldSchema["ui:widget"] = "password"
-					uiFieldSchema["ui:help"] = "This is a sensitive value"
-				}
-			}
+	location := source.Location(name)
+	if location.RepoURL == "" {
+		location = s.fallbackLocation(name)
+	}
 
-			// Add specific widgets based on type if applicable
-			if typeVal, ok := config["type"]; ok {
-				if typeStr, ok := typeVal.(string); ok {
-					switch typeStr {
-					case "number", "integer":
-						uiFieldSchema["ui:widget"] = "updown"
-					case "boolean":
-						// No special UI needed for boolean, the default checkbox works well
-					case "array":
-						// Could add specific array handling if needed
-					case "object":
-						// Could add specific object handling if needed
-					}
-				}
-			}
+	if version == "" {
+		return location, nil
+	}
 
-			// Add placeholder if helpful
-			if defaultVal, ok := config["default"]; ok {
-				uiFieldSchema["ui:placeholder"] = fmt.Sprintf("Default: %v", defaultVal)
-			}
+	versions, err := s.sourceVersions(ctx, source, name)
+	if err != nil {
+		return model.BlueprintLocation{}, fmt.Errorf("failed to list versions of blueprint %s: %w", name, err)
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			location.Version = v.Version
+			location.Ref = v.Ref
+			location.Commit = v.Commit
+			return location, nil
+		}
+	}
 
-		case map[string]interface{}:
-			// Process description
-			if descVal, ok := config["description"]; ok {
-				if desc, ok := descVal.(string); ok && desc != "" {
-					uiFieldSchema["ui:help"] = desc
-					uiFieldSchema["ui:enableMarkdownInDescription"] = true
-				}
-			}
+	return model.BlueprintLocation{}, fmt.Errorf("version %s of blueprint %s not found", version, name)
+}
 
-			// Check if it's a secret
-			if secretVal, ok := config["secret"]; ok {
-				if boolVal, ok := secretVal.(bool); ok && boolVal {
-					uiFieldSchema["ui:widget"] = "password"
-				}
-			}
+func (s *BlueprintService) fallbackLocation(name string) model.BlueprintLocation {
+	return model.BlueprintLocation{
+		RepoURL: fmt.Sprintf("https://github.com/%s.git", s.cfg.Pulumi.BlueprintGithubLocation),
+		RepoDir: name,
+	}
+}
 
-			// Add specific widgets based on type if applicable
-			if typeVal, ok := config["type"]; ok {
-				if typeStr, ok := typeVal.(string); ok {
-					switch typeStr {
-					case "number", "integer":
-						uiFieldSchema["ui:widget"] = "updown"
-					case "boolean":
-						// No special UI needed for boolean, the default checkbox works well
-					case "array":
-						// Could add specific array handling if needed
-					case "object":
-						// Could add specific object handling if needed
-					}
-				}
-			}
+// NewRequiredConfig returns the required config keys that the target version of a blueprint
+// declares and the current version does not, sorted by name. An empty version reads the default branch.
+func (s *BlueprintService) NewRequiredConfig(ctx context.Context, name, currentVersion, targetVersion string) ([]string, error) {
+	_, current, err := s.readConfigSchema(ctx, name, currentVersion)
+	if err != nil {
+		return nil, err
+	}
 
-			// Add placeholder if helpful
-			if defaultVal, ok := config["default"]; ok {
-				uiFieldSchema["ui:placeholder"] = fmt.Sprintf("Default: %v", defaultVal)
-				uiFieldSchema["ui:widget"] = "DefaultValueOverrideWidget"
-			}
+	_, target, err := s.readConfigSchema(ctx, name, targetVersion)
+	if err != nil {
+		return nil, err
+	}
 
-		case string:
-			// If config is just a string, it's a simple description
-			if config != "" {
-				uiFieldSchema["ui:description"] = config
-				uiFieldSchema["ui:enableMarkdownInDescription"] = true
-			}
-		}
+	known := make(map[string]bool, len(current))
+	for _, property := range current {
+		known[property.Name] = true
+	}
 
-		// Only add the property to the UI schema if we have UI elements to specify
-		if len(uiFieldSchema) > 0 {
-			// Add this property to the items map without overwriting the existing entries
-			uiSchema["advanced"]["items"].(map[string]interface{})[propName] = uiFieldSchema
+	// Properties are sorted by name already
+	newConfig := []string{}
+	for _, property := range target {
+		if property.Required && !known[property.Name] {
+			newConfig = append(newConfig, property.Name)
 		}
 	}
 
-	return uiSchema, nil
+	return newConfig, nil
 }
 
-// ReadFromCache reads blueprints from the cache file if it exists and is not expired
-func readFromCache(c echo.Context, cacheFilePath string, cacheExpiration time.Duration) ([]model.Blueprint, bool) {
-	// Check if cache file exists
-	fileInfo, err := os.Stat(cacheFilePath)
+// readVersionedPulumiYaml reads the Pulumi.yaml of a released blueprint version
+func (s *BlueprintService) readVersionedPulumiYaml(ctx context.Context, name, version string) (*model.PulumiYaml, error) {
+	location, err := s.GetBlueprintLocation(ctx, name, version)
 	if err != nil {
-		if os.IsNotExist(err) {
-			c.Logger().Debugf("Cache file does not exist: %s", cacheFilePath)
-		} else {
-			c.Logger().Warnf("Error checking cache file: %v", err)
-		}
-		return nil, false
+		return nil, err
 	}
 
-	// Check if cache is expired
-	if time.Since(fileInfo.ModTime()) > cacheExpiration {
-		c.Logger().Debugf("Cache is expired: %s", cacheFilePath)
-		return nil, false
+	pulumiYaml, _, err := s.readPulumiYaml(ctx, name, location.Ref)
+	return pulumiYaml, err
+}
+
+// getRuntime extracts the runtime name from different possible formats
+func getRuntime(runtimeField interface{}) string {
+	// If it's a string, return directly
+	if runtimeStr, ok := runtimeField.(string); ok {
+		return runtimeStr
 	}
 
-	// Read cache file
-	cacheData, err := os.ReadFile(cacheFilePath)
-	if err != nil {
-		c.Logger().Warnf("Error reading cache file: %v", err)
-		return nil, false
+	// If it's a map, try to extract the name
+	if runtimeMap, ok := runtimeField.(map[string]interface{}); ok {
+		if name, exists := runtimeMap["name"]; exists {
+			if nameStr, ok := name.(string); ok {
+				return nameStr
+			}
+		}
 	}
 
-	// Parse cache data
-	var blueprints []model.Blueprint
-	err = json.Unmarshal(cacheData, &blueprints)
-	if err != nil {
-		c.Logger().Warnf("Error parsing cache data: %v", err)
-		return nil, false
+	// Try to marshal/unmarshal to handle complex types
+	if runtimeField != nil {
+		var runtimeObj model.RuntimeObject
+		// Convert to YAML and back to structured object
+		yamlBytes, err := yaml.Marshal(runtimeField)
+		if err == nil {
+			err = yaml.Unmarshal(yamlBytes, &runtimeObj)
+			if err == nil && runtimeObj.Name != "" {
+				return runtimeObj.Name
+			}
+		}
 	}
 
-	// Cache is valid and parsed successfully
-	return blueprints, true
+	return "unknown"
 }
 
-// SaveToCache saves blueprints to the cache file
-func saveToCache(c echo.Context, cacheFilePath string, blueprints []model.Blueprint) {
-	// Serialize blueprints to JSON
-	cacheData, err := json.Marshal(blueprints)
-	if err != nil {
-		c.Logger().Warnf("Error serializing blueprints to JSON: %v", err)
-		return
-	}
+// blueprintSchema is the cached part of the JSON schema of a blueprint. The stage options are
+// added on every read, as ESC environments change without a change to the blueprint.
+type blueprintSchema struct {
+	Schema map[string]interface{} `json:"schema"`
+	ESCTag string                 `json:"escTag,omitempty"`
+}
 
-	// Write to cache file
-	err = os.WriteFile(cacheFilePath, cacheData, 0644)
+// GetBlueprintSchema retrieves the JSON schema for a specific blueprint.
+// An empty version reads the default branch.
+func (s *BlueprintService) GetBlueprintSchema(ctx context.Context, name, version string) (map[string]interface{}, error) {
+	cachedSchema, err := cached(ctx, s, blueprintSchemaKey(name, version), s.cfg.Cache.SchemaTTL, func(ctx context.Context) (blueprintSchema, error) {
+		return s.loadBlueprintSchema(ctx, name, version)
+	})
 	if err != nil {
-		c.Logger().Warnf("Error writing cache file: %v", err)
-		return
+		return nil, err
 	}
 
-	c.Logger().Infof("Successfully updated cache file: %s", cacheFilePath)
+	schema := cachedSchema.Schema
+	schema["stage"] = s.stageSchema(ctx, cachedSchema.ESCTag)
+	return schema, nil
 }
 
-// ConvertToJSONSchema converts property overrides to a JSON schema
-func (s *BlueprintService) convertToJSONSchema(overrides []model.PropertyOverride, esc, name, description string) map[string]interface{} {
-	properties := make(map[string]interface{})
-	required := []string{}
-
-	for _, override := range overrides {
-		properties[override.Name] = map[string]interface{}{
-			"type": override.Type,
-		}
-		if override.Title != "" {
-			properties[override.Name].(map[string]interface{})["title"] = override.Title
-		} else {
-			properties[override.Name].(map[string]interface{})["title"] = override.Name
-		}
-		if len(override.Enum) > 0 {
-			properties[override.Name].(map[string]interface{})["enum"] = override.Enum
-		}
-		if len(override.EnumNames) > 0 {
-			properties[override.Name].(map[string]interface{})["enumNames"] = override.EnumNames
-		}
-		if override.Default != "" {
-			properties[override.Name].(map[string]interface{})["default"] = override.Default
-		}
-		if len(override.Items) > 0 {
-			properties[override.Name].(map[string]interface{})["items"] = override.Items
-		}
+func (s *BlueprintService) loadBlueprintSchema(ctx context.Context, name, version string) (blueprintSchema, error) {
+	pulumiYaml, properties, err := s.readConfigSchema(ctx, name, version)
+	if err != nil {
+		s.logger.Printf("Failed to get config schema for blueprint %s: %v", name, err)
+		return blueprintSchema{}, err
+	}
 
-		if override.Default == "" {
-			required = append(required, override.Name)
+	// Get the ESC tag value safely
+	var escTag string
+	if escTagVal, ok := pulumiYaml.Config["esc:tag"]; ok {
+		if escTagStr, ok := escTagVal.(string); ok {
+			escTag = escTagStr
 		}
 	}
 
-	var escEnvironment map[string]interface{}
-	if esc != "" {
-		environmentsResp, err := s.GetEnvironmentsForUserAndTag("", esc)
-		if err == nil && environmentsResp != nil {
-			oneOfOptions := make([]map[string]interface{}, 0, len(environmentsResp.Environments))
+	return blueprintSchema{
+		Schema: convertToJSONSchema(properties, pulumiYaml.Template.DisplayName, pulumiYaml.Template.Description),
+		ESCTag: escTag,
+	}, nil
+}
 
-			for _, env := range environmentsResp.Environments {
-				option := map[string]interface{}{
-					"const": fmt.Sprintf("%s/%s", env.Project, env.Name),
-					"title": env.Name,
-				}
-				oneOfOptions = append(oneOfOptions, option)
-			}
+// GetBlueprintUISchema retrieves the UI schema for a specific blueprint.
+// An empty version reads the default branch.
+func (s *BlueprintService) GetBlueprintUISchema(ctx context.Context, name, version string) (map[string]map[string]interface{}, error) {
+	return cached(ctx, s, blueprintUISchemaKey(name, version), s.cfg.Cache.SchemaTTL, func(ctx context.Context) (map[string]map[string]interface{}, error) {
+		return s.loadBlueprintUISchema(ctx, name, version)
+	})
+}
 
-			escEnvironment = map[string]interface{}{
-				"type":  "string",
-				"oneOf": oneOfOptions,
-			}
-		} else {
-			fmt.Printf("Error fetching environments: %v\n", err)
-		}
+func (s *BlueprintService) loadBlueprintUISchema(ctx context.Context, name, version string) (map[string]map[string]interface{}, error) {
+	_, properties, err := s.readConfigSchema(ctx, name, version)
+	if err != nil {
+		s.logger.Printf("Failed to get config schema for blueprint %s: %v", name, err)
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"type":        "object",
-		"stage":       escEnvironment,
-		"properties":  properties,
-		"required":    required,
-		"title":       name,
-		"description": description,
+	uiSchema := map[string]map[string]interface{}{
+		"advanced": {
+			"items": configUISchema(properties),
+		},
 	}
-}
 
-// GetEnvironmentsForUserAndTag retrieves environments for a user and tag
-func (s *BlueprintService) GetEnvironmentsForUserAndTag(continuationToken, tag string) (*model.EnvironmentsResponse0, error) {
-	url := fmt.Sprintf("%s/esc/environments/%s", s.cfg.Pulumi.APIBaseURL, s.cfg.Pulumi.Organization)
+	return uiSchema, nil
+}
 
-	if continuationToken != "" {
-		url = fmt.Sprintf("%s?continuationToken=%s", url, continuationToken)
+// readConfigSchema reads a blueprint version and parses its template config
+func (s *BlueprintService) readConfigSchema(ctx context.Context, name, version string) (*model.PulumiYaml, []model.ConfigProperty, error) {
+	pulumiYaml, err := s.readVersionedPulumiYaml(ctx, name, version)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	properties, err := parseConfigSchema(pulumiYaml.Template.Config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, nil, fmt.Errorf("invalid config schema in blueprint %s: %w", name, err)
 	}
 
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
+	return pulumiYaml, properties, nil
+}
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+// convertToJSONSchema converts the blueprint config properties to a JSON schema
+func convertToJSONSchema(properties []model.ConfigProperty, name, description string) map[string]interface{} {
+	schema := configObjectSchema(properties)
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = name
+	schema["description"] = description
+
+	return schema
+}
+
+// stageSchema lists the ESC environments tagged with the esc tag of a blueprint as the stages
+// to choose from. It returns nil for blueprints without tag or when the environments cannot be read.
+func (s *BlueprintService) stageSchema(ctx context.Context, esc string) map[string]interface{} {
+	if esc == "" {
+		return nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	environmentsResp, err := s.GetEnvironmentsForUserAndTag(ctx, "", esc)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		s.logger.Printf("Error fetching environments: %v", err)
+		return nil
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	oneOfOptions := make([]map[string]interface{}, 0, len(environmentsResp.Environments))
+	for _, env := range environmentsResp.Environments {
+		option := map[string]interface{}{
+			"const": fmt.Sprintf("%s/%s", env.Project, env.Name),
+			"title": env.Name,
+		}
+		oneOfOptions = append(oneOfOptions, option)
 	}
-	var envResponse model.EnvironmentsResponse0
-	if err := json.Unmarshal(body, &envResponse); err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+
+	return map[string]interface{}{
+		"type":  "string",
+		"oneOf": oneOfOptions,
 	}
+}
+
+// GetEnvironmentsForUserAndTag retrieves the environments tagged with the esc tag from all pages,
+// starting at the continuation token if it is set
+func (s *BlueprintService) GetEnvironmentsForUserAndTag(ctx context.Context, continuationToken, tag string) (*model.EnvironmentsResponse0, error) {
+	environments := pulumiapi.Paginate(ctx, continuationToken, func(ctx context.Context, token string) ([]model.Environment0, string, error) {
+		query := url.Values{}
+		if token != "" {
+			query.Set("continuationToken", token)
+		}
 
-	envResponse.Environments = filterByNameTag(envResponse.Environments, tag)
+		var page model.EnvironmentsResponse0
+		if err := s.client.Get(ctx, fmt.Sprintf("/esc/environments/%s", s.cfg.Pulumi.Organization), query, &page); err != nil {
+			return nil, "", err
+		}
+		return page.Environments, page.ContinuationToken, nil
+	})
+
+	all, err := pulumiapi.Collect(environments)
+	if err != nil {
+		return nil, err
+	}
 
-	return &envResponse, nil
+	return &model.EnvironmentsResponse0{
+		Environments: filterByNameTag(all, tag),
+	}, nil
 }
 
 func filterByNameTag(environments []model.Environment0, nameValue string) []model.Environment0 {
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// BlueprintCache stores serialized blueprint data by key until it expires. Every invalidation
+// starts a new generation; values loaded in an earlier one are not stored.
+type BlueprintCache interface {
+	// Get returns the value of a key, or false if it is missing or has expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Generation returns the number of invalidations so far
+	Generation(ctx context.Context) (uint64, error)
+	// Set stores the value of a key for the given time, unless the cache was invalidated since
+	// the generation was read
+	Set(ctx context.Context, generation uint64, key string, value []byte, ttl time.Duration) error
+	// DeletePrefix removes all keys starting with the prefix and starts a new generation, an
+	// empty prefix purges the cache
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// NewBlueprintCache builds the configured cache backend. Unknown backends fall back to memory.
+func NewBlueprintCache(cfg *config.Config, entries *repository.CacheRepository) BlueprintCache {
+	switch cfg.Cache.Backend {
+	case "database":
+		return NewDatabaseBlueprintCache(entries)
+	case "memory", "":
+	default:
+		log.Printf("Unknown blueprint cache backend %q, using memory", cfg.Cache.Backend)
+	}
+	return NewMemoryBlueprintCache(cfg.Cache.Size)
+}
+
+// Keys of the blueprint cache. The data of a single blueprint shares a prefix, so that it can
+// be invalidated at once.
+const blueprintCatalogKey = "catalog"
+
+func blueprintVersionsKey(source string) string {
+	return "versions/" + source
+}
+
+func blueprintKeyPrefix(name string) string {
+	return "blueprint/" + name + "/"
+}
+
+func pulumiYamlKey(name, source, ref string) string {
+	return fmt.Sprintf("%spulumi-yaml/%s/%s", blueprintKeyPrefix(name), source, ref)
+}
+
+func blueprintSchemaKey(name, version string) string {
+	return fmt.Sprintf("%sschema/%s", blueprintKeyPrefix(name), version)
+}
+
+func blueprintUISchemaKey(name, version string) string {
+	return fmt.Sprintf("%sui-schema/%s", blueprintKeyPrefix(name), version)
+}
+
+// cached returns the value of a key from the cache of the blueprint service, or loads and caches it.
+// Concurrent loads of the same key are shared and run detached from the callers, so that a caller
+// going away does not fail the others. Every caller decodes its own value, so the value has the
+// same types on a cache hit and on a miss and callers can modify it. The cache failing only costs
+// the load.
+func cached[T any](ctx context.Context, s *BlueprintService, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	if data, ok, err := s.cache.Get(ctx, key); err != nil {
+		s.logger.Printf("Failed to read %s from the cache: %v", key, err)
+	} else if ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+		s.logger.Printf("Ignoring unreadable cache entry %s", key)
+	}
+
+	// Loads started before an invalidation are neither joined nor stored. Without a generation
+	// the value is loaded, but not stored.
+	generation, err := s.cache.Generation(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to read the cache generation: %v", err)
+	}
+	store := err == nil
+	results := s.loads.DoChan(fmt.Sprintf("%s@%d", key, generation), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), blueprintLoadTimeout)
+		defer cancel()
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize %s: %w", key, err)
+		}
+		if store {
+			if err := s.cache.Set(ctx, generation, key, data, ttl); err != nil {
+				s.logger.Printf("Failed to write %s to the cache: %v", key, err)
+			}
+		}
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return value, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return value, result.Err
+		}
+		err := json.Unmarshal(result.Val.([]byte), &value)
+		return value, err
+	}
+}
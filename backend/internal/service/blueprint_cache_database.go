@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// DatabaseBlueprintCache keeps the blueprint cache in the database, so that all replicas share
+// it and an invalidation reaches every one of them
+type DatabaseBlueprintCache struct {
+	entries *repository.CacheRepository
+}
+
+// NewDatabaseBlueprintCache creates a cache backed by the cache entries table
+func NewDatabaseBlueprintCache(entries *repository.CacheRepository) *DatabaseBlueprintCache {
+	return &DatabaseBlueprintCache{
+		entries: entries,
+	}
+}
+
+func (c *DatabaseBlueprintCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	entry, err := c.entries.Find(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return entry.Value, true, nil
+}
+
+func (c *DatabaseBlueprintCache) Generation(ctx context.Context) (uint64, error) {
+	return c.entries.Generation(ctx)
+}
+
+// Set stores the value and removes the expired entries, which are only written on cache misses.
+// The generation is compared in the database, so that an invalidation by any replica counts.
+func (c *DatabaseBlueprintCache) Set(ctx context.Context, generation uint64, key string, value []byte, ttl time.Duration) error {
+	saved, err := c.entries.Save(ctx, generation, &model.CacheEntry{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil || !saved {
+		return err
+	}
+	return c.entries.DeleteExpired(ctx)
+}
+
+func (c *DatabaseBlueprintCache) DeletePrefix(ctx context.Context, prefix string) error {
+	return c.entries.DeletePrefix(ctx, prefix)
+}
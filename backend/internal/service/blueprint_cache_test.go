@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an in-memory database with the tables of the models
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestBlueprintService(cache BlueprintCache) *BlueprintService {
+	return &BlueprintService{
+		cfg:    &config.Config{},
+		cache:  cache,
+		logger: log.New(io.Discard, "", 0),
+	}
+}
+
+func TestBlueprintCacheBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) BlueprintCache{
+		"memory": func(t *testing.T) BlueprintCache { return NewMemoryBlueprintCache(10) },
+		"database": func(t *testing.T) BlueprintCache {
+			return NewDatabaseBlueprintCache(repository.NewCacheRepository(newTestDB(t, &model.CacheEntry{}, &model.CacheGeneration{})))
+		},
+	}
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := newCache(t)
+
+			for _, key := range []string{blueprintCatalogKey, blueprintSchemaKey("web", "v1"), blueprintSchemaKey("web-api", "v1")} {
+				if err := cache.Set(ctx, 0, key, []byte(key), time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := cache.Set(ctx, 0, "expired", []byte("old"), -time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			if value, ok, err := cache.Get(ctx, blueprintCatalogKey); err != nil || !ok || string(value) != blueprintCatalogKey {
+				t.Fatalf("Get = %q, %t, %v", value, ok, err)
+			}
+			if _, ok, _ := cache.Get(ctx, "expired"); ok {
+				t.Fatal("expired entries must not be returned")
+			}
+
+			if err := cache.DeletePrefix(ctx, blueprintKeyPrefix("web")); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := cache.Get(ctx, blueprintSchemaKey("web", "v1")); ok {
+				t.Fatal("entry of the invalidated blueprint is still cached")
+			}
+			if _, ok, _ := cache.Get(ctx, blueprintSchemaKey("web-api", "v1")); !ok {
+				t.Fatal("entry of another blueprint sharing the name prefix was removed")
+			}
+		})
+	}
+}
+
+func TestCachedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBlueprintService(NewMemoryBlueprintCache(10))
+
+	loads := 0
+	load := func(ctx context.Context) (map[string]interface{}, error) {
+		loads++
+		return map[string]interface{}{"required": []string{"name"}}, nil
+	}
+
+	miss, err := cached(ctx, s, "schema", time.Hour, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit, err := cached(ctx, s, "schema", time.Hour, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loads != 1 {
+		t.Fatalf("expected a single load, got %d", loads)
+	}
+	// Both reads decode the cached JSON, so the value has the same types either way
+	if !reflect.DeepEqual(miss, hit) {
+		t.Fatalf("cache miss returned %#v, cache hit %#v", miss, hit)
+	}
+}
+
+func TestCachedDropsLoadsStartedBeforeInvalidation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBlueprintService(NewMemoryBlueprintCache(10))
+
+	loading := make(chan struct{})
+	proceed := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		value, _ := cached(ctx, s, "catalog", time.Hour, func(ctx context.Context) (string, error) {
+			close(loading)
+			<-proceed
+			return "stale", nil
+		})
+		done <- value
+	}()
+
+	<-loading
+	if err := s.InvalidateCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A read after the invalidation must not join the load in flight
+	fresh, err := cached(ctx, s, "catalog", time.Hour, func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	if err != nil || fresh != "fresh" {
+		t.Fatalf("cached = %q, %v", fresh, err)
+	}
+
+	close(proceed)
+	if stale := <-done; stale != "stale" {
+		t.Fatalf("the load in flight returned %q", stale)
+	}
+	if value, _, _ := s.cache.Get(ctx, "catalog"); string(value) != `"fresh"` {
+		t.Fatalf("the cache holds %s", value)
+	}
+}
+
+func TestCachedDropsLoadsInvalidatedByAnotherReplica(t *testing.T) {
+	ctx := context.Background()
+	entries := repository.NewCacheRepository(newTestDB(t, &model.CacheEntry{}, &model.CacheGeneration{}))
+	first := newTestBlueprintService(NewDatabaseBlueprintCache(entries))
+	second := newTestBlueprintService(NewDatabaseBlueprintCache(entries))
+
+	loading := make(chan struct{})
+	proceed := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := cached(ctx, first, "catalog", time.Hour, func(ctx context.Context) (string, error) {
+			close(loading)
+			<-proceed
+			return "stale", nil
+		})
+		done <- err
+	}()
+
+	<-loading
+	if err := second.InvalidateCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	close(proceed)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if value, ok, err := second.cache.Get(ctx, "catalog"); err != nil || ok {
+		t.Fatalf("the load invalidated by the other replica was stored: %s, %v", value, err)
+	}
+}
+
+func TestCachedLoadOutlivesCaller(t *testing.T) {
+	s := newTestBlueprintService(NewMemoryBlueprintCache(10))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	loaded := make(chan error, 1)
+	_, err := cached(ctx, s, "catalog", time.Hour, func(loadCtx context.Context) (string, error) {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		loaded <- loadCtx.Err()
+		return "catalog", nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the caller to give up, got %v", err)
+	}
+	if err := <-loaded; err != nil {
+		t.Fatalf("the load was cancelled with its caller: %v", err)
+	}
+}
+
+func TestWithoutSecretRequirementsReadsCachedSchemas(t *testing.T) {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"name":     map[string]interface{}{"type": "string"},
+			"password": map[string]interface{}{"type": "string", "writeOnly": true},
+		},
+		"required": []interface{}{"name", "password"},
+	}
+
+	required := withoutSecretRequirements(schema)["required"]
+	if !reflect.DeepEqual(required, []string{"name"}) {
+		t.Fatalf("required = %#v", required)
+	}
+}
+
+func TestRefreshFromPushInvalidatesChangedBlueprints(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBlueprintService(NewMemoryBlueprintCache(10))
+	s.cfg.Pulumi.BlueprintGithubLocation = "acme/blueprints/templates"
+
+	keys := []string{blueprintSchemaKey("web", "v1"), blueprintSchemaKey("worker", "v1"), blueprintVersionsKey("github:acme/blueprints")}
+	for _, key := range keys {
+		if err := s.cache.Set(ctx, 0, key, []byte(`{}`), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	payload := `{
+		"ref": "refs/heads/main",
+		"repository": {"full_name": "acme/blueprints", "default_branch": "main"},
+		"commits": [{"modified": ["templates/web/Pulumi.yaml", "README.md"]}]
+	}`
+	refresh, err := s.RefreshFromPush(ctx, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("the catalog rebuild was not drained: %v", err)
+	}
+
+	if !reflect.DeepEqual(refresh.Blueprints, []string{"web"}) || refresh.All {
+		t.Fatalf("unexpected refresh %+v", refresh)
+	}
+	if _, ok, _ := s.cache.Get(ctx, keys[0]); ok {
+		t.Fatal("the changed blueprint is still cached")
+	}
+	for _, key := range keys[1:] {
+		if _, ok, _ := s.cache.Get(ctx, key); !ok {
+			t.Fatalf("%s was invalidated", key)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"golang.org/x/mod/semver"
+)
+
+// BlueprintSource is a location blueprints are read from
+type BlueprintSource interface {
+	// Name identifies the source in the merged blueprint catalog
+	Name() string
+	// ListBlueprints returns the names of all blueprint candidates in the source.
+	// Candidates without a readable Pulumi.yaml are skipped by the catalog.
+	ListBlueprints(ctx context.Context) ([]string, error)
+	// ReadPulumiYaml returns the raw Pulumi.yaml of a blueprint at a git ref,
+	// or on the default branch when ref is empty
+	ReadPulumiYaml(ctx context.Context, name, ref string) ([]byte, error)
+	// ListVersions returns the version tags of the source. Sources without
+	// version support return no versions.
+	ListVersions(ctx context.Context) ([]model.BlueprintVersion, error)
+	// Location returns where Pulumi Deployments can fetch the blueprint from.
+	// RepoURL is empty for sources that are not backed by a git repository.
+	Location(name string) model.BlueprintLocation
+}
+
+// NewBlueprintSources builds the configured blueprint sources in priority order.
+// When no sources are configured the legacy PULUMI_BLUEPRINT_GITHUB_LOCATION repository is used.
+func NewBlueprintSources(cfg *config.Config) []BlueprintSource {
+	specs := cfg.Pulumi.BlueprintSources
+	if len(specs) == 0 && cfg.Pulumi.BlueprintGithubLocation != "" {
+		specs = []string{"github:" + cfg.Pulumi.BlueprintGithubLocation}
+	}
+
+	sources := make([]BlueprintSource, 0, len(specs))
+	for _, spec := range specs {
+		source, err := newBlueprintSource(cfg, strings.TrimSpace(spec))
+		if err != nil {
+			log.Printf("Skipping blueprint source %q: %v", spec, err)
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
+// newBlueprintSource creates a single source from a "<kind>:<location>" spec
+func newBlueprintSource(cfg *config.Config, spec string) (BlueprintSource, error) {
+	kind, location, found := strings.Cut(spec, ":")
+	if !found {
+		return nil, fmt.Errorf("expected <kind>:<location>")
+	}
+
+	switch kind {
+	case "github":
+		return NewGitHubBlueprintSource(cfg.GitHub.Token, location)
+	case "file":
+		return NewLocalBlueprintSource(location)
+	case "git":
+		return NewGitBlueprintSource(location, cfg.GitHub.Token)
+	case "pulumi":
+		organization := location
+		if organization == "" {
+			organization = cfg.Pulumi.Organization
+		}
+		return NewPulumiBlueprintSource(cfg, organization)
+	default:
+		return nil, fmt.Errorf("unknown blueprint source kind %q", kind)
+	}
+}
+
+// versionFromTag maps a git tag to a blueprint version. Tags of the form
+// <blueprint>/<version> version a single blueprint, all other tags version
+// every blueprint of the source.
+func versionFromTag(tag, commit string) model.BlueprintVersion {
+	version := model.BlueprintVersion{
+		Version: tag,
+		Ref:     "refs/tags/" + tag,
+		Commit:  commit,
+	}
+
+	if blueprint, label, found := strings.Cut(tag, "/"); found {
+		version.Blueprint = blueprint
+		version.Version = label
+	}
+
+	return version
+}
+
+// versionsForBlueprint filters the versions applying to a blueprint and sorts them newest first.
+// Blueprint specific tags take precedence over repository-wide tags with the same label.
+func versionsForBlueprint(name string, versions []model.BlueprintVersion) []model.BlueprintVersion {
+	byLabel := make(map[string]model.BlueprintVersion)
+	for _, version := range versions {
+		if version.Blueprint != "" && version.Blueprint != name {
+			continue
+		}
+		if existing, ok := byLabel[version.Version]; ok && existing.Blueprint != "" {
+			continue
+		}
+		byLabel[version.Version] = version
+	}
+
+	filtered := make([]model.BlueprintVersion, 0, len(byLabel))
+	for _, version := range byLabel {
+		filtered = append(filtered, version)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return compareVersions(filtered[i].Version, filtered[j].Version) > 0
+	})
+
+	return filtered
+}
+
+// compareVersions orders semantic versions before other labels, which are compared lexically
+func compareVersions(a, b string) int {
+	va, vb := canonicalVersion(a), canonicalVersion(b)
+	switch {
+	case va != "" && vb != "":
+		return semver.Compare(va, vb)
+	case va != "":
+		return 1
+	case vb != "":
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// canonicalVersion returns the semver form of a version label, or "" if it is not a semantic version
+func canonicalVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return ""
+	}
+	return version
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/pulumi-idp/internal/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// gitBlueprintRefreshInterval is how long a clone of the default branch is reused before it is fetched again
+const gitBlueprintRefreshInterval = 5 * time.Minute
+
+// gitClone is a local checkout of a single ref
+type gitClone struct {
+	dir      string
+	clonedAt time.Time
+	// readers counts the callers reading from the clone. A replaced clone is retired
+	// and only removed once its last reader is done.
+	readers int
+	retired bool
+}
+
+// GitBlueprintSource reads blueprints from a generic git repository cloned to a local directory
+type GitBlueprintSource struct {
+	repoURL string
+	subDir  string
+	token   string
+
+	mutex     sync.Mutex
+	clones    map[string]*gitClone
+	cloning   singleflight.Group
+	cacheRoot string
+}
+
+// NewGitBlueprintSource creates a source from a "<repo-url>[#subdir]" location.
+// The GitHub token is only sent to github.com remotes.
+func NewGitBlueprintSource(location, githubToken string) (*GitBlueprintSource, error) {
+	repoURL, subDir, _ := strings.Cut(location, "#")
+	if repoURL == "" {
+		return nil, fmt.Errorf("missing git repository URL")
+	}
+
+	source := &GitBlueprintSource{
+		repoURL:   repoURL,
+		subDir:    strings.Trim(subDir, "/"),
+		clones:    make(map[string]*gitClone),
+		cacheRoot: filepath.Join(os.TempDir(), "pulumi-idp-blueprints"),
+	}
+
+	if u, err := url.Parse(repoURL); err == nil && u.Host == "github.com" {
+		source.token = githubToken
+	}
+
+	return source, nil
+}
+
+// Name implements BlueprintSource
+func (s *GitBlueprintSource) Name() string {
+	return "git:" + s.repoURL
+}
+
+// ListBlueprints implements BlueprintSource
+func (s *GitBlueprintSource) ListBlueprints(ctx context.Context) ([]string, error) {
+	root, release, err := s.checkout(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return listBlueprintDirs(root)
+}
+
+// ReadPulumiYaml implements BlueprintSource
+func (s *GitBlueprintSource) ReadPulumiYaml(ctx context.Context, name, ref string) ([]byte, error) {
+	root, release, err := s.checkout(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return readLocalPulumiYaml(root, name)
+}
+
+// ListVersions implements BlueprintSource using the tags advertised by the remote
+func (s *GitBlueprintSource) ListVersions(ctx context.Context) ([]model.BlueprintVersion, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{s.repoURL},
+	})
+
+	refs, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth:          s.auth(),
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", s.repoURL, err)
+	}
+
+	// Annotated tags are advertised twice; the peeled "^{}" entry points at the commit
+	commits := make(map[string]string)
+	for _, ref := range refs {
+		name := ref.Name().String()
+		if !strings.HasPrefix(name, "refs/tags/") {
+			continue
+		}
+		tag := strings.TrimPrefix(name, "refs/tags/")
+		if peeled, ok := strings.CutSuffix(tag, "^{}"); ok {
+			commits[peeled] = ref.Hash().String()
+		} else if _, ok := commits[tag]; !ok {
+			commits[tag] = ref.Hash().String()
+		}
+	}
+
+	versions := make([]model.BlueprintVersion, 0, len(commits))
+	for tag, commit := range commits {
+		versions = append(versions, versionFromTag(tag, commit))
+	}
+
+	return versions, nil
+}
+
+// Location implements BlueprintSource
+func (s *GitBlueprintSource) Location(name string) model.BlueprintLocation {
+	return model.BlueprintLocation{
+		RepoURL: s.repoURL,
+		RepoDir: path.Join(s.subDir, name),
+	}
+}
+
+// checkout returns the blueprint root of a local clone of ref and a func to call once the caller
+// is done reading from it. Tags are cloned once, the default branch is refreshed periodically.
+// Clones run outside the lock, once per ref for all waiting callers.
+func (s *GitBlueprintSource) checkout(ctx context.Context, ref string) (string, func(), error) {
+	s.mutex.Lock()
+	stale := s.stale(ref)
+	s.mutex.Unlock()
+
+	if stale {
+		_, err, _ := s.cloning.Do(ref, func() (interface{}, error) {
+			// Another caller may have refreshed the clone since it was found stale
+			s.mutex.Lock()
+			stale := s.stale(ref)
+			s.mutex.Unlock()
+			if !stale {
+				return nil, nil
+			}
+
+			fresh, err := s.clone(ctx, ref)
+			if err != nil {
+				return nil, err
+			}
+
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			if previous, ok := s.clones[ref]; ok {
+				previous.retired = true
+				previous.removeUnused()
+			}
+			s.clones[ref] = fresh
+			return nil, nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clone := s.clones[ref]
+	clone.readers++
+	release := func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		clone.readers--
+		clone.removeUnused()
+	}
+
+	return filepath.Join(clone.dir, filepath.FromSlash(s.subDir)), release, nil
+}
+
+// stale reports whether ref has to be cloned, because it never was or because the clone of the
+// default branch is due for a refresh. The caller holds the mutex.
+func (s *GitBlueprintSource) stale(ref string) bool {
+	clone, ok := s.clones[ref]
+	return !ok || (ref == "" && time.Since(clone.clonedAt) > gitBlueprintRefreshInterval)
+}
+
+// removeUnused deletes a retired clone nobody reads from anymore. The caller holds the mutex.
+func (c *gitClone) removeUnused() {
+	if c.retired && c.readers == 0 {
+		os.RemoveAll(c.dir)
+	}
+}
+
+// clone creates a fresh shallow clone of ref, or of the default branch when ref is empty
+func (s *GitBlueprintSource) clone(ctx context.Context, ref string) (*gitClone, error) {
+	if err := os.MkdirAll(s.cacheRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blueprint cache directory: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(s.cacheRoot, "git-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+
+	options := &git.CloneOptions{
+		URL:   s.repoURL,
+		Depth: 1,
+		Auth:  s.auth(),
+	}
+	if ref != "" {
+		options.ReferenceName = plumbing.ReferenceName(ref)
+		options.SingleBranch = true
+	}
+
+	if _, err := git.PlainCloneContext(ctx, dir, false, options); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to clone %s: %w", s.repoURL, err)
+	}
+
+	return &gitClone{
+		dir:      dir,
+		clonedAt: time.Now(),
+	}, nil
+}
+
+func (s *GitBlueprintSource) auth() transport.AuthMethod {
+	if s.token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{
+		Username: "x-access-token",
+		Password: s.token,
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newTestGitSource creates a repository with a web blueprint under blueprints/ and a source cloning it into a temporary directory
+func newTestGitSource(t *testing.T) *GitBlueprintSource {
+	t.Helper()
+
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoDir, "blueprints", "web"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "blueprints", "web", "Pulumi.yaml"), []byte("name: web\nruntime: yaml\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Add("blueprints/web/Pulumi.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Commit("Add web blueprint", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewGitBlueprintSource(repoDir+"#blueprints", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.cacheRoot = t.TempDir()
+	return source
+}
+
+func TestCheckoutKeepsReplacedClonesUntilReleased(t *testing.T) {
+	ctx := context.Background()
+	source := newTestGitSource(t)
+
+	oldRoot, releaseOld, err := source.checkout(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Let the clone expire, so that the next checkout refreshes it
+	source.mutex.Lock()
+	source.clones[""].clonedAt = time.Now().Add(-2 * gitBlueprintRefreshInterval)
+	source.mutex.Unlock()
+
+	newRoot, releaseNew, err := source.checkout(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseNew()
+	if newRoot == oldRoot {
+		t.Fatal("the expired clone was not refreshed")
+	}
+
+	if _, err := os.Stat(filepath.Join(oldRoot, "web", "Pulumi.yaml")); err != nil {
+		t.Fatalf("the replaced clone was removed while it was read: %v", err)
+	}
+	releaseOld()
+	if _, err := os.Stat(filepath.Dir(oldRoot)); !os.IsNotExist(err) {
+		t.Fatalf("the replaced clone was kept after its last reader was done: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(newRoot, "web", "Pulumi.yaml")); err != nil {
+		t.Fatalf("the current clone was removed: %v", err)
+	}
+}
+
+func TestCheckoutClonesOncePerRef(t *testing.T) {
+	ctx := context.Background()
+	source := newTestGitSource(t)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := source.ListBlueprints(ctx)
+			if err != nil || len(names) != 1 {
+				t.Errorf("unexpected blueprints: %v, %v", names, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(source.cacheRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected a single clone, found %d", len(entries))
+	}
+}
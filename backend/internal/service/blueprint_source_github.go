@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pulumi-idp/internal/model"
+	"golang.org/x/oauth2"
+)
+
+// GitHubBlueprintSource reads blueprints from the top-level directories of a GitHub repository path
+type GitHubBlueprintSource struct {
+	token string
+	owner string
+	repo  string
+	path  string
+}
+
+// NewGitHubBlueprintSource creates a source from an "owner/repo[/path]" location
+func NewGitHubBlueprintSource(token, location string) (*GitHubBlueprintSource, error) {
+	parts := strings.SplitN(location, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid GitHub location %q, expected owner/repo[/path]", location)
+	}
+
+	source := &GitHubBlueprintSource{
+		token: token,
+		owner: parts[0],
+		repo:  parts[1],
+	}
+	if len(parts) > 2 {
+		source.path = strings.Trim(parts[2], "/")
+	}
+
+	return source, nil
+}
+
+// Name implements BlueprintSource
+func (s *GitHubBlueprintSource) Name() string {
+	return fmt.Sprintf("github:%s/%s", s.owner, s.repo)
+}
+
+// ListBlueprints implements BlueprintSource
+func (s *GitHubBlueprintSource) ListBlueprints(ctx context.Context) ([]string, error) {
+	client := s.client(ctx)
+
+	_, directoryContent, _, err := client.Repositories.GetContents(
+		ctx,
+		s.owner,
+		s.repo,
+		s.path,
+		&github.RepositoryContentGetOptions{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve repository contents: %w", err)
+	}
+
+	var names []string
+	for _, content := range directoryContent {
+		if content.GetType() == "dir" {
+			names = append(names, content.GetName())
+		}
+	}
+
+	return names, nil
+}
+
+// ReadPulumiYaml implements BlueprintSource
+func (s *GitHubBlueprintSource) ReadPulumiYaml(ctx context.Context, name, ref string) ([]byte, error) {
+	client := s.client(ctx)
+
+	fileContent, _, _, err := client.Repositories.GetContents(
+		ctx,
+		s.owner,
+		s.repo,
+		path.Join(s.path, name, "Pulumi.yaml"),
+		&github.RepositoryContentGetOptions{Ref: ref},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Pulumi.yaml: %w", err)
+	}
+
+	contentStr, err := fileContent.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Pulumi.yaml: %w", err)
+	}
+
+	return []byte(contentStr), nil
+}
+
+// ListVersions implements BlueprintSource using the repository tags
+func (s *GitHubBlueprintSource) ListVersions(ctx context.Context) ([]model.BlueprintVersion, error) {
+	client := s.client(ctx)
+
+	var versions []model.BlueprintVersion
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		tags, resp, err := client.Repositories.ListTags(ctx, s.owner, s.repo, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository tags: %w", err)
+		}
+
+		for _, tag := range tags {
+			versions = append(versions, versionFromTag(tag.GetName(), tag.GetCommit().GetSHA()))
+		}
+
+		if resp.NextPage == 0 {
+			return versions, nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+// Location implements BlueprintSource
+func (s *GitHubBlueprintSource) Location(name string) model.BlueprintLocation {
+	return model.BlueprintLocation{
+		RepoURL: fmt.Sprintf("https://github.com/%s/%s.git", s.owner, s.repo),
+		RepoDir: path.Join(s.path, name),
+	}
+}
+
+func (s *GitHubBlueprintSource) client(ctx context.Context) *github.Client {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: s.token},
+	)
+	return github.NewClient(oauth2.NewClient(ctx, ts))
+}
+
+// changedBlueprints returns the blueprints whose directories contain one of the files,
+// which are paths relative to the repository root
+func (s *GitHubBlueprintSource) changedBlueprints(files []string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, file := range files {
+		relative := file
+		if s.path != "" {
+			var found bool
+			if relative, found = strings.CutPrefix(file, s.path+"/"); !found {
+				continue
+			}
+		}
+
+		// Files next to the blueprint directories do not belong to a blueprint
+		name, _, found := strings.Cut(relative, "/")
+		if !found || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
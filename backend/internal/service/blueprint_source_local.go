@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+// LocalBlueprintSource reads blueprints from the subdirectories of a local directory
+type LocalBlueprintSource struct {
+	root string
+}
+
+// NewLocalBlueprintSource creates a source for a local directory
+func NewLocalBlueprintSource(root string) (*LocalBlueprintSource, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blueprint directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	return &LocalBlueprintSource{
+		root: root,
+	}, nil
+}
+
+// Name implements BlueprintSource
+func (s *LocalBlueprintSource) Name() string {
+	return "file:" + s.root
+}
+
+// ListBlueprints implements BlueprintSource
+func (s *LocalBlueprintSource) ListBlueprints(_ context.Context) ([]string, error) {
+	return listBlueprintDirs(s.root)
+}
+
+// ReadPulumiYaml implements BlueprintSource
+func (s *LocalBlueprintSource) ReadPulumiYaml(_ context.Context, name, ref string) ([]byte, error) {
+	if ref != "" {
+		return nil, fmt.Errorf("local blueprints are not versioned")
+	}
+	return readLocalPulumiYaml(s.root, name)
+}
+
+// ListVersions implements BlueprintSource. Local blueprints are not versioned.
+func (s *LocalBlueprintSource) ListVersions(_ context.Context) ([]model.BlueprintVersion, error) {
+	return nil, nil
+}
+
+// Location implements BlueprintSource. Local blueprints cannot be deployed from.
+func (s *LocalBlueprintSource) Location(_ string) model.BlueprintLocation {
+	return model.BlueprintLocation{}
+}
+
+// listBlueprintDirs returns the names of all subdirectories of root
+func listBlueprintDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blueprint directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// readLocalPulumiYaml reads the Pulumi.yaml of the blueprint directory root/name
+func readLocalPulumiYaml(root, name string) ([]byte, error) {
+	// Reject names that would escape the blueprint directory
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid blueprint name %q", name)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, name, "Pulumi.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Pulumi.yaml: %w", err)
+	}
+
+	return content, nil
+}
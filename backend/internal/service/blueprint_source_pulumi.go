@@ -0,0 +1,173 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	pulumiapi "github.com/pulumi-idp/internal/pulumi"
+)
+
+// PulumiBlueprintSource reads blueprints from the templates published to the Pulumi Cloud registry of an organization
+type PulumiBlueprintSource struct {
+	cfg          *config.Config
+	organization string
+	client       *pulumiapi.Client
+	// httpClient downloads the template archives, which are not served by the Pulumi API
+	httpClient *http.Client
+}
+
+// NewPulumiBlueprintSource creates a source for the registry templates of an organization
+func NewPulumiBlueprintSource(cfg *config.Config, organization string) (*PulumiBlueprintSource, error) {
+	if organization == "" {
+		return nil, fmt.Errorf("organization is required")
+	}
+
+	return &PulumiBlueprintSource{
+		cfg:          cfg,
+		organization: organization,
+		client:       pulumiapi.NewClient(cfg),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// Name implements BlueprintSource
+func (s *PulumiBlueprintSource) Name() string {
+	return "pulumi:" + s.organization
+}
+
+// ListBlueprints implements BlueprintSource
+func (s *PulumiBlueprintSource) ListBlueprints(ctx context.Context) ([]string, error) {
+	templates, err := s.listTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+
+	return names, nil
+}
+
+// ReadPulumiYaml implements BlueprintSource by extracting Pulumi.yaml from the template archive
+func (s *PulumiBlueprintSource) ReadPulumiYaml(ctx context.Context, name, ref string) ([]byte, error) {
+	if ref != "" {
+		return nil, fmt.Errorf("registry templates cannot be read at a git ref")
+	}
+
+	templates, err := s.listTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		if template.Name == name {
+			if template.DownloadURL == "" {
+				return nil, fmt.Errorf("template %s has no download URL", name)
+			}
+			return s.extractPulumiYaml(ctx, template.DownloadURL)
+		}
+	}
+
+	return nil, fmt.Errorf("template %s not found in organization %s", name, s.organization)
+}
+
+// ListVersions implements BlueprintSource. Registry templates are not versioned by git tags.
+func (s *PulumiBlueprintSource) ListVersions(_ context.Context) ([]model.BlueprintVersion, error) {
+	return nil, nil
+}
+
+// Location implements BlueprintSource. Registry templates are not deployed from git.
+func (s *PulumiBlueprintSource) Location(_ string) model.BlueprintLocation {
+	return model.BlueprintLocation{}
+}
+
+// listTemplates returns all registry templates of the organization, following continuation tokens
+func (s *PulumiBlueprintSource) listTemplates(ctx context.Context) ([]model.RegistryTemplate, error) {
+	return pulumiapi.Collect(pulumiapi.Paginate(ctx, "", func(ctx context.Context, token string) ([]model.RegistryTemplate, string, error) {
+		query := url.Values{}
+		query.Set("orgLogin", s.organization)
+		if token != "" {
+			query.Set("continuationToken", token)
+		}
+
+		var page model.RegistryTemplatesResponse
+		if err := s.client.Get(ctx, "/preview/registry/templates", query, &page); err != nil {
+			return nil, "", err
+		}
+		return page.Templates, page.ContinuationToken, nil
+	}))
+}
+
+// extractPulumiYaml downloads a gzipped template archive and returns its top-most Pulumi.yaml
+func (s *PulumiBlueprintSource) extractPulumiYaml(ctx context.Context, downloadURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading template: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("template download failed with status %d", resp.StatusCode)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template archive: %w", err)
+	}
+	defer gz.Close()
+
+	var content []byte
+	depth := -1
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg || path.Base(header.Name) != "Pulumi.yaml" {
+			continue
+		}
+
+		// Archives may wrap the template in a directory; prefer the least nested Pulumi.yaml
+		nesting := strings.Count(path.Clean(header.Name), "/")
+		if depth != -1 && nesting >= depth {
+			continue
+		}
+
+		content, err = io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Pulumi.yaml from archive: %w", err)
+		}
+		depth = nesting
+	}
+
+	if content == nil {
+		return nil, fmt.Errorf("template archive does not contain a Pulumi.yaml")
+	}
+
+	return content, nil
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+// jsonSchemaDialect is the JSON Schema draft emitted for blueprint config
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// configTypes are the config types a blueprint may declare
+var configTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"number":  true,
+	"boolean": true,
+	"array":   true,
+	"object":  true,
+}
+
+// parseConfigSchema parses the template.config section of a Pulumi.yaml into typed properties sorted by name
+func parseConfigSchema(config map[string]interface{}) ([]model.ConfigProperty, error) {
+	properties := make([]model.ConfigProperty, 0, len(config))
+	for name, raw := range config {
+		property, err := parseConfigProperty(name, raw)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, property)
+	}
+
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Name < properties[j].Name
+	})
+
+	return properties, nil
+}
+
+// parseConfigProperty parses a single config entry. A plain string is taken as the description
+// of a string property, maps may use the keywords of JSON Schema plus displayName and secret.
+func parseConfigProperty(name string, raw interface{}) (model.ConfigProperty, error) {
+	property := model.ConfigProperty{Name: name}
+
+	if description, ok := raw.(string); ok {
+		property.Type = "string"
+		property.Description = description
+		property.Required = true
+		return property, nil
+	}
+
+	definition, ok := toStringMap(raw)
+	if !ok {
+		return property, fmt.Errorf("config %s: unsupported definition of type %T", name, raw)
+	}
+
+	var err error
+	fail := func(keyword string, value interface{}) (model.ConfigProperty, error) {
+		return property, fmt.Errorf("config %s: invalid %s %v", name, keyword, value)
+	}
+
+	for key, value := range definition {
+		switch key {
+		case "type":
+			if property.Type, ok = value.(string); !ok || !configTypes[property.Type] {
+				return fail(key, value)
+			}
+		case "displayName", "title":
+			if property.Title, ok = value.(string); !ok {
+				return fail(key, value)
+			}
+		case "description":
+			if property.Description, ok = value.(string); !ok {
+				return fail(key, value)
+			}
+		case "default":
+			property.Default = normalizeYAMLValue(value)
+			property.HasDefault = true
+		case "secret":
+			if property.Secret, ok = value.(bool); !ok {
+				return fail(key, value)
+			}
+		case "format":
+			if property.Format, ok = value.(string); !ok {
+				return fail(key, value)
+			}
+		case "pattern":
+			if property.Pattern, ok = value.(string); !ok {
+				return fail(key, value)
+			}
+			if _, err := regexp.Compile(property.Pattern); err != nil {
+				return fail(key, value)
+			}
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return fail(key, value)
+			}
+			for _, v := range values {
+				property.Enum = append(property.Enum, normalizeYAMLValue(v))
+			}
+		case "enumNames":
+			values, ok := value.([]interface{})
+			if !ok {
+				return fail(key, value)
+			}
+			for _, v := range values {
+				property.EnumNames = append(property.EnumNames, fmt.Sprintf("%v", v))
+			}
+		case "minimum":
+			if property.Minimum, ok = toFloat(value); !ok {
+				return fail(key, value)
+			}
+		case "maximum":
+			if property.Maximum, ok = toFloat(value); !ok {
+				return fail(key, value)
+			}
+		case "exclusiveMinimum":
+			if property.ExclusiveMinimum, ok = toFloat(value); !ok {
+				return fail(key, value)
+			}
+		case "exclusiveMaximum":
+			if property.ExclusiveMaximum, ok = toFloat(value); !ok {
+				return fail(key, value)
+			}
+		case "multipleOf":
+			if property.MultipleOf, ok = toFloat(value); !ok {
+				return fail(key, value)
+			}
+		case "minLength":
+			if property.MinLength, ok = toInt(value); !ok {
+				return fail(key, value)
+			}
+		case "maxLength":
+			if property.MaxLength, ok = toInt(value); !ok {
+				return fail(key, value)
+			}
+		case "minItems":
+			if property.MinItems, ok = toInt(value); !ok {
+				return fail(key, value)
+			}
+		case "maxItems":
+			if property.MaxItems, ok = toInt(value); !ok {
+				return fail(key, value)
+			}
+		case "uniqueItems":
+			if property.UniqueItems, ok = value.(bool); !ok {
+				return fail(key, value)
+			}
+		case "required":
+			required, ok := value.(bool)
+			if !ok {
+				return fail(key, value)
+			}
+			property.Required = required
+		case "items":
+			items, err := parseConfigProperty(name+"[]", value)
+			if err != nil {
+				return property, err
+			}
+			property.Items = &items
+		case "properties":
+			nested, ok := toStringMap(value)
+			if !ok {
+				return fail(key, value)
+			}
+			for nestedName, nestedRaw := range nested {
+				nestedProperty, err := parseConfigProperty(nestedName, nestedRaw)
+				if err != nil {
+					return property, fmt.Errorf("config %s: %w", name, err)
+				}
+				property.Properties = append(property.Properties, nestedProperty)
+			}
+			sort.Slice(property.Properties, func(i, j int) bool {
+				return property.Properties[i].Name < property.Properties[j].Name
+			})
+		case "dependencies":
+			// A list names the properties required alongside this one,
+			// a map declares properties that only apply once this one is set
+			switch dependencies := value.(type) {
+			case []interface{}:
+				for _, dependency := range dependencies {
+					dependencyName, ok := dependency.(string)
+					if !ok {
+						return fail(key, value)
+					}
+					property.DependentRequired = append(property.DependentRequired, dependencyName)
+				}
+			default:
+				dependent, ok := toStringMap(value)
+				if !ok {
+					return fail(key, value)
+				}
+				if property.DependentProperties, err = parseConfigSchema(dependent); err != nil {
+					return property, fmt.Errorf("config %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	if _, explicit := definition["required"]; !explicit {
+		property.Required = !property.HasDefault
+	}
+	if property.Type == "" {
+		property.Type = inferConfigType(property)
+	}
+	if property.Type == "array" && property.Items == nil {
+		property.Items = &model.ConfigProperty{Name: name + "[]", Type: "string"}
+	}
+	if len(property.EnumNames) > 0 && len(property.EnumNames) != len(property.Enum) {
+		return property, fmt.Errorf("config %s: enumNames must match enum", name)
+	}
+
+	return property, nil
+}
+
+// inferConfigType derives the type of a property without an explicit type from its other keywords
+func inferConfigType(property model.ConfigProperty) string {
+	switch property.Default.(type) {
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	switch {
+	case property.Items != nil:
+		return "array"
+	case len(property.Properties) > 0:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+// configSchema returns the JSON Schema of a config property
+func configSchema(property model.ConfigProperty) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type": property.Type,
+	}
+
+	if property.Title != "" {
+		schema["title"] = property.Title
+	} else if property.Name != "" {
+		schema["title"] = property.Name
+	}
+	if property.Description != "" {
+		schema["description"] = property.Description
+	}
+	if property.HasDefault {
+		schema["default"] = property.Default
+	}
+	if property.Secret {
+		schema["writeOnly"] = true
+	}
+
+	if len(property.EnumNames) > 0 {
+		options := make([]map[string]interface{}, len(property.Enum))
+		for i, value := range property.Enum {
+			options[i] = map[string]interface{}{
+				"const": value,
+				"title": property.EnumNames[i],
+			}
+		}
+		schema["oneOf"] = options
+	} else if len(property.Enum) > 0 {
+		schema["enum"] = property.Enum
+	}
+
+	setIf(schema, "format", property.Format, property.Format != "")
+	setIf(schema, "pattern", property.Pattern, property.Pattern != "")
+	setIf(schema, "minLength", property.MinLength, property.MinLength != nil)
+	setIf(schema, "maxLength", property.MaxLength, property.MaxLength != nil)
+	setIf(schema, "minimum", property.Minimum, property.Minimum != nil)
+	setIf(schema, "maximum", property.Maximum, property.Maximum != nil)
+	setIf(schema, "exclusiveMinimum", property.ExclusiveMinimum, property.ExclusiveMinimum != nil)
+	setIf(schema, "exclusiveMaximum", property.ExclusiveMaximum, property.ExclusiveMaximum != nil)
+	setIf(schema, "multipleOf", property.MultipleOf, property.MultipleOf != nil)
+	setIf(schema, "minItems", property.MinItems, property.MinItems != nil)
+	setIf(schema, "maxItems", property.MaxItems, property.MaxItems != nil)
+	setIf(schema, "uniqueItems", true, property.UniqueItems)
+
+	if property.Items != nil {
+		items := configSchema(*property.Items)
+		delete(items, "title")
+		schema["items"] = items
+	}
+
+	if property.Type == "object" && len(property.Properties) > 0 {
+		for key, value := range configObjectSchema(property.Properties) {
+			if key != "type" {
+				schema[key] = value
+			}
+		}
+	}
+
+	return schema
+}
+
+// configObjectSchema returns the JSON Schema of an object with the given properties
+func configObjectSchema(properties []model.ConfigProperty) map[string]interface{} {
+	schemaProperties := make(map[string]interface{}, len(properties))
+	required := []string{}
+	dependentRequired := make(map[string]interface{})
+	dependentSchemas := make(map[string]interface{})
+
+	for _, property := range properties {
+		schemaProperties[property.Name] = configSchema(property)
+		if property.Required {
+			required = append(required, property.Name)
+		}
+		if len(property.DependentRequired) > 0 {
+			dependentRequired[property.Name] = property.DependentRequired
+		}
+		if len(property.DependentProperties) > 0 {
+			dependentSchemas[property.Name] = configObjectSchema(property.DependentProperties)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": schemaProperties,
+		"required":   required,
+	}
+	setIf(schema, "dependentRequired", dependentRequired, len(dependentRequired) > 0)
+	setIf(schema, "dependentSchemas", dependentSchemas, len(dependentSchemas) > 0)
+
+	return schema
+}
+
+// configUISchema returns the react-jsonschema-form UI schema entries for a set of properties.
+// Properties without UI hints are left out.
+func configUISchema(properties []model.ConfigProperty) map[string]interface{} {
+	uiSchema := make(map[string]interface{})
+
+	for _, property := range properties {
+		if field := configFieldUISchema(property); len(field) > 0 {
+			uiSchema[property.Name] = field
+		}
+		for name, field := range configUISchema(property.DependentProperties) {
+			uiSchema[name] = field
+		}
+	}
+
+	return uiSchema
+}
+
+func configFieldUISchema(property model.ConfigProperty) map[string]interface{} {
+	field := make(map[string]interface{})
+
+	if property.Description != "" {
+		field["ui:enableMarkdownInDescription"] = true
+	}
+
+	switch property.Type {
+	case "string":
+		if property.Secret {
+			field["ui:widget"] = "password"
+		} else if property.HasDefault && len(property.Enum) == 0 {
+			field["ui:widget"] = "DefaultValueOverrideWidget"
+		}
+	case "number", "integer":
+		field["ui:widget"] = "updown"
+	case "object":
+		for name, nested := range configUISchema(property.Properties) {
+			field[name] = nested
+		}
+	case "array":
+		if property.Items != nil {
+			if items := configFieldUISchema(*property.Items); len(items) > 0 {
+				field["items"] = items
+			}
+		}
+	}
+
+	if property.HasDefault && (property.Type == "string" || property.Type == "number" || property.Type == "integer") {
+		field["ui:placeholder"] = fmt.Sprintf("Default: %v", property.Default)
+	}
+
+	return field
+}
+
+func setIf(schema map[string]interface{}, key string, value interface{}, condition bool) {
+	if condition {
+		schema[key] = value
+	}
+}
+
+// toStringMap converts the map types produced by YAML decoders into a map with string keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			converted[fmt.Sprintf("%v", k)] = v
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// normalizeYAMLValue makes a decoded YAML value JSON serializable
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		m, _ := toStringMap(v)
+		normalized := make(map[string]interface{}, len(m))
+		for key, item := range m {
+			normalized[key] = normalizeYAMLValue(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeYAMLValue(item)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+func toFloat(value interface{}) (*float64, bool) {
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return nil, false
+	}
+	return &f, true
+}
+
+func toInt(value interface{}) (*int, bool) {
+	var i int
+	switch v := value.(type) {
+	case int:
+		i = v
+	case int64:
+		i = int(v)
+	case uint64:
+		i = int(v)
+	default:
+		return nil, false
+	}
+	if i < 0 {
+		return nil, false
+	}
+	return &i, true
+}
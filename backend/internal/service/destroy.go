@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// CleanupDeletedWorkload checks the latest deployment of a stack marked for deletion. The stack is
+// only deleted once its destroy has succeeded and it holds no resources anymore. Failed destroys are
+// queued again with a doubling backoff until the configured attempts are used up, then the workload
+// is marked destroy-failed and left for an operator.
+func (s *WorkloadService) CleanupDeletedWorkload(ctx context.Context, organization, project, stack string) (string, *model.WorkloadRecord, error) {
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if errors.Is(err, repository.ErrNotFound) {
+		record = nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	if record != nil && record.Status == model.WorkloadStatusDestroyFailed {
+		return model.DestroyOutcomePending, record, nil
+	}
+
+	deployments, err := s.pulumiService.GetStackUpdates(ctx, &model.ListStackUpdatesParams{
+		Page:     1,
+		PageSize: 1,
+	}, project, stack)
+	if err != nil {
+		return "", record, err
+	}
+
+	// Stacks that were never deployed through Pulumi Deployments only need to be empty
+	reason := "stack was never deployed"
+	if len(deployments.Deployments) > 0 {
+		latest := deployments.Deployments[0]
+		switch latest.Status {
+		case "succeeded", "failed", "skipped":
+		default:
+			return model.DestroyOutcomePending, record, nil
+		}
+
+		// Any other deployment that finished after the delete leaves the resources in place
+		if latest.PulumiOperation != "destroy" || latest.Status != "succeeded" {
+			return s.retryWorkloadDestroy(ctx, organization, project, stack, record,
+				fmt.Sprintf("%s deployment %s finished with status %s", latest.PulumiOperation, latest.ID, latest.Status))
+		}
+		reason = fmt.Sprintf("destroy deployment %s succeeded", latest.ID)
+	}
+
+	resources, err := s.pulumiService.GetLatestStackResources(ctx, organization, project, stack)
+	if err != nil {
+		return "", record, err
+	}
+	if len(resources.Resources) > 0 {
+		return s.retryWorkloadDestroy(ctx, organization, project, stack, record,
+			fmt.Sprintf("stack still has %d resources, %s", len(resources.Resources), reason))
+	}
+
+	if err := s.pulumiService.DeleteStack(ctx, organization, project, stack); err != nil {
+		return "", record, err
+	}
+	if err := s.workloads.DeleteByStack(ctx, organization, project, stack); err != nil {
+		return "", record, err
+	}
+	return model.DestroyOutcomeDeleted, record, nil
+}
+
+// DestroyStack queues the destroy of a stack matched by a cleanup rule on behalf of the IDP.
+// Stacks that are already being destroyed or have a running operation are skipped.
+func (s *WorkloadService) DestroyStack(ctx context.Context, organization, project, stack string) (bool, error) {
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+	if record != nil {
+		switch record.Status {
+		case model.WorkloadStatusDeleting, model.WorkloadStatusDestroyFailed,
+			model.WorkloadStatusProvisioning, model.WorkloadStatusUpdating:
+			return false, nil
+		}
+	}
+
+	current, err := s.pulumiService.GetStack(ctx, project, stack)
+	if err != nil {
+		return false, err
+	}
+	if current.Tags["idp:auto-delete"] == "true" || current.CurrentOperation != nil {
+		return false, nil
+	}
+
+	if err := s.DeleteWorkload(withSystemUser(ctx), organization, project, stack); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// retryWorkloadDestroy schedules or queues the next destroy of a workload whose destroy failed
+func (s *WorkloadService) retryWorkloadDestroy(ctx context.Context, organization, project, stack string, record *model.WorkloadRecord, reason string) (string, *model.WorkloadRecord, error) {
+	// Retries are counted on the catalog record. Stacks without one, or that were not deleted
+	// through the IDP, are never destroyed by the cleanup.
+	if record == nil || record.Status != model.WorkloadStatusDeleting {
+		return "", record, fmt.Errorf("stack %s is not being deleted through the IDP and needs manual cleanup: %s", stack, reason)
+	}
+
+	now := time.Now()
+	if record.NextDestroyAt == nil {
+		log.Printf("Destroy of %s/%s/%s failed after %d retries: %s", organization, project, stack, record.DestroyAttempts, reason)
+		if record.DestroyAttempts >= s.cfg.Cleanup.MaxDestroyAttempts {
+			record.Status = model.WorkloadStatusDestroyFailed
+			if err := s.workloads.Save(ctx, record); err != nil {
+				return "", record, err
+			}
+			return model.DestroyOutcomeFailed, record, nil
+		}
+
+		next := now.Add(s.cfg.Cleanup.DestroyRetryBackoff << record.DestroyAttempts)
+		record.NextDestroyAt = &next
+		if err := s.workloads.Save(ctx, record); err != nil {
+			return "", record, err
+		}
+		return model.DestroyOutcomePending, record, nil
+	}
+
+	if now.Before(*record.NextDestroyAt) {
+		return model.DestroyOutcomePending, record, nil
+	}
+
+	if _, err := s.pulumiService.DeleteDeployment(ctx, organization, project, stack); err != nil {
+		return "", record, fmt.Errorf("failed to queue destroy retry: %w", err)
+	}
+	record.DestroyAttempts++
+	record.NextDestroyAt = nil
+	if err := s.workloads.Save(ctx, record); err != nil {
+		return "", record, err
+	}
+	return model.DestroyOutcomeRetried, record, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+// ErrInvalidExpiry is returned for a TTL or expiry that cannot be applied to a workload
+var ErrInvalidExpiry = errors.New("invalid expiry")
+
+// parseTTL parses a TTL given as a Go duration, like 36h, or as a number of days, like 7d
+func parseTTL(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%w: TTL %q is not a duration", ErrInvalidExpiry, value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: TTL %q is not a duration", ErrInvalidExpiry, value)
+	}
+	return ttl, nil
+}
+
+// resolveExpiry returns when a new workload expires: at the requested time, after the requested TTL,
+// or after the default TTL of its stage. Workloads without any of these never expire.
+func (s *WorkloadService) resolveExpiry(req *model.WorkloadRequest) (*time.Time, error) {
+	now := time.Now()
+	if req.ExpiresAt != nil {
+		return s.checkExpiry(now, *req.ExpiresAt)
+	}
+
+	ttl := req.TTL
+	if ttl == "" {
+		for _, entry := range s.cfg.Expiry.StageTTLs {
+			stage, stageTTL, found := strings.Cut(strings.TrimSpace(entry), ":")
+			if found && req.Stage != "" && strings.EqualFold(stage, req.Stage) {
+				ttl = stageTTL
+				break
+			}
+		}
+	}
+	if ttl == "" {
+		return nil, nil
+	}
+
+	duration, err := parseTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkExpiry(now, now.Add(duration))
+}
+
+// checkExpiry checks that an expiry lies in the future and within the maximum TTL
+func (s *WorkloadService) checkExpiry(now, expiresAt time.Time) (*time.Time, error) {
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: %s is not in the future", ErrInvalidExpiry, expiresAt.Format(time.RFC3339))
+	}
+	if s.cfg.Expiry.MaxTTL > 0 && expiresAt.After(now.Add(s.cfg.Expiry.MaxTTL)) {
+		return nil, fmt.Errorf("%w: %s exceeds the maximum TTL of %s", ErrInvalidExpiry, expiresAt.Format(time.RFC3339), s.cfg.Expiry.MaxTTL)
+	}
+	expiresAt = expiresAt.UTC().Truncate(time.Second)
+	return &expiresAt, nil
+}
+
+// ExtendWorkload moves the expiry of a workload. A TTL extends the current expiry, or the
+// current time if the workload has none or is already past it. The owners are warned again
+// before the new expiry.
+func (s *WorkloadService) ExtendWorkload(ctx context.Context, organization, project, stack string, req *model.ExtendRequest) (*model.WorkloadRecord, error) {
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status == model.WorkloadStatusDeleting {
+		return nil, fmt.Errorf("%w: workload %s is being deleted", ErrInvalidExpiry, stack)
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	switch {
+	case req.ExpiresAt != nil:
+		expiresAt, err = s.checkExpiry(now, *req.ExpiresAt)
+	case req.TTL != "":
+		var ttl time.Duration
+		ttl, err = parseTTL(req.TTL)
+		if err != nil {
+			break
+		}
+		base := now
+		if record.ExpiresAt != nil && record.ExpiresAt.After(now) {
+			base = *record.ExpiresAt
+		}
+		expiresAt, err = s.checkExpiry(now, base.Add(ttl))
+	default:
+		err = fmt.Errorf("%w: either ttl or expiresAt is required", ErrInvalidExpiry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	before := workloadSnapshot(record)
+	if err := s.pulumiService.SetStackTag(ctx, organization, project, stack, model.Tag{
+		Key:   model.ExpiresAtTag,
+		Value: expiresAt.Format(time.RFC3339),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set stack tag %s: %w", model.ExpiresAtTag, err)
+	}
+
+	record.ExpiresAt = expiresAt
+	record.ExpiryNotifiedAt = nil
+	record.UpdatedBy = actor(ctx)
+	if err := s.workloads.Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, &model.AuditEvent{
+		Actor:        actor(ctx),
+		Action:       model.AuditActionWorkloadExtend,
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Blueprint:    recordBlueprintName(record),
+		Outcome:      model.JobStatusSucceeded,
+		Before:       before,
+		After:        workloadSnapshot(record),
+	})
+	return record, nil
+}
+
+// ExpiringWorkloads returns the workloads that expire before the given time and are not being deleted yet
+func (s *WorkloadService) ExpiringWorkloads(ctx context.Context, until time.Time) ([]model.WorkloadRecord, error) {
+	return s.workloads.ListExpiring(ctx, until)
+}
+
+// ExpireWorkload queues the destroy of an expired workload. The stack is deleted by the cleanup
+// once the destroy has run, like for any other deleted workload.
+func (s *WorkloadService) ExpireWorkload(ctx context.Context, record *model.WorkloadRecord) error {
+	return s.DeleteWorkload(withSystemUser(ctx), record.Organization, record.Blueprint, record.Stack)
+}
+
+// MarkExpiryNotified records that the owners of a workload were warned about its expiry
+func (s *WorkloadService) MarkExpiryNotified(ctx context.Context, record *model.WorkloadRecord) error {
+	now := time.Now()
+	record.ExpiryNotifiedAt = &now
+	return s.workloads.Save(ctx, record)
+}
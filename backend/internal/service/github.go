@@ -3,7 +3,11 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/go-github/github"
 	"github.com/pulumi-idp/internal/config"
@@ -15,9 +19,16 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// ErrWebhookNotConfigured is returned for webhook deliveries while no webhook secret is set
+var ErrWebhookNotConfigured = errors.New("GitHub webhook secret is not configured")
+
+// ErrInvalidWebhookSignature is returned for webhook deliveries that were not signed with the webhook secret
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
 // GitHubService implements GitHubServiceInterface
 type GitHubService struct {
 	cfg *config.Config
@@ -30,8 +41,32 @@ func NewGitHubService(cfg *config.Config) *GitHubService {
 	}
 }
 
+// VerifyWebhookSignature checks the X-Hub-Signature-256 header of a webhook delivery,
+// the HMAC-SHA256 of the payload keyed with the webhook secret
+func (s *GitHubService) VerifyWebhookSignature(payload []byte, signature string) error {
+	if s.cfg.GitHub.WebhookSecret == "" {
+		return ErrWebhookNotConfigured
+	}
+
+	digest, found := strings.CutPrefix(signature, "sha256=")
+	if !found {
+		return ErrInvalidWebhookSignature
+	}
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.GitHub.WebhookSecret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
 // ExchangeCodeForToken exchanges GitHub OAuth code for access token
-func (s *GitHubService) ExchangeCodeForToken(code string) (*model.OAuthResponse, error) {
+func (s *GitHubService) ExchangeCodeForToken(ctx context.Context, code string) (*model.OAuthResponse, error) {
 	if code == "" {
 		return nil, fmt.Errorf("missing required field: code")
 	}
@@ -47,11 +82,13 @@ func (s *GitHubService) ExchangeCodeForToken(code string) (*model.OAuthResponse,
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(
-		"https://github.com/login/oauth/access_token",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://github.com/login/oauth/access_token", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request GitHub: %w", err)
 	}
@@ -109,6 +146,17 @@ func (s *GitHubService) CreateRepository(ctx context.Context, req *model.RepoCre
 	return repo, resp, nil
 }
 
+// DeleteRepository deletes a GitHub repository
+func (s *GitHubService) DeleteRepository(ctx context.Context, owner, repo string) error {
+	client := s.getGitHubClient(ctx)
+
+	if _, err := client.Repositories.Delete(ctx, owner, repo); err != nil {
+		return fmt.Errorf("failed to delete repository: %w", err)
+	}
+
+	return nil
+}
+
 // SetupBranchProtection sets up branch protection for a repository
 func (s *GitHubService) SetupBranchProtection(ctx context.Context, owner, repo, branch string, requireReviews bool) error {
 	client := s.getGitHubClient(ctx)
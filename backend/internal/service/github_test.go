@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+)
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.GitHub.WebhookSecret = "It's a Secret to Everybody"
+	github := NewGitHubService(cfg)
+
+	payload := `{"ref":"refs/heads/main"}`
+	tests := []struct {
+		name      string
+		payload   string
+		signature string
+		want      error
+	}{
+		// The example of the GitHub webhook documentation
+		{"documented example", "Hello, World!", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", nil},
+		{"valid signature", payload, sign(cfg.GitHub.WebhookSecret, payload), nil},
+		{"other secret", payload, sign("other secret", payload), ErrInvalidWebhookSignature},
+		{"tampered payload", payload + " ", sign(cfg.GitHub.WebhookSecret, payload), ErrInvalidWebhookSignature},
+		{"SHA-1 signature", payload, "sha1=2fd4e1c67a2d28fced849ee1bb76e7391b93eb12", ErrInvalidWebhookSignature},
+		{"not hex", payload, "sha256=not-a-digest", ErrInvalidWebhookSignature},
+		{"no signature", payload, "", ErrInvalidWebhookSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := github.VerifyWebhookSignature([]byte(test.payload), test.signature)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("VerifyWebhookSignature = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignatureRequiresSecret(t *testing.T) {
+	github := NewGitHubService(&config.Config{})
+
+	payload := `{"zen":"Keep it logically awesome."}`
+	err := github.VerifyWebhookSignature([]byte(payload), sign("", payload))
+	if !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Fatalf("expected ErrWebhookNotConfigured, got %v", err)
+	}
+}
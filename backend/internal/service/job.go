@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// ErrJobInProgress is returned for a change of a workload while another job for its stack is running
+var ErrJobInProgress = errors.New("another job is running for this workload")
+
+// UndoFunc reverts the effect of an action performed by a job step
+type UndoFunc func(ctx context.Context) error
+
+// JobStep is a named unit of work executed by a provisioning job.
+// Run registers an undo action with the UndoLog for everything it creates,
+// so that a later failure can be compensated.
+type JobStep struct {
+	Name string
+	Run  func(ctx context.Context, undo *UndoLog) error
+}
+
+// undoEntry is a registered compensation for a completed action
+type undoEntry struct {
+	step   string
+	action string
+	undo   UndoFunc
+}
+
+// UndoLog collects the undo actions registered while a job runs
+type UndoLog struct {
+	step    string
+	entries []undoEntry
+}
+
+// Register records an undo action for the step that is currently running
+func (l *UndoLog) Register(action string, undo UndoFunc) {
+	l.entries = append(l.entries, undoEntry{
+		step:   l.step,
+		action: action,
+		undo:   undo,
+	})
+}
+
+// JobService runs provisioning jobs in the background and records their progress.
+// Jobs run on the replica that accepted them rather than on the leader: their steps are closures
+// over the request, which are not persisted, so no other replica could pick them up. The leader
+// fails the jobs of replicas that stopped instead, see FailOrphanedJobs.
+type JobService struct {
+	cfg    *config.Config
+	jobs   *repository.JobRepository
+	logger *log.Logger
+	// running tracks the jobs of this replica, so that shutdown can wait for them
+	running sync.WaitGroup
+	// ctx is the parent of the job contexts, it is cancelled when shutdown stops waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// jobCancelGrace is how long cancelled jobs are given to record that they failed
+const jobCancelGrace = 5 * time.Second
+
+// rollbackTimeout bounds the undo actions of a failed job
+const rollbackTimeout = 2 * time.Minute
+
+// NewJobService creates a new JobService instance
+func NewJobService(cfg *config.Config, jobs *repository.JobRepository) *JobService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobService{
+		cfg:    cfg,
+		jobs:   jobs,
+		logger: log.New(log.Writer(), "[ProvisioningJob] ", log.LstdFlags),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// CreateJob persists a new pending job with one pending entry per step
+func (s *JobService) CreateJob(ctx context.Context, kind, organization, project, stack string, steps []JobStep) (*model.ProvisioningJob, error) {
+	job := &model.ProvisioningJob{
+		ID:           uuid.NewString(),
+		Kind:         kind,
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Status:       model.JobStatusPending,
+		RequestedBy:  actor(ctx),
+		Worker:       s.cfg.Leader.ID,
+		Steps:        make([]model.ProvisioningStep, 0, len(steps)),
+	}
+
+	for i, step := range steps {
+		job.Steps = append(job.Steps, model.ProvisioningStep{
+			Position: i,
+			Name:     step.Name,
+			Status:   model.JobStatusPending,
+		})
+	}
+
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Start runs the steps of a created job in order in the background.
+// Execution stops at the first failing step, the remaining steps are skipped and
+// the registered undo actions run in reverse order.
+// onComplete is called once the job has finished, successfully or not.
+// The job runs with its own context, independent of the request that started it.
+// Start returns a copy of the job as it was started, the job itself belongs to the run from
+// then on and must not be read by the caller.
+func (s *JobService) Start(job *model.ProvisioningJob, steps []JobStep, onComplete func(ctx context.Context, job *model.ProvisioningJob)) *model.ProvisioningJob {
+	started := copyJob(job)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		ctx, cancel := context.WithCancel(s.ctx)
+		defer cancel()
+
+		s.run(ctx, job, steps)
+
+		if onComplete != nil {
+			// The outcome is recorded even if the job was cancelled
+			onComplete(context.WithoutCancel(ctx), job)
+		}
+	}()
+
+	return started
+}
+
+// copyJob copies a job with the steps and rollback actions a run changes
+func copyJob(job *model.ProvisioningJob) *model.ProvisioningJob {
+	copied := *job
+	copied.Steps = slices.Clone(job.Steps)
+	copied.Rollback = slices.Clone(job.Rollback)
+	return &copied
+}
+
+// HasUnfinishedJob reports whether a job for the stack is still pending or running
+func (s *JobService) HasUnfinishedJob(ctx context.Context, organization, project, stack string) (bool, error) {
+	return s.jobs.HasUnfinished(ctx, organization, project, stack)
+}
+
+// GetJob retrieves a job and its steps
+func (s *JobService) GetJob(ctx context.Context, id string) (*model.ProvisioningJob, error) {
+	return s.jobs.FindByID(ctx, id)
+}
+
+// Shutdown waits for the running jobs of this replica to finish or the context to end, then
+// cancels the jobs still running. Jobs that could not record their failure are failed by
+// the leader once the worker lease of this replica has expired.
+func (s *JobService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Cancel the remaining jobs, so that they are recorded as failed before the process exits
+	s.cancel()
+	select {
+	case <-done:
+	case <-time.After(jobCancelGrace):
+	}
+	return fmt.Errorf("jobs cancelled: %w", ctx.Err())
+}
+
+// FailOrphanedJobs fails and returns the unfinished jobs of replicas that are no longer alive.
+// Their steps never finish, so nothing is rolled back. Jobs without a worker predate leader
+// election and are left alone.
+func (s *JobService) FailOrphanedJobs(ctx context.Context, workers []string) ([]model.ProvisioningJob, error) {
+	jobs, err := s.jobs.ListUnfinished(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []model.ProvisioningJob
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Worker == "" || slices.Contains(workers, job.Worker) {
+			continue
+		}
+
+		reason := fmt.Sprintf("interrupted, worker %s stopped while the job was running", job.Worker)
+		for j := range job.Steps {
+			step := &job.Steps[j]
+			switch step.Status {
+			case model.JobStatusRunning:
+				step.Status = model.JobStatusFailed
+				step.Error = reason
+			case model.JobStatusPending:
+				step.Status = model.JobStatusSkipped
+			default:
+				continue
+			}
+			s.saveStep(ctx, job, step)
+		}
+
+		finished := time.Now()
+		job.FinishedAt = &finished
+		job.Status = model.JobStatusFailed
+		job.Error = reason
+		s.saveJob(ctx, job)
+		s.logger.Printf("Job %s for %s/%s/%s failed: %s", job.ID, job.Organization, job.Project, job.Stack, reason)
+		failed = append(failed, *job)
+	}
+	return failed, nil
+}
+
+// run executes the job steps and persists every status transition
+func (s *JobService) run(ctx context.Context, job *model.ProvisioningJob, steps []JobStep) {
+	// A cancelled job still records its progress
+	store := context.WithoutCancel(ctx)
+
+	job.Status = model.JobStatusRunning
+	s.saveJob(store, job)
+
+	undoLog := &UndoLog{}
+
+	var failed error
+	for i := range steps {
+		step := &job.Steps[i]
+
+		if failed != nil {
+			step.Status = model.JobStatusSkipped
+			s.saveStep(store, job, step)
+			continue
+		}
+
+		started := time.Now()
+		step.StartedAt = &started
+		step.Status = model.JobStatusRunning
+		s.saveStep(store, job, step)
+
+		undoLog.step = step.Name
+		err := steps[i].Run(ctx, undoLog)
+
+		finished := time.Now()
+		step.FinishedAt = &finished
+		if err != nil {
+			step.Status = model.JobStatusFailed
+			step.Error = err.Error()
+			failed = fmt.Errorf("step %s failed: %w", step.Name, err)
+		} else {
+			step.Status = model.JobStatusSucceeded
+		}
+		s.saveStep(store, job, step)
+	}
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if failed != nil {
+		job.Status = model.JobStatusFailed
+		job.Error = failed.Error()
+		s.logger.Printf("Job %s for %s/%s/%s failed: %v", job.ID, job.Organization, job.Project, job.Stack, failed)
+		s.rollback(ctx, job, undoLog)
+	} else {
+		job.Status = model.JobStatusSucceeded
+		s.logger.Printf("Job %s for %s/%s/%s succeeded", job.ID, job.Organization, job.Project, job.Stack)
+	}
+	s.saveJob(store, job)
+}
+
+// rollback runs the registered undo actions in reverse order and records their outcome on the job
+func (s *JobService) rollback(ctx context.Context, job *model.ProvisioningJob, undoLog *UndoLog) {
+	if len(undoLog.entries) == 0 {
+		return
+	}
+
+	// A cancelled job is still rolled back, within the grace period shutdown waits for it
+	timeout := rollbackTimeout
+	if ctx.Err() != nil {
+		timeout = jobCancelGrace
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	job.RollbackStatus = model.RollbackStatusSucceeded
+	for i := len(undoLog.entries) - 1; i >= 0; i-- {
+		entry := undoLog.entries[i]
+		action := model.RollbackAction{
+			Step:   entry.step,
+			Action: entry.action,
+			Status: model.RollbackStatusSucceeded,
+		}
+
+		// Keep going on errors so that one stuck resource does not orphan the others
+		if err := entry.undo(ctx); err != nil {
+			action.Status = model.RollbackStatusFailed
+			action.Error = err.Error()
+			job.RollbackStatus = model.RollbackStatusFailed
+			s.logger.Printf("Job %s: rollback action %s failed: %v", job.ID, entry.action, err)
+		}
+
+		job.Rollback = append(job.Rollback, action)
+	}
+
+	s.logger.Printf("Job %s rollback %s", job.ID, job.RollbackStatus)
+}
+
+func (s *JobService) saveJob(ctx context.Context, job *model.ProvisioningJob) {
+	if err := s.jobs.SaveJob(ctx, job); err != nil {
+		s.logger.Printf("Failed to persist job %s: %v", job.ID, err)
+	}
+}
+
+func (s *JobService) saveStep(ctx context.Context, job *model.ProvisioningJob, step *model.ProvisioningStep) {
+	if err := s.jobs.SaveStep(ctx, step); err != nil {
+		s.logger.Printf("Failed to persist step %s of job %s: %v", step.Name, job.ID, err)
+	}
+}
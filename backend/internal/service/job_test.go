@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+func TestCancelledJobIsRolledBack(t *testing.T) {
+	jobs := repository.NewJobRepository(newTestDB(t, &model.ProvisioningJob{}, &model.ProvisioningStep{}))
+	s := NewJobService(&config.Config{}, jobs)
+
+	undone := make(chan error, 1)
+	steps := []JobStep{{
+		Name: "create",
+		Run: func(ctx context.Context, undo *UndoLog) error {
+			undo.Register("delete", func(ctx context.Context) error {
+				undone <- ctx.Err()
+				return nil
+			})
+			// The step gives up when shutdown cancels the job
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}}
+
+	job, err := s.CreateJob(context.Background(), "provision", "acme", "web", "dev", steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished := make(chan *model.ProvisioningJob, 1)
+	s.Start(job, steps, func(ctx context.Context, job *model.ProvisioningJob) {
+		finished <- job
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil {
+		t.Fatal("expected the job to be cancelled")
+	}
+
+	if err := <-undone; err != nil {
+		t.Fatalf("the undo action ran with a cancelled context: %v", err)
+	}
+	if job := <-finished; job.RollbackStatus != model.RollbackStatusSucceeded {
+		t.Fatalf("rollback status %s", job.RollbackStatus)
+	}
+}
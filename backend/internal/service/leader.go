@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// LeaderService elects the replica that runs the background work through a lease in the
+// database. Every replica also renews a worker lease, so that the leader can tell which
+// replicas are still running jobs.
+type LeaderService struct {
+	cfg             *config.Config
+	leases          *repository.LeaseRepository
+	workloadService *WorkloadService
+	logger          *log.Logger
+	mutex           sync.Mutex
+	leader          bool
+}
+
+// NewLeaderService creates a new LeaderService instance
+func NewLeaderService(cfg *config.Config, leases *repository.LeaseRepository) *LeaderService {
+	return &LeaderService{
+		cfg:    cfg,
+		leases: leases,
+		logger: log.New(log.Writer(), "[Leader] ", log.LstdFlags),
+	}
+}
+
+func (s *LeaderService) SetWorkloadService(service *WorkloadService) {
+	s.workloadService = service
+}
+
+// IsLeader returns whether this replica currently holds the leader lease
+func (s *LeaderService) IsLeader() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.leader
+}
+
+// Leader returns the replica holding the leader lease, or an empty string if it has expired
+func (s *LeaderService) Leader(ctx context.Context) (string, error) {
+	lease, err := s.leases.Find(ctx, model.LeaseLeader)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if lease.ExpiresAt.Before(time.Now()) {
+		return "", nil
+	}
+	return lease.Holder, nil
+}
+
+// Run renews the leases until the context ends, then releases them. onChange is called
+// whenever this replica gains or loses the leadership.
+func (s *LeaderService) Run(ctx context.Context, onChange func(leader bool)) {
+	id := s.cfg.Leader.ID
+	s.logger.Printf("Replica %s joined the leader election", id)
+
+	ticker := time.NewTicker(s.cfg.Leader.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		s.renew(ctx, onChange)
+
+		select {
+		case <-ctx.Done():
+			// The leases are released with a fresh context, the run context has already ended
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			s.setLeader(false, onChange)
+			if err := s.leases.Release(releaseCtx, model.LeaseLeader, id); err != nil {
+				s.logger.Printf("Failed to release the leader lease: %v", err)
+			}
+			if err := s.leases.Release(releaseCtx, model.WorkerLeasePrefix+id, id); err != nil {
+				s.logger.Printf("Failed to release the worker lease: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew renews the worker lease, tries to acquire or renew the leader lease and, as leader,
+// recovers the jobs of replicas that are gone
+func (s *LeaderService) renew(ctx context.Context, onChange func(leader bool)) {
+	id := s.cfg.Leader.ID
+	duration := s.cfg.Leader.LeaseDuration
+
+	if _, err := s.leases.Acquire(ctx, model.WorkerLeasePrefix+id, id, duration); err != nil {
+		s.logger.Printf("Failed to renew the worker lease: %v", err)
+	}
+
+	leader, err := s.leases.Acquire(ctx, model.LeaseLeader, id, duration)
+	if err != nil {
+		// Without a renewed lease another replica may take over, so the work stops here
+		s.logger.Printf("Failed to renew the leader lease: %v", err)
+		leader = false
+	}
+	s.setLeader(leader, onChange)
+
+	if !leader || s.workloadService == nil {
+		return
+	}
+
+	workers, err := s.leases.ListActive(ctx, model.WorkerLeasePrefix)
+	if err != nil {
+		s.logger.Printf("Failed to list the live replicas: %v", err)
+		return
+	}
+	alive := make([]string, 0, len(workers))
+	for _, worker := range workers {
+		alive = append(alive, strings.TrimPrefix(worker.Name, model.WorkerLeasePrefix))
+	}
+	if err := s.workloadService.RecoverOrphanedJobs(ctx, alive); err != nil {
+		s.logger.Printf("Failed to recover the jobs of stopped replicas: %v", err)
+	}
+}
+
+func (s *LeaderService) setLeader(leader bool, onChange func(leader bool)) {
+	s.mutex.Lock()
+	changed := s.leader != leader
+	s.leader = leader
+	s.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if leader {
+		s.logger.Printf("Replica %s is the leader", s.cfg.Leader.ID)
+	} else {
+		s.logger.Printf("Replica %s is no longer the leader", s.cfg.Leader.ID)
+	}
+	if onChange != nil {
+		onChange(leader)
+	}
+}
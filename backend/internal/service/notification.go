@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+)
+
+// NotificationService sends notifications about workloads to the configured webhook.
+// Without a webhook, notifications are only logged.
+type NotificationService struct {
+	cfg        *config.Config
+	httpClient *http.Client
+	logger     *log.Logger
+}
+
+// NewNotificationService creates a new NotificationService instance
+func NewNotificationService(cfg *config.Config) *NotificationService {
+	return &NotificationService{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		logger: log.New(log.Writer(), "[Notification] ", log.LstdFlags),
+	}
+}
+
+// Notify sends a notification
+func (s *NotificationService) Notify(ctx context.Context, notification *model.Notification) error {
+	s.logger.Printf("%s: %s", notification.Event, notification.Text)
+	if s.cfg.Notify.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error encoding notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Notify.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("notification webhook failed: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// expiryNotification describes the upcoming or passed expiry of a workload
+func expiryNotification(event string, record *model.WorkloadRecord) *model.Notification {
+	notification := &model.Notification{
+		Event:        event,
+		Organization: record.Organization,
+		Project:      record.Blueprint,
+		Stack:        record.Stack,
+		Name:         record.Name,
+		Team:         record.Team,
+		Owner:        record.CreatedBy,
+		ExpiresAt:    record.ExpiresAt,
+	}
+
+	expiresAt := ""
+	if record.ExpiresAt != nil {
+		expiresAt = record.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	switch event {
+	case model.NotificationWorkloadExpiring:
+		notification.Text = fmt.Sprintf("Workload %s (%s/%s) expires at %s and will then be destroyed. Extend its TTL to keep it.",
+			record.Name, record.Blueprint, record.Stack, expiresAt)
+	case model.NotificationWorkloadExpired:
+		notification.Text = fmt.Sprintf("Workload %s (%s/%s) expired at %s and is being destroyed.",
+			record.Name, record.Blueprint, record.Stack, expiresAt)
+	}
+	return notification
+}
+
+// NotifyDestroyFailed reports a workload whose destroy failed for good
+func (s *NotificationService) NotifyDestroyFailed(ctx context.Context, organization, project, stack string, record *model.WorkloadRecord) error {
+	notification := &model.Notification{
+		Event:        model.NotificationWorkloadDestroyFailed,
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Name:         stack,
+		Text: fmt.Sprintf("The destroy of workload %s/%s kept failing. The stack was kept and needs to be cleaned up manually.",
+			project, stack),
+	}
+	if record != nil {
+		notification.Name = record.Name
+		notification.Team = record.Team
+		notification.Owner = record.CreatedBy
+	}
+	return s.Notify(ctx, notification)
+}
+
+// NotifyCleanupMatched reports a stack that matched a cleanup rule which only notifies
+func (s *NotificationService) NotifyCleanupMatched(ctx context.Context, rule, organization, project, stack string, record *model.WorkloadRecord) error {
+	notification := &model.Notification{
+		Event:        model.NotificationStackCleanupMatched,
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Name:         stack,
+		Text: fmt.Sprintf("Stack %s/%s matches the cleanup rule %s. Please check whether it is still needed.",
+			project, stack, rule),
+	}
+	if record != nil {
+		notification.Name = record.Name
+		notification.Team = record.Team
+		notification.Owner = record.CreatedBy
+	}
+	return s.Notify(ctx, notification)
+}
+
+// NotifyExpiry sends the expiring or expired notification of a workload
+func (s *NotificationService) NotifyExpiry(ctx context.Context, event string, record *model.WorkloadRecord) error {
+	return s.Notify(ctx, expiryNotification(event, record))
+}
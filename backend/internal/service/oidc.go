@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pulumi-idp/internal/model"
+)
+
+// oidcVerifier verifies JWTs issued by an OIDC provider against the provider's published signing keys
+type oidcVerifier struct {
+	issuer      string
+	audience    string
+	groupsClaim string
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+// newOIDCVerifier creates a verifier for the tokens of an issuer. Tokens are only accepted for
+// the audience, otherwise every token of a shared issuer would be valid.
+func newOIDCVerifier(issuer, audience, groupsClaim string, httpClient *http.Client) (*oidcVerifier, error) {
+	if audience == "" {
+		return nil, fmt.Errorf("AUTH_OIDC_AUDIENCE is required when AUTH_OIDC_ISSUER is set")
+	}
+
+	return &oidcVerifier{
+		issuer:      issuer,
+		audience:    audience,
+		groupsClaim: groupsClaim,
+		httpClient:  httpClient,
+	}, nil
+}
+
+// verify checks the signature, issuer, audience and lifetime of a JWT and returns the user it identifies
+func (v *oidcVerifier) verify(ctx context.Context, token string) (*model.User, error) {
+	verifier, err := v.tokenVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := verifier.Verify(oidc.ClientContext(ctx, v.httpClient), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrUnauthenticated)
+	}
+
+	user := &model.User{
+		Login:    firstStringClaim(claims, "preferred_username", "email", "sub"),
+		Name:     firstStringClaim(claims, "name"),
+		Email:    firstStringClaim(claims, "email"),
+		Provider: model.AuthProviderOIDC,
+	}
+	if groups, ok := claims[v.groupsClaim].([]interface{}); ok {
+		for _, group := range groups {
+			if group, ok := group.(string); ok {
+				user.Teams = append(user.Teams, group)
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// tokenVerifier reads the discovery document of the issuer on first use, so that the server
+// starts while the issuer is unreachable. The provider refreshes its signing keys on its own.
+func (v *oidcVerifier) tokenVerifier() (*oidc.IDTokenVerifier, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.verifier != nil {
+		return v.verifier, nil
+	}
+
+	// The key set fetches keys with this context for as long as the server runs
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), v.httpClient), v.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC discovery document: %w", err)
+	}
+	v.verifier = provider.Verifier(&oidc.Config{ClientID: v.audience})
+	return v.verifier, nil
+}
+
+// firstStringClaim returns the first non-empty string claim of the given names
+func firstStringClaim(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if value, ok := claims[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// testIssuer serves an OIDC discovery document and key set and signs tokens with its key
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"jwks_uri":                              issuer.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &key.PublicKey,
+			KeyID:     "test",
+			Algorithm: "RS256",
+			Use:       "sig",
+		}}})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: i.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (i *testIssuer) claims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":                i.server.URL,
+		"aud":                "pulumi-idp",
+		"sub":                "1234",
+		"preferred_username": "alice",
+		"groups":             []string{"platform"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestNewOIDCVerifierRequiresAudience(t *testing.T) {
+	if _, err := newOIDCVerifier("https://issuer.example.com", "", "groups", http.DefaultClient); err == nil {
+		t.Fatal("expected an error for an issuer without audience")
+	}
+}
+
+func TestOIDCVerifierAcceptsValidToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier, err := newOIDCVerifier(issuer.server.URL, "pulumi-idp", "groups", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := verifier.verify(context.Background(), issuer.sign(t, issuer.claims(nil)))
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if user.Login != "alice" || len(user.Teams) != 1 || user.Teams[0] != "platform" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+}
+
+func TestOIDCVerifierRejectsInvalidClaims(t *testing.T) {
+	issuer := newTestIssuer(t)
+	other := newTestIssuer(t)
+	verifier, err := newOIDCVerifier(issuer.server.URL, "pulumi-idp", "groups", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"other audience":   issuer.sign(t, issuer.claims(map[string]interface{}{"aud": "other-app"})),
+		"no audience":      issuer.sign(t, issuer.claims(map[string]interface{}{"aud": nil})),
+		"other issuer":     issuer.sign(t, issuer.claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"expired":          issuer.sign(t, issuer.claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"other signer":     other.sign(t, issuer.claims(nil)),
+		"malformed token":  "not.a.jwt",
+		"tampered payload": issuer.sign(t, issuer.claims(nil))[:20] + "x" + issuer.sign(t, issuer.claims(nil))[21:],
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := verifier.verify(context.Background(), token); !errors.Is(err, ErrUnauthenticated) {
+				t.Fatalf("expected ErrUnauthenticated, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyViolationError reports the policies a workload request does not satisfy
+type PolicyViolationError struct {
+	Violations []model.PolicyViolation
+}
+
+func (e *PolicyViolationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.Policy, violation.Message))
+	}
+	return "workload request violates policies: " + strings.Join(messages, "; ")
+}
+
+// compiledPolicy is a policy with its CEL programs
+type compiledPolicy struct {
+	model.Policy
+	when cel.Program
+	rule cel.Program
+}
+
+// PolicyService admits workload requests against the CEL policies in the policy directory.
+// Policies are evaluated in-process; without a policy directory every request is admitted.
+type PolicyService struct {
+	cfg       *config.Config
+	workloads *repository.WorkloadRepository
+	policies  []compiledPolicy
+}
+
+// NewPolicyService creates a new PolicyService instance
+func NewPolicyService(cfg *config.Config, workloads *repository.WorkloadRepository) *PolicyService {
+	return &PolicyService{
+		cfg:       cfg,
+		workloads: workloads,
+	}
+}
+
+// Load reads and compiles the policies of the *.yaml and *.yml files in the policy directory
+func (s *PolicyService) Load() error {
+	if s.cfg.Policy.Dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(s.cfg.Policy.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read policy directory: %w", err)
+	}
+
+	env, err := policyEnv()
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+
+	var policies []compiledPolicy
+	names := make(map[string]string)
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(s.cfg.Policy.Dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read policy file %s: %w", name, err)
+		}
+
+		var file model.PolicyFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse policy file %s: %w", name, err)
+		}
+
+		for _, policy := range file.Policies {
+			if policy.Name == "" {
+				return fmt.Errorf("policy file %s has a policy without a name", name)
+			}
+			if other, ok := names[policy.Name]; ok {
+				return fmt.Errorf("policy %s of %s is already defined in %s", policy.Name, name, other)
+			}
+			names[policy.Name] = name
+
+			compiled, err := compilePolicy(env, policy)
+			if err != nil {
+				return fmt.Errorf("policy %s of %s: %w", policy.Name, name, err)
+			}
+			policies = append(policies, compiled)
+		}
+	}
+
+	s.policies = policies
+	return nil
+}
+
+// Policies returns the loaded policies
+func (s *PolicyService) Policies() []model.Policy {
+	policies := make([]model.Policy, 0, len(s.policies))
+	for _, policy := range s.policies {
+		policies = append(policies, policy.Policy)
+	}
+	return policies
+}
+
+// EvaluateCreate returns the policies a create request violates
+func (s *PolicyService) EvaluateCreate(ctx context.Context, req *model.WorkloadRequest) ([]model.PolicyViolation, error) {
+	return s.evaluate(ctx, model.PolicyOperationCreate, req, nil)
+}
+
+// EvaluateUpdate returns the policies an update violates. Policies see the workload as it would
+// be after the update: the stored create request with the updated fields applied.
+func (s *PolicyService) EvaluateUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) ([]model.PolicyViolation, error) {
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	effective := *req
+	if record != nil {
+		effective = record.CreationRequest
+		effective.Name = record.Name
+		if req.Stage != "" {
+			effective.Stage = req.Stage
+		}
+		if req.Team != "" {
+			effective.Team = req.Team
+		}
+		if req.ProjectID != "" {
+			effective.ProjectID = req.ProjectID
+		}
+		if req.Version != "" {
+			effective.Version = req.Version
+		}
+		effective.Advanced = append(append([]map[string]interface{}{}, record.CreationRequest.Advanced...), req.Advanced...)
+		effective.DryRun = req.DryRun
+	}
+	return s.evaluate(ctx, model.PolicyOperationUpdate, &effective, record)
+}
+
+// AdmitCreate returns a PolicyViolationError if a create request violates any policy
+func (s *PolicyService) AdmitCreate(ctx context.Context, req *model.WorkloadRequest) error {
+	return admit(s.EvaluateCreate(ctx, req))
+}
+
+// AdmitUpdate returns a PolicyViolationError if an update violates any policy
+func (s *PolicyService) AdmitUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) error {
+	return admit(s.EvaluateUpdate(ctx, organization, project, stack, req))
+}
+
+func (s *PolicyService) evaluate(ctx context.Context, operation string, req *model.WorkloadRequest, record *model.WorkloadRecord) ([]model.PolicyViolation, error) {
+	violations := []model.PolicyViolation{}
+	if len(s.policies) == 0 {
+		return violations, nil
+	}
+
+	activation, err := s.activation(ctx, operation, req, record)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range s.policies {
+		if len(policy.Operations) > 0 && !containsFold(policy.Operations, operation) {
+			continue
+		}
+
+		if policy.when != nil {
+			applies, err := evalBool(policy.when, activation)
+			if err != nil {
+				violations = append(violations, policyError(policy, err))
+				continue
+			}
+			if !applies {
+				continue
+			}
+		}
+
+		// Rules that cannot be evaluated reject the request rather than letting it through
+		allowed, err := evalBool(policy.rule, activation)
+		if err != nil {
+			violations = append(violations, policyError(policy, err))
+			continue
+		}
+		if !allowed {
+			message := policy.Message
+			if message == "" {
+				message = fmt.Sprintf("request violates policy %s", policy.Name)
+			}
+			violations = append(violations, model.PolicyViolation{
+				Policy:  policy.Name,
+				Field:   policy.Field,
+				Message: message,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// activation builds the variables policies are evaluated against
+func (s *PolicyService) activation(ctx context.Context, operation string, req *model.WorkloadRequest, record *model.WorkloadRecord) (map[string]interface{}, error) {
+	request, err := toJSONValue(req)
+	if err != nil {
+		return nil, err
+	}
+
+	user := map[string]interface{}{
+		"login":    "",
+		"provider": "",
+		"teams":    []string{},
+		"role":     "",
+	}
+	if caller := UserFromContext(ctx); caller != nil {
+		user["login"] = caller.Login
+		user["provider"] = caller.Provider
+		user["role"] = caller.Role
+		if caller.Teams != nil {
+			user["teams"] = caller.Teams
+		}
+	}
+
+	projectWorkloads := 0
+	if req.ProjectID != "" {
+		records, err := s.workloads.List(ctx, model.WorkloadFilter{
+			Organization: s.cfg.Pulumi.Organization,
+			ProjectID:    req.ProjectID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range records {
+			if other.Status == model.WorkloadStatusDeleting {
+				continue
+			}
+			if record != nil && other.ID == record.ID {
+				continue
+			}
+			projectWorkloads++
+		}
+	}
+
+	return map[string]interface{}{
+		"request":          request,
+		"operation":        operation,
+		"user":             user,
+		"projectWorkloads": projectWorkloads,
+	}, nil
+}
+
+// policyEnv declares the variables policies can refer to
+func policyEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request", cel.DynType),
+		cel.Variable("operation", cel.StringType),
+		cel.Variable("user", cel.DynType),
+		cel.Variable("projectWorkloads", cel.IntType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy environment: %w", err)
+	}
+	return env, nil
+}
+
+func compilePolicy(env *cel.Env, policy model.Policy) (compiledPolicy, error) {
+	compiled := compiledPolicy{Policy: policy}
+	if policy.Rule == "" {
+		return compiled, fmt.Errorf("rule is required")
+	}
+
+	rule, err := compileExpression(env, policy.Rule)
+	if err != nil {
+		return compiled, fmt.Errorf("invalid rule: %w", err)
+	}
+	compiled.rule = rule
+
+	if policy.When != "" {
+		when, err := compileExpression(env, policy.When)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid condition: %w", err)
+		}
+		compiled.when = when
+	}
+	return compiled, nil
+}
+
+func compileExpression(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+func evalBool(program cel.Program, activation map[string]interface{}) (bool, error) {
+	out, _, err := program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v, not a bool", out.Value())
+	}
+	return result, nil
+}
+
+func admit(violations []model.PolicyViolation, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PolicyViolationError{Violations: violations}
+	}
+	return nil
+}
+
+func policyError(policy compiledPolicy, err error) model.PolicyViolation {
+	return model.PolicyViolation{
+		Policy:  policy.Name,
+		Field:   policy.Field,
+		Message: fmt.Sprintf("policy could not be evaluated: %v", err),
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+const (
+	// deploymentPollInterval is how often the status of a running deployment is checked
+	deploymentPollInterval = 10 * time.Second
+	// previewTimeout bounds how long a job waits for a preview deployment to finish
+	previewTimeout = 30 * time.Minute
+)
+
+// previewLinePattern matches a resource row of the preview output, e.g.
+// " +- aws:ec2:Instance web replace [diff: ~ami]"
+var previewLinePattern = regexp.MustCompile(
+	`^\s*(?:\+-|-\+|\+\+|--|\+|-|~|>|<|=)?\s*[│├└─\s]*` +
+		`([A-Za-z0-9_.-]+:[A-Za-z0-9_./-]*:[A-Za-z0-9_./-]+)\s+(\S+)\s+` +
+		`(create|update|delete|replace|create-replacement|delete-replaced|import|import-replacement|discard|discard-replaced)\b` +
+		`(?:\s+\[diff: ([^\]]*)\])?`)
+
+// previewSteps returns the job steps that queue a preview deployment and wait for its result.
+// onResult receives the planned changes before the job finishes, also when the preview failed.
+func (s *WorkloadService) previewSteps(organization, project, stack string, queue func(ctx context.Context) (*model.CreateDeploymentResponse, error), onResult func(*model.PreviewResult)) []JobStep {
+	var deploymentID string
+
+	return []JobStep{
+		{
+			Name: model.JobStepPreviewQueued,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				deployment, err := queue(ctx)
+				if err != nil {
+					return err
+				}
+				deploymentID = deployment.ID
+				return nil
+			},
+		},
+		{
+			Name: model.JobStepPreviewCompleted,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				preview, err := s.waitForPreview(ctx, organization, project, stack, deploymentID)
+				if preview != nil {
+					onResult(preview)
+				}
+				return err
+			},
+		},
+	}
+}
+
+// waitForPreview waits for a preview deployment to finish and returns the changes it planned
+func (s *WorkloadService) waitForPreview(ctx context.Context, organization, project, stack, deploymentID string) (*model.PreviewResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(deploymentPollInterval)
+	defer ticker.Stop()
+
+	var status string
+	for {
+		deployment, err := s.pulumiService.GetDeployment(ctx, organization, project, stack, deploymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get preview deployment %s: %w", deploymentID, err)
+		}
+		status = deployment.Status
+		if status == "succeeded" || status == "failed" || status == "skipped" {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("preview deployment %s did not finish: %w", deploymentID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	lines, err := s.collectDeploymentLogs(ctx, organization, project, stack, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := parsePreviewChanges(lines)
+	result := &model.PreviewResult{
+		DeploymentID: deploymentID,
+		Status:       status,
+		Changes:      changes,
+		Summary:      summarizeChanges(changes),
+	}
+
+	if status != "succeeded" {
+		return result, fmt.Errorf("preview deployment %s finished with status %s", deploymentID, status)
+	}
+
+	return result, nil
+}
+
+// collectDeploymentLogs reads all pages of the logs of a deployment
+func (s *WorkloadService) collectDeploymentLogs(ctx context.Context, organization, project, stack, deploymentID string) ([]model.LogLine, error) {
+	var lines []model.LogLine
+	token := ""
+	for {
+		logs, err := s.GetDeploymentLogs(ctx, organization, project, stack, deploymentID, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read logs of deployment %s: %w", deploymentID, err)
+		}
+		lines = append(lines, logs.Lines...)
+
+		if logs.NextToken == "" || logs.NextToken == token || len(logs.Lines) == 0 {
+			return lines, nil
+		}
+		token = logs.NextToken
+	}
+}
+
+// parsePreviewChanges extracts the planned resource operations from the output of a preview.
+// A resource reported more than once keeps its last operation.
+func parsePreviewChanges(lines []model.LogLine) []model.ResourceChange {
+	changes := []model.ResourceChange{}
+	index := make(map[string]int)
+
+	for _, line := range lines {
+		match := previewLinePattern.FindStringSubmatch(line.Line)
+		if match == nil {
+			continue
+		}
+
+		change := model.ResourceChange{
+			Type:      match[1],
+			Name:      match[2],
+			Operation: match[3],
+		}
+		if match[4] != "" {
+			for _, diff := range strings.Split(match[4], ",") {
+				change.Diff = append(change.Diff, strings.TrimSpace(diff))
+			}
+		}
+
+		key := change.Type + "::" + change.Name
+		if i, ok := index[key]; ok {
+			changes[i] = change
+			continue
+		}
+		index[key] = len(changes)
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// summarizeChanges counts planned changes by operation
+func summarizeChanges(changes []model.ResourceChange) map[string]int {
+	summary := make(map[string]int)
+	for _, change := range changes {
+		summary[change.Operation]++
+	}
+	return summary
+}
@@ -1,289 +1,159 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
+	"iter"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+
 	"github.com/pulumi-idp/internal/config"
 	"github.com/pulumi-idp/internal/model"
+	pulumiapi "github.com/pulumi-idp/internal/pulumi"
 	esc "github.com/pulumi/esc-sdk/sdk/go"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	"io"
-	"net/http"
-	"os"
-	"os/exec"
-	"strings"
-	"time"
 )
 
 // PulumiService implements PulumiServiceInterface
 type PulumiService struct {
-	cfg        *config.Config
-	httpClient *http.Client
+	cfg    *config.Config
+	client *pulumiapi.Client
 }
 
 // NewPulumiService creates a new Pulumi service
 func NewPulumiService(cfg *config.Config) *PulumiService {
 	return &PulumiService{
-		cfg: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		cfg:    cfg,
+		client: pulumiapi.NewClient(cfg),
 	}
 }
 
 // SetStackTag sets a tag on a stack
-func (s *PulumiService) SetStackTag(organization, project, stack string, tag model.Tag) error {
+func (s *PulumiService) SetStackTag(ctx context.Context, organization, project, stack string, tag model.Tag) error {
 	reqBody := model.StackTagRequest{
 		Name:  tag.Key,
 		Value: tag.Value,
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error marshaling request body: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/tags", s.cfg.Pulumi.APIBaseURL, organization, project, stack)
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to set stack tag: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
+	path := fmt.Sprintf("/stacks/%s/%s/%s/tags", organization, project, stack)
+	if err := s.client.Post(ctx, path, reqBody, nil); err != nil {
+		return fmt.Errorf("failed to set stack tag: %w", err)
 	}
 
 	return nil
 }
 
 // CreateStack creates a new stack
-func (s *PulumiService) CreateStack(organization, project, stackName string) (*model.StackCreationResponse, error) {
+func (s *PulumiService) CreateStack(ctx context.Context, organization, project, stackName string) (*model.StackCreationResponse, error) {
 	reqBody := model.StackCreationRequest{
 		StackName: stackName,
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling request body: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/stacks/%s/%s", s.cfg.Pulumi.APIBaseURL, organization, project)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		data, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create stack: HTTP %d %s", resp.StatusCode, string(data))
-	}
-
 	var stackResp model.StackCreationResponse
-	if err := json.NewDecoder(resp.Body).Decode(&stackResp); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	path := fmt.Sprintf("/stacks/%s/%s", organization, project)
+	if err := s.client.Post(ctx, path, reqBody, &stackResp); err != nil {
+		return nil, fmt.Errorf("failed to create stack: %w", err)
 	}
 
 	return &stackResp, nil
 }
 
 // DeleteStack deletes a stack
-func (s *PulumiService) DeleteStack(organization, project, stack string) error {
+func (s *PulumiService) DeleteStack(ctx context.Context, organization, project, stack string) error {
 	if organization == "" || project == "" || stack == "" {
 		return fmt.Errorf("organization, project, and stack are required")
 	}
 
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s", s.cfg.Pulumi.APIBaseURL, organization, project, stack)
-
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete stack: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
+	path := fmt.Sprintf("/stacks/%s/%s/%s", organization, project, stack)
+	if err := s.client.Delete(ctx, path); err != nil {
+		return fmt.Errorf("failed to delete stack: %w", err)
 	}
 
 	return nil
 }
 
 // GetLatestStackResources retrieves the latest stack resources
-func (s *PulumiService) GetLatestStackResources(organization, project, stack string) (*model.StackResourcesResponse, error) {
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/resources/latest",
-		s.cfg.Pulumi.APIBaseURL, organization, project, stack)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/vnd.pulumi+8")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
+func (s *PulumiService) GetLatestStackResources(ctx context.Context, organization, project, stack string) (*model.StackResourcesResponse, error) {
 	var stackResources model.StackResourcesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&stackResources); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	path := fmt.Sprintf("/stacks/%s/%s/%s/resources/latest", organization, project, stack)
+	if err := s.client.Get(ctx, path, nil, &stackResources); err != nil {
+		return nil, err
 	}
 
 	return &stackResources, nil
 }
 
 // GetStack retrieves a stack
-func (s *PulumiService) GetStack(project, stack string) (*model.Stack, error) {
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s", s.cfg.Pulumi.APIBaseURL, s.cfg.Pulumi.Organization, project, stack)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+func (s *PulumiService) GetStack(ctx context.Context, project, stack string) (*model.Stack, error) {
+	var stackResponse model.Stack
+	path := fmt.Sprintf("/stacks/%s/%s/%s", s.cfg.Pulumi.Organization, project, stack)
+	if err := s.client.Get(ctx, path, nil, &stackResponse); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
+	return &stackResponse, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// ListStacks lists the stacks matching the options from all pages, starting at the
+// continuation token of the options if it is set
+func (s *PulumiService) ListStacks(ctx context.Context, options *model.ListStacksOptions) (*model.ListStacksResponse, error) {
+	stacks, err := pulumiapi.Collect(s.Stacks(ctx, options))
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var stackResponse model.Stack
-	if err := json.Unmarshal(body, &stackResponse); err != nil {
-		return nil, fmt.Errorf("error decoding response body: %w", err)
+		return nil, fmt.Errorf("failed to list stacks: %w", err)
 	}
 
-	return &stackResponse, nil
+	return &model.ListStacksResponse{Stacks: stacks}, nil
 }
 
-// ListStacks lists stacks with options
-func (s *PulumiService) ListStacks(options *model.ListStacksOptions) (*model.ListStacksResponse, error) {
-	url := fmt.Sprintf("%s/user/stacks", s.cfg.Pulumi.APIBaseURL)
-
+// Stacks iterates over the stacks matching the options, fetching the pages as they are needed
+func (s *PulumiService) Stacks(ctx context.Context, options *model.ListStacksOptions) iter.Seq2[model.Stack, error] {
+	query := url.Values{}
+	continuationToken := ""
 	if options != nil {
-		params := make([]string, 0)
-
-		if options.Organization != "" {
-			params = append(params, fmt.Sprintf("organization=%s", options.Organization))
-		}
-
-		if options.Project != "" {
-			params = append(params, fmt.Sprintf("project=%s", options.Project))
-		}
-
-		if options.TagName != "" {
-			params = append(params, fmt.Sprintf("tagName=%s", options.TagName))
-		}
-
-		if options.TagValue != "" {
-			params = append(params, fmt.Sprintf("tagValue=%s", options.TagValue))
-		}
+		setQuery(query, "organization", options.Organization)
+		setQuery(query, "project", options.Project)
+		setQuery(query, "tagName", options.TagName)
+		setQuery(query, "tagValue", options.TagValue)
+		continuationToken = options.ContinuationToken
+	}
 
-		if options.ContinuationToken != "" {
-			params = append(params, fmt.Sprintf("continuationToken=%s", options.ContinuationToken))
+	return pulumiapi.Paginate(ctx, continuationToken, func(ctx context.Context, token string) ([]model.Stack, string, error) {
+		page := url.Values{}
+		for key, values := range query {
+			page[key] = values
 		}
+		setQuery(page, "continuationToken", token)
 
-		if len(params) > 0 {
-			url += "?" + strings.Join(params, "&")
+		var listResp model.ListStacksResponse
+		if err := s.client.Get(ctx, "/user/stacks", page, &listResp); err != nil {
+			return nil, "", err
 		}
-	}
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	// Add the necessary headers
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	// Execute the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
+		return listResp.Stacks, listResp.ContinuationToken, nil
+	})
+}
 
-	// Check for successful response
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list stacks: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
-	}
+// escAuthContext returns ctx with the access token the ESC SDK authenticates with. Unlike
+// esc.NewAuthContext, the SDK calls are cancelled together with ctx.
+func escAuthContext(ctx context.Context, accessToken string) context.Context {
+	return context.WithValue(ctx, esc.ContextAPIKeys, map[string]esc.APIKey{
+		"Authorization": {Key: accessToken, Prefix: "token"},
+	})
+}
 
-	// Parse the response
-	var listResp model.ListStacksResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+// setQuery sets a query parameter unless its value is empty
+func setQuery(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
 	}
-
-	return &listResp, nil
 }
 
 // CreateStackSettings creates stack settings
-func (s *PulumiService) CreateStackSettings(organization, project, stack, cloneUrl string) error {
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/deployments/settings", s.cfg.Pulumi.APIBaseURL, organization, project, stack)
-
+func (s *PulumiService) CreateStackSettings(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) error {
 	deploymentRequest := model.CreateDeploymentRequest{
 		SourceContext: &model.SourceContext{
-			Git: &model.GitSource{
-				RepoURL: cloneUrl,
-				RepoDir: &project,
-				Branch:  pulumi.StringRef("refs/heads/main"),
-			},
+			Git: deploymentGitSource(location),
 		},
 		OperationContext: &model.OperationContext{
 			PreRunCommands: &[]string{
@@ -293,183 +163,192 @@ func (s *PulumiService) CreateStackSettings(organization, project, stack, cloneU
 		},
 	}
 
-	requestBody, err := json.Marshal(deploymentRequest)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
+	path := fmt.Sprintf("/stacks/%s/%s/%s/deployments/settings", organization, project, stack)
+	return s.client.Post(ctx, path, deploymentRequest, nil)
+}
 
-	// Send the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+// deploymentGitSource returns the git source a blueprint location is deployed from.
+// A pinned blueprint version is deployed from its commit, otherwise the ref or the main branch is tracked.
+func deploymentGitSource(location model.BlueprintLocation) *model.GitSource {
+	gitSource := &model.GitSource{
+		RepoURL: location.RepoURL,
+		RepoDir: &location.RepoDir,
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	switch {
+	case location.Commit != "":
+		gitSource.Commit = pulumi.StringRef(location.Commit)
+	case location.Ref != "":
+		gitSource.Branch = pulumi.StringRef(location.Ref)
+	default:
+		gitSource.Branch = pulumi.StringRef("refs/heads/main")
 	}
 
-	// Parse the successful response
-	var deploymentResponse model.CreateDeploymentResponse
-	if err := json.Unmarshal(body, &deploymentResponse); err != nil {
-		return fmt.Errorf("error unmarshaling response: %w", err)
-	}
-	return nil
+	return gitSource
 }
 
 // DeleteDeployment deletes a deployment
-func (s *PulumiService) DeleteDeployment(organization, project, stack string) error {
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/deployments", s.cfg.Pulumi.APIBaseURL, organization, project, stack)
-
-	deploymentRequest := model.CreateDeploymentRequest{
+func (s *PulumiService) DeleteDeployment(ctx context.Context, organization, project, stack string) (*model.CreateDeploymentResponse, error) {
+	return s.queueDeployment(ctx, organization, project, stack, model.CreateDeploymentRequest{
 		InheritSettings: pulumi.BoolRef(true),
 		Operation:       "destroy",
-	}
+	})
+}
 
-	requestBody, err := json.Marshal(deploymentRequest)
+// CreateDeployment creates a deployment
+func (s *PulumiService) CreateDeployment(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) (*model.CreateDeploymentResponse, error) {
+	err := s.CreateStackSettings(ctx, organization, project, stack, location)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
+	return s.queueDeployment(ctx, s.cfg.Pulumi.Organization, project, stack, model.CreateDeploymentRequest{
+		InheritSettings: pulumi.BoolRef(true),
+		Operation:       "update",
+	})
+}
 
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
+// CreatePreviewDeployment queues a preview of the blueprint at the given location
+// without changing the deployment settings of the stack
+func (s *PulumiService) CreatePreviewDeployment(ctx context.Context, organization, project, stack string, location model.BlueprintLocation) (*model.CreateDeploymentResponse, error) {
+	return s.queueDeployment(ctx, organization, project, stack, model.CreateDeploymentRequest{
+		SourceContext: &model.SourceContext{
+			Git: deploymentGitSource(location),
+		},
+		InheritSettings: pulumi.BoolRef(true),
+		Operation:       "preview",
+	})
+}
 
-	// Send the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+// queueDeployment submits a deployment request for a stack
+func (s *PulumiService) queueDeployment(ctx context.Context, organization, project, stack string, deploymentRequest model.CreateDeploymentRequest) (*model.CreateDeploymentResponse, error) {
+	var deploymentResponse model.CreateDeploymentResponse
+	path := fmt.Sprintf("/stacks/%s/%s/%s/deployments", organization, project, stack)
+	if err := s.client.Post(ctx, path, deploymentRequest, &deploymentResponse); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+	return &deploymentResponse, nil
+}
+
+// GetDeployment retrieves the status of a single deployment
+func (s *PulumiService) GetDeployment(ctx context.Context, organization, project, stack, deploymentID string) (*model.Deployment, error) {
+	var deployment model.Deployment
+	path := fmt.Sprintf("/stacks/%s/%s/%s/deployments/%s", organization, project, stack, deploymentID)
+	if err := s.client.Get(ctx, path, nil, &deployment); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	return &deployment, nil
+}
+
+// GetDeploymentLogs retrieves a page of the logs of a deployment
+func (s *PulumiService) GetDeploymentLogs(ctx context.Context, organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error) {
+	query := url.Values{}
+	setQuery(query, "continuationToken", continuationToken)
+
+	var logResponse model.LogResponse
+	path := fmt.Sprintf("/stacks/%s/%s/%s/deployments/%s/logs", organization, project, stack, deploymentID)
+	if err := s.client.Get(ctx, path, query, &logResponse); err != nil {
+		return nil, fmt.Errorf("failed to fetch logs: %w", err)
 	}
 
-	return nil
+	return &logResponse, nil
 }
 
-// CreateDeployment creates a deployment
-func (s *PulumiService) CreateDeployment(organization, project, stack, cloneUrl string) (*model.CreateDeploymentResponse, error) {
-	err := s.CreateStackSettings(organization, project, stack, cloneUrl)
-	if err != nil {
-		return nil, err
+// CreateEnvironment creates the ESC environment backing a workload stack
+func (s *PulumiService) CreateEnvironment(ctx context.Context, organization, project, environment string) error {
+	escClient := esc.NewClient(esc.NewConfiguration())
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
+
+	if err := escClient.CreateEnvironment(authCtx, organization, project, environment); err != nil {
+		return fmt.Errorf("error creating environment: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/deployments", s.cfg.Pulumi.APIBaseURL, s.cfg.Pulumi.Organization, project, stack)
+	return nil
+}
 
-	deploymentRequest := model.CreateDeploymentRequest{
-		InheritSettings: pulumi.BoolRef(true),
-		Operation:       "update",
+// UpdateEnvironment writes the stage import and the Pulumi config into a workload's ESC environment.
+// Without a stage, the environment imports nothing.
+func (s *PulumiService) UpdateEnvironment(ctx context.Context, organization, project, environment, stage string, pulumiConfig []map[string]interface{}) error {
+	escClient := esc.NewClient(esc.NewConfiguration())
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
+
+	updatePayload := &esc.EnvironmentDefinition{
+		Values: &esc.EnvironmentDefinitionValues{
+			PulumiConfig: map[string]interface{}{},
+		},
 	}
-	requestBody, err := json.Marshal(deploymentRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	if stage != "" {
+		updatePayload.Imports = []string{stage}
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+	for _, config := range pulumiConfig {
+		for key, value := range config {
+			updatePayload.Values.PulumiConfig[key] = value
+		}
 	}
 
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	// Send the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+	if _, err := escClient.UpdateEnvironment(authCtx, organization, project, environment, updatePayload); err != nil {
+		return fmt.Errorf("error updating environment: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return nil
+}
+
+// GetEnvironmentConfig returns the Pulumi config stored in a workload's ESC environment definition.
+// The definition is read without opening the environment, so secret values stay encrypted.
+func (s *PulumiService) GetEnvironmentConfig(ctx context.Context, organization, project, environment string) (map[string]interface{}, error) {
+	escClient := esc.NewClient(esc.NewConfiguration())
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
+
+	definition, _, err := escClient.GetEnvironment(authCtx, organization, project, environment)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("error reading environment: %w", err)
 	}
 
-	// Parse the successful response
-	var deploymentResponse model.CreateDeploymentResponse
-	if err := json.Unmarshal(body, &deploymentResponse); err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	if definition.Values == nil || definition.Values.PulumiConfig == nil {
+		return map[string]interface{}{}, nil
 	}
-
-	return &deploymentResponse, nil
+	return definition.Values.PulumiConfig, nil
 }
 
-// RunPulumiUp runs a Pulumi update
-func (s *PulumiService) RunPulumiUp(pulumiProjectName, bluePrintName, cloneUrl string, pulumiConfig []map[string]interface{}, stage string) {
-	go func() {
-		configuration := esc.NewConfiguration()
-		escClient := esc.NewClient(configuration)
-		authCtx := esc.NewAuthContext(s.cfg.Pulumi.APIToken)
+// OpenEnvironmentConfig opens an ESC environment and returns its Pulumi config with secret values decrypted
+func (s *PulumiService) OpenEnvironmentConfig(ctx context.Context, organization, project, environment string) (map[string]interface{}, error) {
+	escClient := esc.NewClient(esc.NewConfiguration())
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
 
-		err := escClient.CreateEnvironment(authCtx, s.cfg.Pulumi.Organization, bluePrintName, pulumiProjectName)
-		if err != nil {
-			_ = fmt.Errorf("error creating environment: %w", err)
-		}
+	_, values, err := escClient.OpenAndReadEnvironment(authCtx, organization, project, environment)
+	if err != nil {
+		return nil, fmt.Errorf("error opening environment: %w", err)
+	}
 
-		updatePayload := &esc.EnvironmentDefinition{
-			Imports: []string{
-				stage,
-			},
-			Values: &esc.EnvironmentDefinitionValues{
-				PulumiConfig: map[string]interface{}{},
-			},
-		}
+	pulumiConfig, ok := values["pulumiConfig"].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}, nil
+	}
+	return pulumiConfig, nil
+}
 
-		for _, config := range pulumiConfig {
-			for key, value := range config {
-				updatePayload.Values.PulumiConfig[key] = value
-			}
-		}
+// DeleteEnvironment deletes the ESC environment backing a workload stack
+func (s *PulumiService) DeleteEnvironment(ctx context.Context, organization, project, environment string) error {
+	escClient := esc.NewClient(esc.NewConfiguration())
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
 
-		_, err = escClient.UpdateEnvironment(authCtx, s.cfg.Pulumi.Organization, bluePrintName, pulumiProjectName, updatePayload)
-		if err != nil {
-			_ = fmt.Errorf("error updating environment: %w", err)
-		}
+	if err := escClient.DeleteEnvironment(authCtx, organization, project, environment); err != nil {
+		return fmt.Errorf("error deleting environment: %w", err)
+	}
 
-		_, err = s.CreateDeployment(s.cfg.Pulumi.Organization, bluePrintName, pulumiProjectName, cloneUrl)
-		if err != nil {
-			_ = fmt.Errorf("error creating deployment: %w", err)
-		}
-	}()
-	fmt.Println("Pulumi deployment started in background")
+	return nil
 }
 
 // RunPulumiNew runs a Pulumi new command
-func (s *PulumiService) RunPulumiNew(tempDir, template, projectName, projectDesc string) error {
+func (s *PulumiService) RunPulumiNew(ctx context.Context, tempDir, template, projectName, projectDesc string) error {
 	if projectName == "" {
 		projectName = "pulumi-project"
 	}
 
-	cmd := exec.Command("pulumi", "new", template, "--dir", tempDir, "--name", projectName, "--description", projectDesc, "--yes", "--force", "-g")
+	cmd := exec.CommandContext(ctx, "pulumi", "new", template, "--dir", tempDir, "--name", projectName, "--description", projectDesc, "--yes", "--force", "-g")
 	cmd.Env = os.Environ()
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -477,133 +356,83 @@ func (s *PulumiService) RunPulumiNew(tempDir, template, projectName, projectDesc
 	return cmd.Run()
 }
 
-// GetStackUpdates retrieves stack updates
-func (s *PulumiService) GetStackUpdates(params *model.ListStackUpdatesParams, project, stack string) (*model.StackDeploymentsResponse, error) {
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/deployments", s.cfg.Pulumi.APIBaseURL, s.cfg.Pulumi.Organization, project, stack)
-
-	queryParams := make([]string, 0)
-
-	queryParams = append(queryParams, "pageSize=1")
-
+// GetStackUpdates retrieves a page of the stack deployments, newest first. The endpoint pages by
+// number, without the page size Pulumi picks its default.
+func (s *PulumiService) GetStackUpdates(ctx context.Context, params *model.ListStackUpdatesParams, project, stack string) (*model.StackDeploymentsResponse, error) {
+	query := url.Values{}
 	if params.Page > 0 {
-		queryParams = append(queryParams, fmt.Sprintf("page=%d", params.Page))
+		query.Set("page", strconv.Itoa(params.Page))
 	}
-
-	if len(queryParams) > 0 {
-		url = fmt.Sprintf("%s?%s", url, strings.Join(queryParams, "&"))
-	}
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+	if params.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(params.PageSize))
 	}
-
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list stack deployments: HTTP %d, response: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+	if params.OutputType != "" {
+		query.Set("output-type", params.OutputType)
 	}
 
 	var deploymentsResponse model.StackDeploymentsResponse
-	if err := json.Unmarshal(bodyBytes, &deploymentsResponse); err != nil {
-		return nil, fmt.Errorf("error decoding response body: %w", err)
+	path := fmt.Sprintf("/stacks/%s/%s/%s/deployments", s.cfg.Pulumi.Organization, project, stack)
+	if err := s.client.Get(ctx, path, query, &deploymentsResponse); err != nil {
+		return nil, fmt.Errorf("failed to list stack deployments: %w", err)
 	}
 
 	return &deploymentsResponse, nil
 }
 
-func (s *PulumiService) GetTeams(organization, accessToken string) (*model.TeamsResponse, error) {
-
-	fmt.Printf("--------Getting teams for organization %s\n", organization)
-	url := fmt.Sprintf("%s/orgs/%s/teams", s.cfg.Pulumi.APIBaseURL, s.cfg.Pulumi.Organization)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+// GetTeams lists the teams of the organization visible to the access token
+func (s *PulumiService) GetTeams(ctx context.Context, organization, accessToken string) (*model.TeamsResponse, error) {
+	var teamsResponse model.TeamsResponse
+	path := fmt.Sprintf("/orgs/%s/teams", organization)
+	if err := s.client.WithToken(accessToken).Get(ctx, path, nil, &teamsResponse); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("Accept", "application/vnd.pulumi+8")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", accessToken))
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
+	return &teamsResponse, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+// GetTeam returns a team of the organization and the stacks it has access to
+func (s *PulumiService) GetTeam(ctx context.Context, organization, team string) (*model.TeamDetails, error) {
+	var details model.TeamDetails
+	path := fmt.Sprintf("/orgs/%s/teams/%s", organization, team)
+	if err := s.client.Get(ctx, path, nil, &details); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+	return &details, nil
+}
 
-	var teamsResponse model.TeamsResponse
-	if err := json.Unmarshal(body, &teamsResponse); err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+// GetUser returns the Pulumi user an access token belongs to
+func (s *PulumiService) GetUser(ctx context.Context, accessToken string) (*model.PulumiUser, error) {
+	var user model.PulumiUser
+	if err := s.client.WithToken(accessToken).Get(ctx, "/user", nil, &user); err != nil {
+		return nil, err
 	}
 
-	return &teamsResponse, nil
+	return &user, nil
 }
 
-func (s *PulumiService) GrantStackAccessToTeam(organization, team, projectName, stackName string, permission int) error {
-
+func (s *PulumiService) GrantStackAccessToTeam(ctx context.Context, organization, team, projectName, stackName string, permission int) error {
 	payload := model.RequestBody{
-		AddStackPermission: model.StackPermission{
+		AddStackPermission: &model.StackPermission{
 			ProjectName: projectName,
 			StackName:   stackName,
 			Permission:  permission,
 		},
 	}
 
-	// Marshal the payload to JSON
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request payload: %w", err)
-	}
-
-	// Construct the URL
-	url := fmt.Sprintf("%s/orgs/%s/teams/%s", s.cfg.Pulumi.APIBaseURL, organization, team)
-
-	// Create the request
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Accept", "application/vnd.pulumi+8")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	// Send the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	path := fmt.Sprintf("/orgs/%s/teams/%s", organization, team)
+	return s.client.Patch(ctx, path, payload, nil)
+}
 
-	// Check response status
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// RevokeStackAccessFromTeam removes the permission of a team on a stack
+func (s *PulumiService) RevokeStackAccessFromTeam(ctx context.Context, organization, team, projectName, stackName string) error {
+	payload := model.RequestBody{
+		RemoveStackPermission: &model.StackPermission{
+			ProjectName: projectName,
+			StackName:   stackName,
+		},
 	}
 
-	return nil
+	path := fmt.Sprintf("/orgs/%s/teams/%s", organization, team)
+	return s.client.Patch(ctx, path, payload, nil)
 }
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+)
+
+// newTestPulumiService serves the Pulumi API with a handler that records the requests
+func newTestPulumiService(t *testing.T, requests *[]*url.URL) *PulumiService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Pulumi.APIBaseURL = server.URL
+	cfg.Pulumi.Organization = "acme"
+	return NewPulumiService(cfg)
+}
+
+func TestGetStackUpdatesHonoursPageSize(t *testing.T) {
+	var requests []*url.URL
+	s := newTestPulumiService(t, &requests)
+
+	params := &model.ListStackUpdatesParams{Page: 2, PageSize: 25, OutputType: "cli"}
+	if _, err := s.GetStackUpdates(context.Background(), params, "web", "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := requests[0].Query()
+	if requests[0].Path != "/stacks/acme/web/dev/deployments" || query.Get("page") != "2" ||
+		query.Get("pageSize") != "25" || query.Get("output-type") != "cli" {
+		t.Fatalf("unexpected request %s", requests[0])
+	}
+}
+
+func TestGetTeamsUsesOrganization(t *testing.T) {
+	var requests []*url.URL
+	s := newTestPulumiService(t, &requests)
+
+	if _, err := s.GetTeams(context.Background(), "other", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if requests[0].Path != "/orgs/other/teams" {
+		t.Fatalf("unexpected request %s", requests[0])
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+// ErrForbidden is returned when the caller's role or team memberships do not allow an action
+var ErrForbidden = errors.New("forbidden")
+
+// Workload actions checked by the RBAC layer
+const (
+	// ActionView covers workload details, deployment logs and provisioning jobs
+	ActionView   = "view"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// roleRank orders the roles by privilege
+var roleRank = map[string]int{
+	model.RoleViewer:   1,
+	model.RoleOperator: 2,
+	model.RoleAdmin:    3,
+}
+
+// actionRoles is the minimum role needed for a workload action on a workload owned by one of the caller's teams
+var actionRoles = map[string]string{
+	ActionView:   model.RoleViewer,
+	ActionUpdate: model.RoleOperator,
+	ActionDelete: model.RoleOperator,
+}
+
+// RBACService authorizes workload operations. A user's role is the highest role granted to
+// them or one of their teams. Except for admins, users can only act on workloads owned by one
+// of their teams, the team that was granted access to the stack when it was created.
+type RBACService struct {
+	cfg       *config.Config
+	workloads *repository.WorkloadRepository
+	// provisioners maps a blueprint to the teams allowed to provision it
+	provisioners map[string][]string
+}
+
+// NewRBACService creates a new RBACService instance
+func NewRBACService(cfg *config.Config, workloads *repository.WorkloadRepository) *RBACService {
+	provisioners := make(map[string][]string)
+	for _, entry := range cfg.RBAC.BlueprintProvisioners {
+		blueprint, team, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || blueprint == "" || team == "" {
+			continue
+		}
+		provisioners[blueprint] = append(provisioners[blueprint], team)
+	}
+
+	warnUnqualifiedUsers("RBAC_ADMIN_TEAMS", cfg.RBAC.AdminTeams)
+	warnUnqualifiedUsers("RBAC_OPERATOR_TEAMS", cfg.RBAC.OperatorTeams)
+	warnUnqualifiedUsers("RBAC_VIEWER_TEAMS", cfg.RBAC.ViewerTeams)
+
+	return &RBACService{
+		cfg:          cfg,
+		workloads:    workloads,
+		provisioners: provisioners,
+	}
+}
+
+// Role returns the role of a user
+func (s *RBACService) Role(user *model.User) string {
+	if !s.cfg.RBAC.Enabled {
+		return model.RoleAdmin
+	}
+
+	switch {
+	case matchesAny(user, s.cfg.RBAC.AdminTeams):
+		return model.RoleAdmin
+	case matchesAny(user, s.cfg.RBAC.OperatorTeams):
+		return model.RoleOperator
+	case matchesAny(user, s.cfg.RBAC.ViewerTeams):
+		return model.RoleViewer
+	}
+	return s.cfg.RBAC.DefaultRole
+}
+
+// AuthorizeWorkload checks that the caller may perform an action on a workload.
+// Workloads without an owning team can only be accessed by admins.
+func (s *RBACService) AuthorizeWorkload(ctx context.Context, action, organization, project, stack string) error {
+	user, role, err := s.caller(ctx)
+	if err != nil || role == model.RoleAdmin {
+		return err
+	}
+
+	if roleRank[role] < roleRank[actionRoles[action]] {
+		return fmt.Errorf("%w: the %s role cannot %s workloads", ErrForbidden, role, action)
+	}
+
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: workload %s has no owning team", ErrForbidden, stack)
+	} else if err != nil {
+		return err
+	}
+
+	if record.Team == "" || !isMember(user, record.Team) {
+		return fmt.Errorf("%w: %s is not a member of the team owning workload %s", ErrForbidden, user.Login, stack)
+	}
+	return nil
+}
+
+// AuthorizeProvision checks that the caller may provision a blueprint for a team.
+// The caller has to be a member of that team and, if the blueprint is restricted,
+// of one of the teams allowed to provision it.
+func (s *RBACService) AuthorizeProvision(ctx context.Context, blueprint, team string) error {
+	user, role, err := s.caller(ctx)
+	if err != nil || role == model.RoleAdmin {
+		return err
+	}
+
+	if roleRank[role] < roleRank[model.RoleOperator] {
+		return fmt.Errorf("%w: the %s role cannot provision workloads", ErrForbidden, role)
+	}
+
+	if team == "" || !isMember(user, team) {
+		return fmt.Errorf("%w: %s is not a member of team %q", ErrForbidden, user.Login, team)
+	}
+
+	if allowed, restricted := s.provisioners[blueprint]; restricted && !matchesAny(user, allowed) {
+		return fmt.Errorf("%w: %s may not provision blueprint %s", ErrForbidden, user.Login, blueprint)
+	}
+	return nil
+}
+
+// AuthorizeTransfer checks that the caller may hand a workload over to another team, which
+// takes the same permissions as provisioning the workload's blueprint for that team
+func (s *RBACService) AuthorizeTransfer(ctx context.Context, organization, project, stack, team string) error {
+	if team == "" {
+		return nil
+	}
+
+	blueprint := project
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err == nil {
+		if strings.EqualFold(record.Team, team) {
+			return nil
+		}
+		if record.BlueprintName != "" {
+			blueprint = record.BlueprintName
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	return s.AuthorizeProvision(ctx, blueprint, team)
+}
+
+// VisibleTeams returns the teams whose workloads the caller may view, or nil if the caller may
+// view all workloads
+func (s *RBACService) VisibleTeams(ctx context.Context) ([]string, error) {
+	user, role, err := s.caller(ctx)
+	if err != nil || role == model.RoleAdmin {
+		return nil, err
+	}
+
+	if roleRank[role] < roleRank[actionRoles[ActionView]] {
+		return []string{}, nil
+	}
+	return append([]string{}, user.Teams...), nil
+}
+
+// AuthorizeRole checks that the caller has at least the given role
+func (s *RBACService) AuthorizeRole(ctx context.Context, required string) error {
+	_, role, err := s.caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	if roleRank[role] < roleRank[required] {
+		return fmt.Errorf("%w: the %s role is required", ErrForbidden, required)
+	}
+	return nil
+}
+
+// caller returns the user of a request and their role
+func (s *RBACService) caller(ctx context.Context) (*model.User, string, error) {
+	if !s.cfg.RBAC.Enabled {
+		return nil, model.RoleAdmin, nil
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		return nil, "", fmt.Errorf("%w: request is not authenticated", ErrForbidden)
+	}
+	return user, s.Role(user), nil
+}
+
+// matchesAny reports whether a user is named by one of the entries, either as
+// user:<provider>:<login> or through a team
+func matchesAny(user *model.User, entries []string) bool {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if identity, ok := strings.CutPrefix(entry, "user:"); ok {
+			if strings.EqualFold(identity, user.Identity()) {
+				return true
+			}
+			continue
+		}
+		if isMember(user, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUnqualifiedUsers logs the user entries of a setting that lack the provider. They match
+// nobody, since a bare login could be claimed through any provider.
+func warnUnqualifiedUsers(setting string, entries []string) {
+	for _, entry := range entries {
+		identity, ok := strings.CutPrefix(strings.TrimSpace(entry), "user:")
+		if ok && !strings.Contains(identity, ":") {
+			log.Printf("Ignoring %s entry %q, user entries need the provider, like user:github:%s", setting, entry, identity)
+		}
+	}
+}
+
+// isMember reports whether a user belongs to a team. Team names are compared case-insensitively,
+// since GitHub team slugs and Pulumi team names differ in case.
+func isMember(user *model.User, team string) bool {
+	for _, userTeam := range user.Teams {
+		if strings.EqualFold(userTeam, team) {
+			return true
+		}
+	}
+	return false
+}
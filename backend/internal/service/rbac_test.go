@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+func TestMatchesAnyComparesIdentities(t *testing.T) {
+	entries := []string{"user:github:alice", "platform"}
+
+	tests := []struct {
+		name string
+		user *model.User
+		want bool
+	}{
+		{"github user", &model.User{Login: "alice", Provider: model.AuthProviderGitHub}, true},
+		{"case differs", &model.User{Login: "Alice", Provider: model.AuthProviderGitHub}, true},
+		{"same login through OIDC", &model.User{Login: "alice", Provider: model.AuthProviderOIDC}, false},
+		{"same login through Pulumi", &model.User{Login: "alice", Provider: model.AuthProviderPulumi}, false},
+		{"team member", &model.User{Login: "bob", Provider: model.AuthProviderOIDC, Teams: []string{"Platform"}}, true},
+		{"other user", &model.User{Login: "bob", Provider: model.AuthProviderGitHub, Teams: []string{"developers"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := matchesAny(test.user, entries); got != test.want {
+				t.Fatalf("matchesAny = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchesAnyIgnoresUnqualifiedUsers(t *testing.T) {
+	user := &model.User{Login: "alice", Provider: model.AuthProviderOIDC}
+	if matchesAny(user, []string{"user:alice"}) {
+		t.Fatal("a user entry without provider must not match")
+	}
+}
+
+func TestRoleResolvesTeams(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RBAC.Enabled = true
+	cfg.RBAC.AdminTeams = []string{"platform", "user:github:root"}
+	cfg.RBAC.OperatorTeams = []string{"developers"}
+	cfg.RBAC.DefaultRole = model.RoleViewer
+	rbac := NewRBACService(cfg, nil)
+
+	tests := []struct {
+		name string
+		user *model.User
+		want string
+	}{
+		{"admin team", &model.User{Login: "a", Provider: model.AuthProviderGitHub, Teams: []string{"developers", "platform"}}, model.RoleAdmin},
+		{"admin user", &model.User{Login: "root", Provider: model.AuthProviderGitHub}, model.RoleAdmin},
+		{"admin login through OIDC", &model.User{Login: "root", Provider: model.AuthProviderOIDC}, model.RoleViewer},
+		{"operator team", &model.User{Login: "b", Provider: model.AuthProviderGitHub, Teams: []string{"developers"}}, model.RoleOperator},
+		{"no team", &model.User{Login: "c", Provider: model.AuthProviderGitHub}, model.RoleViewer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rbac.Role(test.user); got != test.want {
+				t.Fatalf("Role = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func newTestRBACService(t *testing.T) *RBACService {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.RBAC.Enabled = true
+	cfg.RBAC.AdminTeams = []string{"platform"}
+	cfg.RBAC.OperatorTeams = []string{"payments", "checkout"}
+	cfg.RBAC.DefaultRole = model.RoleViewer
+	cfg.RBAC.BlueprintProvisioners = []string{"aws-eks:platform"}
+
+	workloads := repository.NewWorkloadRepository(newTestDB(t, &model.WorkloadRecord{}))
+	for _, record := range []model.WorkloadRecord{
+		{Organization: "acme", Blueprint: "aws-ecs", Stack: "api", Team: "payments"},
+		{Organization: "acme", Blueprint: "aws-eks", BlueprintName: "aws-eks", Stack: "cluster", Team: "payments"},
+	} {
+		if err := workloads.Save(context.Background(), &record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewRBACService(cfg, workloads)
+}
+
+func TestVisibleTeams(t *testing.T) {
+	rbac := newTestRBACService(t)
+
+	tests := []struct {
+		name string
+		user *model.User
+		want []string
+	}{
+		{"admin", &model.User{Login: "a", Provider: model.AuthProviderGitHub, Teams: []string{"platform"}}, nil},
+		{"operator", &model.User{Login: "b", Provider: model.AuthProviderGitHub, Teams: []string{"payments"}}, []string{"payments"}},
+		{"viewer without teams", &model.User{Login: "c", Provider: model.AuthProviderGitHub}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			teams, err := rbac.VisibleTeams(WithUser(context.Background(), test.user))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(teams, test.want) {
+				t.Fatalf("VisibleTeams = %#v, want %#v", teams, test.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeTransfer(t *testing.T) {
+	rbac := newTestRBACService(t)
+
+	tests := []struct {
+		name    string
+		teams   []string
+		project string
+		stack   string
+		team    string
+		allowed bool
+	}{
+		{"same team", []string{"payments"}, "aws-ecs", "api", "Payments", true},
+		{"no team", []string{"payments"}, "aws-ecs", "api", "", true},
+		{"member of the new team", []string{"payments", "checkout"}, "aws-ecs", "api", "checkout", true},
+		{"not a member of the new team", []string{"payments"}, "aws-ecs", "api", "checkout", false},
+		{"restricted blueprint", []string{"payments", "checkout"}, "aws-eks", "cluster", "checkout", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &model.User{Login: "bob", Provider: model.AuthProviderGitHub, Teams: test.teams}
+
+			err := rbac.AuthorizeTransfer(WithUser(context.Background(), user), "acme", test.project, test.stack, test.team)
+			if test.allowed && err != nil {
+				t.Fatalf("expected the transfer to be allowed, got %v", err)
+			}
+			if !test.allowed && !errors.Is(err, ErrForbidden) {
+				t.Fatalf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
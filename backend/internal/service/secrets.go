@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+// escSecret is the ESC function that stores a value encrypted at rest
+const escSecret = "fn::secret"
+
+// mergeConfig merges the advanced entries of a request in order, the way they end up in pulumiConfig
+func mergeConfig(advanced []map[string]interface{}) map[string]interface{} {
+	config := make(map[string]interface{})
+	for _, entry := range advanced {
+		for key, value := range entry {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+// secretConfig returns the pulumiConfig to write for a workload. Values of secret properties are
+// wrapped in fn::secret. Secret properties missing from config keep their value from stored, the
+// config currently in the environment, so that clients never have to send a secret back.
+func secretConfig(properties []model.ConfigProperty, config, stored map[string]interface{}) map[string]interface{} {
+	value, _ := secretObject(properties, config, stored, true)
+	return value.(map[string]interface{})
+}
+
+// redactSecrets returns a copy of config without the values of secret properties
+func redactSecrets(properties []model.ConfigProperty, config map[string]interface{}) map[string]interface{} {
+	return redactObject(properties, config)
+}
+
+// redactAdvanced removes the values of secret properties from the advanced entries of a request
+func redactAdvanced(properties []model.ConfigProperty, advanced []map[string]interface{}) []map[string]interface{} {
+	if advanced == nil {
+		return nil
+	}
+	redacted := make([]map[string]interface{}, 0, len(advanced))
+	for _, entry := range advanced {
+		redacted = append(redacted, redactObject(properties, entry))
+	}
+	return redacted
+}
+
+func secretValue(property model.ConfigProperty, value interface{}, hasValue bool, stored interface{}, hasStored bool) (interface{}, bool) {
+	switch {
+	case property.Secret && hasValue:
+		return wrapSecret(value), true
+	case property.Secret && hasStored:
+		return wrapSecret(stored), true
+	case property.Type == "object" && len(property.Properties) > 0:
+		object, ok := value.(map[string]interface{})
+		if hasValue && !ok {
+			return value, true
+		}
+		storedObject, _ := stored.(map[string]interface{})
+		return secretObject(property.Properties, object, storedObject, hasValue)
+	case property.Type == "array" && property.Items != nil && hasValue:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value, true
+		}
+		wrapped := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			item, _ = secretValue(*property.Items, item, true, nil, false)
+			wrapped = append(wrapped, item)
+		}
+		return wrapped, true
+	}
+	return value, hasValue
+}
+
+func secretObject(properties []model.ConfigProperty, object, stored map[string]interface{}, hasValue bool) (interface{}, bool) {
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		result[key] = value
+	}
+	for _, property := range objectProperties(properties) {
+		value, hasPropertyValue := object[property.Name]
+		storedValue, hasStored := stored[property.Name]
+		if value, ok := secretValue(property, value, hasPropertyValue, storedValue, hasStored); ok {
+			result[property.Name] = value
+		}
+	}
+	// An omitted object is only written when it carries stored secrets
+	if !hasValue && len(result) == 0 {
+		return nil, false
+	}
+	return result, true
+}
+
+func redactValue(property model.ConfigProperty, value interface{}) (interface{}, bool) {
+	switch {
+	case property.Secret:
+		return nil, false
+	case property.Type == "object" && len(property.Properties) > 0:
+		if object, ok := value.(map[string]interface{}); ok {
+			return redactObject(property.Properties, object), true
+		}
+	case property.Type == "array" && property.Items != nil:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value, true
+		}
+		redacted := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			item, keep := redactValue(*property.Items, item)
+			if !keep {
+				return nil, false
+			}
+			redacted = append(redacted, item)
+		}
+		return redacted, true
+	}
+	return value, true
+}
+
+func redactObject(properties []model.ConfigProperty, object map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		result[key] = value
+	}
+	for _, property := range objectProperties(properties) {
+		value, ok := result[property.Name]
+		if !ok {
+			continue
+		}
+		if value, keep := redactValue(property, value); keep {
+			result[property.Name] = value
+		} else {
+			delete(result, property.Name)
+		}
+	}
+	return result
+}
+
+// objectProperties lists the properties an object can hold, including those that only apply
+// once another property is set
+func objectProperties(properties []model.ConfigProperty) []model.ConfigProperty {
+	var all []model.ConfigProperty
+	for _, property := range properties {
+		all = append(all, property)
+		all = append(all, objectProperties(property.DependentProperties)...)
+	}
+	return all
+}
+
+// wrapSecret wraps a value in fn::secret. ESC only encrypts strings, so other values are
+// stored as their JSON encoding. Values that already are secrets are returned as is.
+func wrapSecret(value interface{}) interface{} {
+	if object, ok := value.(map[string]interface{}); ok {
+		if _, ok := object[escSecret]; ok && len(object) == 1 {
+			return value
+		}
+	}
+
+	plaintext, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			data = []byte(fmt.Sprint(value))
+		}
+		plaintext = string(data)
+	}
+	return map[string]interface{}{escSecret: plaintext}
+}
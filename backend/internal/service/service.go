@@ -7,28 +7,68 @@ import (
 
 // Service contains all services
 type Service struct {
-	PulumiService    *PulumiService
-	BlueprintService *BlueprintService
-	GitHubService    *GitHubService
-	WorkloadService  *WorkloadService
+	PulumiService       *PulumiService
+	BlueprintService    *BlueprintService
+	GitHubService       *GitHubService
+	WorkloadService     *WorkloadService
+	JobService          *JobService
+	AuthService         *AuthService
+	RBACService         *RBACService
+	AuditService        *AuditService
+	ApprovalService     *ApprovalService
+	PolicyService       *PolicyService
+	NotificationService *NotificationService
+	LeaderService       *LeaderService
+	StackStatusService  *StackStatusService
 }
 
 // NewService creates a new service instance with all services
-func NewService(_ *repository.Repository, cfg *config.Config) *Service {
+func NewService(repos *repository.Repository, cfg *config.Config) *Service {
 	pulumiService := NewPulumiService(cfg)
-	blueprintService := NewBlueprintService(cfg)
+	blueprintService := NewBlueprintService(cfg, repos.Cache)
 	githubService := NewGitHubService(cfg)
-	workloadService := NewWorkloadService(cfg)
+	workloadService := NewWorkloadService(cfg, repos.Workload)
+	jobService := NewJobService(cfg, repos.Job)
+	authService := NewAuthService(cfg)
+	rbacService := NewRBACService(cfg, repos.Workload)
+	auditService := NewAuditService(cfg, repos.Audit)
+	approvalService := NewApprovalService(cfg, repos.Approval)
+	policyService := NewPolicyService(cfg, repos.Workload)
+	notificationService := NewNotificationService(cfg)
+	leaderService := NewLeaderService(cfg, repos.Lease)
+	stackStatusService := NewStackStatusService(cfg, repos.Workload)
 
 	// Set dependencies
 	workloadService.SetPulumiService(pulumiService)
 	workloadService.SetBlueprintService(blueprintService)
 	workloadService.SetGitHubService(githubService)
+	workloadService.SetJobService(jobService)
+	workloadService.SetAuditService(auditService)
+	workloadService.SetStackStatusService(stackStatusService)
+	workloadService.SetRBACService(rbacService)
+	authService.SetPulumiService(pulumiService)
+	approvalService.SetWorkloadService(workloadService)
+	approvalService.SetBlueprintService(blueprintService)
+	approvalService.SetPulumiService(pulumiService)
+	approvalService.SetRBACService(rbacService)
+	approvalService.SetAuditService(auditService)
+	leaderService.SetWorkloadService(workloadService)
+	blueprintService.SetAuditService(auditService)
+	stackStatusService.SetPulumiService(pulumiService)
 
 	return &Service{
-		PulumiService:    pulumiService,
-		BlueprintService: blueprintService,
-		GitHubService:    githubService,
-		WorkloadService:  workloadService,
+		PulumiService:       pulumiService,
+		BlueprintService:    blueprintService,
+		GitHubService:       githubService,
+		WorkloadService:     workloadService,
+		JobService:          jobService,
+		AuthService:         authService,
+		RBACService:         rbacService,
+		AuditService:        auditService,
+		ApprovalService:     approvalService,
+		PolicyService:       policyService,
+		NotificationService: notificationService,
+		LeaderService:       leaderService,
+		StackStatusService:  stackStatusService,
 	}
 }
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	pulumiapi "github.com/pulumi-idp/internal/pulumi"
+	"github.com/pulumi-idp/internal/repository"
+	"golang.org/x/sync/errgroup"
+)
+
+// StackStatusService keeps the Pulumi Cloud status of the workload stacks in memory, so that the
+// workload list does not call the Pulumi API for every stack. A background refresher reads the
+// statuses of all workloads before they expire.
+type StackStatusService struct {
+	cfg           *config.Config
+	pulumiService *PulumiService
+	workloads     *repository.WorkloadRepository
+	logger        *log.Logger
+	mutex         sync.RWMutex
+	entries       map[string]stackStatusEntry
+}
+
+type stackStatusEntry struct {
+	status    model.StackStatus
+	fetchedAt time.Time
+}
+
+type stackStatusResult struct {
+	status *model.StackStatus
+	err    error
+}
+
+// NewStackStatusService creates a new StackStatusService instance
+func NewStackStatusService(cfg *config.Config, workloads *repository.WorkloadRepository) *StackStatusService {
+	return &StackStatusService{
+		cfg:       cfg,
+		workloads: workloads,
+		logger:    log.New(log.Writer(), "[StackStatus] ", log.LstdFlags),
+		entries:   make(map[string]stackStatusEntry),
+	}
+}
+
+func (s *StackStatusService) SetPulumiService(service *PulumiService) {
+	s.pulumiService = service
+}
+
+// Enrich sets the Pulumi status on the stacks. Expired and missing statuses are read concurrently.
+// A stack whose status cannot be read keeps its catalog state, or the last known status, and
+// carries the error instead of failing the others.
+func (s *StackStatusService) Enrich(ctx context.Context, stacks []model.Stack) {
+	now := time.Now()
+	var missing []int
+
+	s.mutex.RLock()
+	for i := range stacks {
+		entry, ok := s.entries[stackStatusKey(stacks[i])]
+		if ok {
+			applyStackStatus(&stacks[i], entry.status)
+		}
+		if !ok || now.Sub(entry.fetchedAt) > s.cfg.Status.TTL {
+			missing = append(missing, i)
+		}
+	}
+	s.mutex.RUnlock()
+
+	for i, result := range s.fetch(ctx, stacks, missing) {
+		if result.err != nil {
+			stacks[i].StatusError = result.err.Error()
+			continue
+		}
+		applyStackStatus(&stacks[i], *result.status)
+	}
+}
+
+// Invalidate drops the cached status of a stack, so that it is read again the next time it is listed
+func (s *StackStatusService) Invalidate(organization, project, stack string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, fmt.Sprintf("%s/%s/%s", organization, project, stack))
+}
+
+// Run refreshes the statuses of all workloads until the context ends
+func (s *StackStatusService) Run(ctx context.Context) {
+	if s.cfg.Status.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Status.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh reads the statuses of all workloads of the catalog and forgets those of removed workloads
+func (s *StackStatusService) refresh(ctx context.Context) {
+	records, err := s.workloads.List(ctx, model.WorkloadFilter{
+		Organization: s.cfg.Pulumi.Organization,
+	})
+	if err != nil {
+		s.logger.Printf("Failed to list workloads: %v", err)
+		return
+	}
+
+	stacks := make([]model.Stack, 0, len(records))
+	indexes := make([]int, 0, len(records))
+	listed := make(map[string]bool, len(records))
+	for i := range records {
+		stack := records[i].ToStack()
+		stacks = append(stacks, stack)
+		indexes = append(indexes, i)
+		listed[stackStatusKey(stack)] = true
+	}
+
+	failed := 0
+	for _, result := range s.fetch(ctx, stacks, indexes) {
+		if result.err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		s.logger.Printf("Failed to refresh the status of %d of %d stacks", failed, len(stacks))
+	}
+
+	s.mutex.Lock()
+	for key := range s.entries {
+		if !listed[key] {
+			delete(s.entries, key)
+		}
+	}
+	s.mutex.Unlock()
+}
+
+// fetch reads the statuses of the stacks at the indexes with a bounded number of concurrent
+// requests and caches them. It returns the statuses or errors by stack index.
+func (s *StackStatusService) fetch(ctx context.Context, stacks []model.Stack, indexes []int) map[int]stackStatusResult {
+	results := make(map[int]stackStatusResult, len(indexes))
+	if len(indexes) == 0 {
+		return results
+	}
+
+	var mutex sync.Mutex
+	var group errgroup.Group
+	group.SetLimit(max(s.cfg.Status.Concurrency, 1))
+	for _, i := range indexes {
+		group.Go(func() error {
+			status, err := s.readStatus(ctx, stacks[i])
+
+			mutex.Lock()
+			results[i] = stackStatusResult{status: status, err: err}
+			mutex.Unlock()
+			if err == nil {
+				s.mutex.Lock()
+				s.entries[stackStatusKey(stacks[i])] = stackStatusEntry{status: *status, fetchedAt: time.Now()}
+				s.mutex.Unlock()
+			}
+			// A failed stack does not stop the others
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	return results
+}
+
+// readStatus reads the latest deployment and the state of a stack from the Pulumi API. A stack
+// that does not exist yet, like one still being provisioned, has an empty status.
+func (s *StackStatusService) readStatus(ctx context.Context, stack model.Stack) (*model.StackStatus, error) {
+	current, err := s.pulumiService.GetStack(ctx, stack.ProjectName, stack.StackName)
+	if pulumiapi.IsNotFound(err) {
+		return &model.StackStatus{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	deployments, err := s.pulumiService.GetStackUpdates(ctx, &model.ListStackUpdatesParams{
+		Page:       1,
+		PageSize:   1,
+		OutputType: "cli",
+	}, stack.ProjectName, stack.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.StackStatus{
+		ResourceCount:    current.ResourceCount,
+		LastUpdate:       current.LastUpdate,
+		CurrentOperation: current.CurrentOperation,
+	}
+	if len(deployments.Deployments) > 0 {
+		status.Result = deployments.Deployments[0].Status
+		status.DeploymentID = deployments.Deployments[0].ID
+	}
+	return status, nil
+}
+
+// applyStackStatus sets a status on a catalog stack. Only active workloads show the result of
+// their latest deployment, the catalog state of the others says more.
+func applyStackStatus(stack *model.Stack, status model.StackStatus) {
+	stack.ResourceCount = status.ResourceCount
+	stack.DeploymentId = status.DeploymentID
+	stack.CurrentOperation = status.CurrentOperation
+	if status.LastUpdate > 0 {
+		stack.LastUpdate = status.LastUpdate
+	}
+	if stack.Result == model.WorkloadStatusActive && status.Result != "" {
+		stack.Result = status.Result
+	}
+}
+
+func stackStatusKey(stack model.Stack) string {
+	return fmt.Sprintf("%s/%s/%s", stack.OrgName, stack.ProjectName, stack.StackName)
+}
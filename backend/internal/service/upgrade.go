@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+// ErrMissingRequiredConfig is returned when an upgrade does not provide config the target blueprint version requires
+var ErrMissingRequiredConfig = errors.New("missing required config")
+
+// PreviewUpgrade starts a job that previews a workload on another blueprint version.
+// The preview runs against the current workload config and does not change the stack settings;
+// config keys the target version newly requires are reported in the plan.
+func (s *WorkloadService) PreviewUpgrade(ctx context.Context, organization, project, stack, version string) (*model.UpgradePlan, error) {
+	record, blueprintName, version, err := s.resolveUpgrade(ctx, organization, project, stack, version)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := s.blueprintService.GetBlueprintLocation(ctx, blueprintName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	newConfig, err := s.blueprintService.NewRequiredConfig(ctx, blueprintName, record.BlueprintVersion, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare blueprint config: %w", err)
+	}
+
+	// The preview result is attached to the job once the deployment has finished
+	var job *model.ProvisioningJob
+	steps := s.previewSteps(organization, project, stack,
+		func(ctx context.Context) (*model.CreateDeploymentResponse, error) {
+			return s.pulumiService.CreatePreviewDeployment(ctx, organization, project, stack, location)
+		},
+		func(preview *model.PreviewResult) {
+			job.Preview = preview
+		},
+	)
+
+	job, err = s.jobService.CreateJob(ctx, model.JobKindUpgradePreview, organization, project, stack, steps)
+	if err != nil {
+		return nil, err
+	}
+	// The audited change is the version move the preview evaluates
+	before := workloadSnapshot(record)
+	after := workloadSnapshot(record)
+	after["blueprintVersion"] = version
+	started := s.jobService.Start(job, steps, func(ctx context.Context, job *model.ProvisioningJob) {
+		s.auditJob(ctx, record, job, before, after)
+	})
+
+	return &model.UpgradePlan{
+		CurrentVersion:    record.BlueprintVersion,
+		TargetVersion:     version,
+		NewRequiredConfig: newConfig,
+		Job:               started,
+	}, nil
+}
+
+// ApplyUpgrade moves a workload to another blueprint version. The config of the last update is
+// carried over and extended with the config from the request, which must cover every key the
+// target version newly requires.
+func (s *WorkloadService) ApplyUpgrade(ctx context.Context, organization, project, stack string, req *model.UpgradeRequest) (*model.ProvisioningJob, error) {
+	record, blueprintName, version, err := s.resolveUpgrade(ctx, organization, project, stack, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	newConfig, err := s.blueprintService.NewRequiredConfig(ctx, blueprintName, record.BlueprintVersion, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare blueprint config: %w", err)
+	}
+
+	provided := make(map[string]bool)
+	for _, config := range req.Advanced {
+		for key := range config {
+			provided[key] = true
+		}
+	}
+	var missing []string
+	for _, key := range newConfig {
+		if !provided[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingRequiredConfig, strings.Join(missing, ", "))
+	}
+
+	advanced := append(append([]map[string]interface{}{}, record.CreationRequest.Advanced...), req.Advanced...)
+
+	return s.UpdateWorkload(ctx, organization, project, stack, &model.WorkloadRequest{
+		Name:          record.Name,
+		BlueprintName: record.BlueprintName,
+		Blueprint:     record.Blueprint,
+		Stage:         record.Stage,
+		Advanced:      advanced,
+		Version:       version,
+	})
+}
+
+// resolveUpgrade loads the workload to upgrade and resolves the target version, defaulting to the latest release
+func (s *WorkloadService) resolveUpgrade(ctx context.Context, organization, project, stack, version string) (*model.WorkloadRecord, string, string, error) {
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if record.CreationRequest.CookieCut {
+		return nil, "", "", fmt.Errorf("cookie-cut workloads cannot be pinned to a blueprint version")
+	}
+
+	blueprintName := record.BlueprintName
+	if blueprintName == "" {
+		blueprintName = project
+	}
+
+	if version == "" {
+		version, err = s.blueprintService.LatestBlueprintVersion(ctx, blueprintName)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if version == "" {
+			return nil, "", "", fmt.Errorf("blueprint %s has no released versions", blueprintName)
+		}
+	}
+
+	return record, blueprintName, version, nil
+}
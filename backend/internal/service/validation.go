@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ValidationError reports the fields of a workload request that do not match the workload or blueprint schema
+type ValidationError struct {
+	Fields []model.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Path, field.Message))
+	}
+	return "invalid workload request: " + strings.Join(messages, "; ")
+}
+
+// updateFields are the workload schema properties an update applies
+var updateFields = []string{"stage", "team", "projectId"}
+
+// missingPropertyPattern extracts the property names from a "missing properties" message
+var missingPropertyPattern = regexp.MustCompile(`'([^']+)'`)
+
+// ValidateWorkloadRequest validates a create request before anything is provisioned.
+// The request body is validated against the workload schema, the merged advanced config
+// against the schema of the blueprint version that will be deployed, and the stage
+// against the ESC environments the blueprint accepts. raw is the decoded request body.
+func (s *WorkloadService) ValidateWorkloadRequest(ctx context.Context, req *model.WorkloadRequest, raw map[string]interface{}) error {
+	workloadSchema, err := s.GetWorkloadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	version, err := s.createVersion(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	fields, err := validateAgainstSchema(workloadSchema, raw, "")
+	if err != nil {
+		return err
+	}
+
+	blueprintFields, err := s.validateBlueprintConfig(ctx, req.BlueprintName, version, req, false)
+	if err != nil {
+		return err
+	}
+	fields = append(fields, blueprintFields...)
+
+	if _, err := s.resolveExpiry(req); err != nil {
+		path := "ttl"
+		if req.ExpiresAt != nil {
+			path = "expiresAt"
+		}
+		fields = append(fields, model.FieldError{Path: path, Message: err.Error()})
+	}
+
+	return validationResult(fields)
+}
+
+// ValidateWorkloadUpdate validates the fields an update applies: stage, team and project ID
+// against the workload schema when they are set, and the advanced config against the blueprint schema.
+func (s *WorkloadService) ValidateWorkloadUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest, raw map[string]interface{}) error {
+	blueprintName, version := project, req.Version
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if record != nil {
+		if record.BlueprintName != "" {
+			blueprintName = record.BlueprintName
+		}
+		if version == "" {
+			version = record.BlueprintVersion
+		}
+	}
+
+	workloadSchema, err := s.GetWorkloadSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Only validate the workload properties the update actually applies
+	properties, _ := workloadSchema["properties"].(map[string]interface{})
+	updateSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+	instance := make(map[string]interface{})
+	for _, name := range updateFields {
+		if value, ok := raw[name]; ok && value != "" && properties[name] != nil {
+			updateSchema["properties"].(map[string]interface{})[name] = properties[name]
+			instance[name] = value
+		}
+	}
+
+	fields, err := validateAgainstSchema(updateSchema, instance, "")
+	if err != nil {
+		return err
+	}
+
+	// Secrets the update omits keep their stored value
+	blueprintFields, err := s.validateBlueprintConfig(ctx, blueprintName, version, req, true)
+	if err != nil {
+		return err
+	}
+
+	return validationResult(append(fields, blueprintFields...))
+}
+
+// validateBlueprintConfig validates the merged advanced config and the stage of a request against a blueprint version.
+// With optionalSecrets, secret properties are not required.
+func (s *WorkloadService) validateBlueprintConfig(ctx context.Context, blueprintName, version string, req *model.WorkloadRequest, optionalSecrets bool) ([]model.FieldError, error) {
+	blueprintSchema, err := s.blueprintService.GetBlueprintSchema(ctx, blueprintName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The entries are merged in order into the environment's pulumiConfig, so validate the result
+	instance, err := toJSONValue(mergeConfig(req.Advanced))
+	if err != nil {
+		return nil, err
+	}
+
+	// The stage is only part of the blueprint schema as a hint for the UI
+	configSchema := make(map[string]interface{}, len(blueprintSchema))
+	for key, value := range blueprintSchema {
+		if key != "stage" {
+			configSchema[key] = value
+		}
+	}
+
+	if optionalSecrets {
+		configSchema = withoutSecretRequirements(configSchema)
+	}
+
+	fields, err := validateAgainstSchema(configSchema, instance, "/advanced")
+	if err != nil {
+		return nil, err
+	}
+
+	if stageSchema, ok := blueprintSchema["stage"].(map[string]interface{}); ok && req.Stage != "" {
+		stageFields, err := validateAgainstSchema(stageSchema, req.Stage, "/stage")
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, stageFields...)
+	}
+
+	return fields, nil
+}
+
+// stringList returns a list of strings, given as []string or as decoded JSON
+func stringList(value interface{}) ([]string, bool) {
+	switch value := value.(type) {
+	case []string:
+		return value, true
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			item, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			list = append(list, item)
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// withoutSecretRequirements returns a copy of an object schema in which write-only properties,
+// the secrets, are no longer required, at any depth
+func withoutSecretRequirements(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		result[key] = value
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	if len(properties) > 0 {
+		rewritten := make(map[string]interface{}, len(properties))
+		for name, property := range properties {
+			if property, ok := property.(map[string]interface{}); ok {
+				rewritten[name] = withoutSecretRequirements(property)
+				continue
+			}
+			rewritten[name] = property
+		}
+		result["properties"] = rewritten
+	}
+
+	// Schemas read from the blueprint cache hold decoded JSON, []interface{} instead of []string
+	if required, ok := stringList(schema["required"]); ok {
+		kept := []string{}
+		for _, name := range required {
+			if property, ok := properties[name].(map[string]interface{}); ok && property["writeOnly"] == true {
+				continue
+			}
+			kept = append(kept, name)
+		}
+		result["required"] = kept
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		result["items"] = withoutSecretRequirements(items)
+	}
+
+	if dependents, ok := schema["dependentSchemas"].(map[string]interface{}); ok {
+		rewritten := make(map[string]interface{}, len(dependents))
+		for name, dependent := range dependents {
+			if dependent, ok := dependent.(map[string]interface{}); ok {
+				rewritten[name] = withoutSecretRequirements(dependent)
+				continue
+			}
+			rewritten[name] = dependent
+		}
+		result["dependentSchemas"] = rewritten
+	}
+
+	return result
+}
+
+// createVersion resolves the blueprint version a create request deploys
+func (s *WorkloadService) createVersion(ctx context.Context, req *model.WorkloadRequest) (string, error) {
+	// Workloads deployed from the blueprint source are pinned to the latest released version
+	// unless the request asks for a specific one
+	if req.Version != "" || req.CookieCut {
+		return req.Version, nil
+	}
+
+	latest, err := s.blueprintService.LatestBlueprintVersion(ctx, req.BlueprintName)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve blueprint version: %w", err)
+	}
+	return latest, nil
+}
+
+// validateAgainstSchema validates a JSON value against a JSON Schema and returns one error per failing field.
+// Paths are prefixed with prefix.
+func validateAgainstSchema(schema map[string]interface{}, instance interface{}, prefix string) ([]model.FieldError, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if err := compiler.AddResource("schema.json", bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	err = compiled.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if err == nil {
+		return nil, nil
+	} else if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var fields []model.FieldError
+	collectFieldErrors(validationErr, prefix, &fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+
+	return fields, nil
+}
+
+// collectFieldErrors flattens a validation error tree into its leaf errors.
+// Failed oneOf and anyOf alternatives are reported once for the field.
+func collectFieldErrors(err *jsonschema.ValidationError, prefix string, fields *[]model.FieldError) {
+	isChoice := strings.HasSuffix(err.KeywordLocation, "/oneOf") || strings.HasSuffix(err.KeywordLocation, "/anyOf")
+	if len(err.Causes) > 0 && !isChoice {
+		for _, cause := range err.Causes {
+			collectFieldErrors(cause, prefix, fields)
+		}
+		return
+	}
+
+	if isChoice {
+		*fields = append(*fields, model.FieldError{
+			Path:    prefix + err.InstanceLocation,
+			Message: "value is not one of the allowed options",
+		})
+		return
+	}
+
+	// Report missing required properties on the property itself
+	if strings.HasSuffix(err.KeywordLocation, "/required") {
+		for _, match := range missingPropertyPattern.FindAllStringSubmatch(err.Message, -1) {
+			*fields = append(*fields, model.FieldError{
+				Path:    prefix + err.InstanceLocation + "/" + match[1],
+				Message: "is required",
+			})
+		}
+		return
+	}
+
+	if strings.Contains(err.KeywordLocation, "/dependentRequired/") {
+		if names := missingPropertyPattern.FindAllStringSubmatch(err.Message, -1); len(names) == 2 {
+			*fields = append(*fields, model.FieldError{
+				Path:    prefix + err.InstanceLocation + "/" + names[0][1],
+				Message: fmt.Sprintf("is required when %s is set", names[1][1]),
+			})
+			return
+		}
+	}
+
+	*fields = append(*fields, model.FieldError{
+		Path:    prefix + err.InstanceLocation,
+		Message: err.Message,
+	})
+}
+
+// toJSONValue converts a Go value into the generic form produced by encoding/json
+func toJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var converted interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+func validationResult(fields []model.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
+}
@@ -2,38 +2,42 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gobeam/stringy"
+	"github.com/google/go-github/github"
 	esc "github.com/pulumi/esc-sdk/sdk/go"
-	"io"
 	"io/ioutil"
-	"net/http"
+	"log"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/labstack/echo/v4"
 	"github.com/pulumi-idp/internal/config"
 	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
 )
 
 // BlueprintService implements BlueprintServiceInterface
 type WorkloadService struct {
 	cfg              *config.Config
-	httpClient       *http.Client
 	pulumiService    *PulumiService
 	blueprintService *BlueprintService
 	githubService    *GitHubService
+	jobService       *JobService
+	auditService     *AuditService
+	statusService    *StackStatusService
+	rbacService      *RBACService
+	workloads        *repository.WorkloadRepository
+	logger           *log.Logger
 }
 
 // NewBlueprintService creates a new BlueprintService instance
-func NewWorkloadService(cfg *config.Config) *WorkloadService {
+func NewWorkloadService(cfg *config.Config, workloads *repository.WorkloadRepository) *WorkloadService {
 	return &WorkloadService{
-		cfg: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		cfg:       cfg,
+		workloads: workloads,
+		logger:    log.New(log.Writer(), "[Workload] ", log.LstdFlags),
 	}
 }
 
@@ -49,6 +53,30 @@ func (s *WorkloadService) SetGitHubService(service *GitHubService) {
 	s.githubService = service
 }
 
+func (s *WorkloadService) SetJobService(service *JobService) {
+	s.jobService = service
+}
+
+func (s *WorkloadService) SetStackStatusService(service *StackStatusService) {
+	s.statusService = service
+}
+
+func (s *WorkloadService) SetAuditService(service *AuditService) {
+	s.auditService = service
+}
+
+func (s *WorkloadService) SetRBACService(service *RBACService) {
+	s.rbacService = service
+}
+
+// visibleTeams returns the teams whose workloads the caller may list, nil for all workloads
+func (s *WorkloadService) visibleTeams(ctx context.Context) ([]string, error) {
+	if s.rbacService == nil {
+		return nil, nil
+	}
+	return s.rbacService.VisibleTeams(ctx)
+}
+
 // isRefType checks if a property type is a reference
 func isRefType(propertyType string) bool {
 	return strings.HasPrefix(propertyType, "$ref/")
@@ -79,7 +107,7 @@ func teamsToOneOfSchema(teams []model.Team) []map[string]interface{} {
 }
 
 // convertWorkloadToJSONSchema converts workload property overrides to a JSON schema
-func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.WorkloadPropertyOverride) map[string]interface{} {
+func (s *WorkloadService) convertWorkloadToJSONSchema(ctx context.Context, overrides []model.WorkloadPropertyOverride) map[string]interface{} {
 	properties := make(map[string]interface{})
 	var required []string
 
@@ -91,7 +119,7 @@ func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.Workload
 		if isRefType(override.Type) {
 			refEntity := getRefEntity(override.Type)
 			if refEntity == "teams" {
-				teamsResp, err := s.pulumiService.GetTeams(s.cfg.Pulumi.Organization, s.cfg.Pulumi.APIToken)
+				teamsResp, err := s.pulumiService.GetTeams(ctx, s.cfg.Pulumi.Organization, s.cfg.Pulumi.APIToken)
 				if err == nil && teamsResp != nil {
 					oneOfOptions := teamsToOneOfSchema(teamsResp.Teams)
 					properties[override.Name] = map[string]interface{}{
@@ -105,7 +133,7 @@ func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.Workload
 					}
 					continue
 				} else {
-					fmt.Printf("Error fetching teams: %v\n", err)
+					s.logger.Printf("Error fetching teams: %v", err)
 				}
 			} else if refEntity == "projects" {
 				projects := []string{"123", "456", "789"} // This should be fetched from a real source
@@ -128,7 +156,7 @@ func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.Workload
 				}
 				continue
 			} else if refEntity == "esc" {
-				environmentsResp, err := s.blueprintService.GetEnvironmentsForUserAndTag("", "")
+				environmentsResp, err := s.blueprintService.GetEnvironmentsForUserAndTag(ctx, "", "")
 				if err == nil && environmentsResp != nil {
 					oneOfOptions := make([]map[string]interface{}, 0, len(environmentsResp.Environments))
 					for _, env := range environmentsResp.Environments {
@@ -149,10 +177,10 @@ func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.Workload
 					}
 					continue
 				} else {
-					fmt.Printf("Error fetching environments: %v\n", err)
+					s.logger.Printf("Error fetching environments: %v", err)
 				}
 			} else {
-				fmt.Printf("Reference type %s not yet implemented\n", refEntity)
+				s.logger.Printf("Reference type %s not yet implemented", refEntity)
 			}
 		}
 
@@ -174,10 +202,10 @@ func (s *WorkloadService) convertWorkloadToJSONSchema(overrides []model.Workload
 }
 
 // GetWorkloadSchema retrieves the JSON schema for workloads
-func (s *WorkloadService) GetWorkloadSchema(c echo.Context) (map[string]interface{}, error) {
+func (s *WorkloadService) GetWorkloadSchema(ctx context.Context) (map[string]interface{}, error) {
 	configuration := esc.NewConfiguration()
 	escClient := esc.NewClient(configuration)
-	authCtx := esc.NewAuthContext(s.cfg.Pulumi.APIToken)
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
 
 	workloadDefinitionLocation := s.cfg.Pulumi.WorkloadDefinitionLocation
 
@@ -186,13 +214,11 @@ func (s *WorkloadService) GetWorkloadSchema(c echo.Context) (map[string]interfac
 
 	_, values, err := escClient.OpenAndReadEnvironment(authCtx, s.cfg.Pulumi.Organization, projName, envName)
 	if err != nil {
-		c.Logger().Debugf("Failed to open environment: %v", err)
 		return nil, fmt.Errorf("failed to open environment: %w", err)
 	}
 
 	escBlueprint, ok := values["workload"]
 	if !ok {
-		c.Logger().Debugf("Secret 'workload' not found in environment %s/%s", projName, envName)
 		return nil, fmt.Errorf("secret 'workload' not found in environment %s/%s", projName, envName)
 	}
 
@@ -226,60 +252,42 @@ func (s *WorkloadService) GetWorkloadSchema(c echo.Context) (map[string]interfac
 		}
 	}
 
-	schema := s.convertWorkloadToJSONSchema(ws.WorkloadPropertyOverrides)
+	schema := s.convertWorkloadToJSONSchema(ctx, ws.WorkloadPropertyOverrides)
 	return schema, nil
 }
 
-// GetWorkloads retrieves all workloads
-func (s *WorkloadService) GetWorkloads(c echo.Context, workload, projectID string) (*model.ListStacksResponse, error) {
-	options := &model.ListStacksOptions{
-		Organization: s.cfg.Pulumi.Organization,
-		TagName:      "idp:workload",
+// GetWorkloads retrieves all workloads from the workload catalog
+func (s *WorkloadService) GetWorkloads(ctx context.Context, workload, projectID string) (*model.ListStacksResponse, error) {
+	teams, err := s.visibleTeams(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if workload != "" {
-		options.TagValue = workload
+	records, err := s.workloads.List(ctx, model.WorkloadFilter{
+		Organization: s.cfg.Pulumi.Organization,
+		Name:         workload,
+		ProjectID:    projectID,
+		Teams:        teams,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workloads: %w", err)
 	}
 
-	if projectID != "" {
-		options.TagValue = projectID
+	stacks := &model.ListStacksResponse{
+		Stacks: make([]model.Stack, 0, len(records)),
 	}
-
-	stacks, err := s.pulumiService.ListStacks(options)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list stacks: %w", err)
+	for i := range records {
+		stacks.Stacks = append(stacks.Stacks, records[i].ToStack())
 	}
-
-	for i := range stacks.Stacks {
-		handler, err := s.pulumiService.GetStackUpdates(&model.ListStackUpdatesParams{
-			Page:       1,
-			PageSize:   1,
-			OutputType: "cli",
-		}, stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
-
-		if err != nil {
-			return nil, err
-		}
-
-		if len(handler.Deployments) > 0 {
-			stacks.Stacks[i].Result = handler.Deployments[0].Status
-		} else {
-			stacks.Stacks[i].Result = "no updates"
-		}
-
-		stackHandler, err := s.pulumiService.GetStack(stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
-		if err != nil {
-			return nil, err
-		}
-
-		stacks.Stacks[i].Tags = stackHandler.Tags
+	if s.statusService != nil {
+		s.statusService.Enrich(ctx, stacks.Stacks)
 	}
 
 	return stacks, nil
 }
 
 // DeleteWorkload deletes a workload
-func (s *WorkloadService) DeleteWorkload(organization, project, stack string) error {
+func (s *WorkloadService) DeleteWorkload(ctx context.Context, organization, project, stack string) error {
 	if organization == "" {
 		return fmt.Errorf("organization is required")
 	}
@@ -292,12 +300,43 @@ func (s *WorkloadService) DeleteWorkload(organization, project, stack string) er
 		return fmt.Errorf("stack is required")
 	}
 
-	err := s.pulumiService.DeleteDeployment(organization, project, stack)
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	event := &model.AuditEvent{
+		Actor:        actor(ctx),
+		Action:       model.AuditActionWorkloadDelete,
+		Organization: organization,
+		Project:      project,
+		Stack:        stack,
+		Blueprint:    project,
+		Outcome:      model.JobStatusSucceeded,
+	}
+	if record != nil {
+		event.Blueprint = recordBlueprintName(record)
+		event.Before = workloadSnapshot(record)
+	}
+	defer s.auditService.Record(ctx, event)
+
+	err = s.queueWorkloadDestroy(ctx, record, event)
+	if err != nil {
+		event.Outcome = model.JobStatusFailed
+		event.Error = err.Error()
+	}
+	return err
+}
+
+// queueWorkloadDestroy queues the destroy deployment of a workload and marks it for cleanup
+func (s *WorkloadService) queueWorkloadDestroy(ctx context.Context, record *model.WorkloadRecord, event *model.AuditEvent) error {
+	deployment, err := s.pulumiService.DeleteDeployment(ctx, event.Organization, event.Project, event.Stack)
 	if err != nil {
 		return err
 	}
+	event.DeploymentID = deployment.ID
 
-	err = s.pulumiService.SetStackTag(organization, project, stack, model.Tag{
+	err = s.pulumiService.SetStackTag(ctx, event.Organization, event.Project, event.Stack, model.Tag{
 		Key:   "idp:auto-delete",
 		Value: "true",
 	})
@@ -305,32 +344,310 @@ func (s *WorkloadService) DeleteWorkload(organization, project, stack string) er
 	if err != nil {
 		return fmt.Errorf("failed to set stack tags: %w", err)
 	}
+	s.invalidateStatus(event.Organization, event.Project, event.Stack)
 
-	return nil
+	if record == nil {
+		return nil
+	}
+	record.Status = model.WorkloadStatusDeleting
+	record.UpdatedBy = event.Actor
+	// A new delete starts the destroy retries over
+	record.DestroyAttempts = 0
+	record.NextDestroyAt = nil
+	return s.workloads.Save(ctx, record)
 }
 
-// UpdateWorkload updates a workload
-func (s *WorkloadService) UpdateWorkload(organization, project, stack string, req *model.WorkloadRequest) error {
+// UpdateWorkload starts a provisioning job that rewrites the workload configuration and redeploys it.
+// A dry run previews the new configuration instead and restores the previous one afterwards,
+// leaving the catalog record untouched.
+func (s *WorkloadService) UpdateWorkload(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (*model.ProvisioningJob, error) {
 	if organization == "" {
-		return fmt.Errorf("organization is required")
+		return nil, fmt.Errorf("organization is required")
 	}
 
 	if project == "" {
-		return fmt.Errorf("project is required")
+		return nil, fmt.Errorf("project is required")
 	}
 
 	if stack == "" {
-		return fmt.Errorf("stack is required")
+		return nil, fmt.Errorf("stack is required")
 	}
 
-	name := stringy.New(req.Name).KebabCase("?", "-").ToLower()
-	s.pulumiService.RunPulumiUp(name, req.BlueprintName, fmt.Sprintf("https://github.com/%s.git", s.cfg.Pulumi.BlueprintGithubLocation), req.Advanced, req.Stage)
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if errors.Is(err, repository.ErrNotFound) && req.DryRun {
+		return nil, fmt.Errorf("dry runs require a workload in the catalog: %w", err)
+	} else if errors.Is(err, repository.ErrNotFound) {
+		// Workloads created before the catalog existed are adopted on their first update
+		record = &model.WorkloadRecord{
+			Organization:    organization,
+			Blueprint:       project,
+			Stack:           stack,
+			Name:            req.Name,
+			BlueprintName:   req.BlueprintName,
+			CreationRequest: *req,
+		}
+	} else if err != nil {
+		return nil, err
+	}
 
-	return nil
+	// Two jobs writing the same environment would overwrite each other's config
+	if running, err := s.jobService.HasUnfinishedJob(ctx, organization, project, stack); err != nil {
+		return nil, err
+	} else if running {
+		return nil, fmt.Errorf("%w: %s/%s", ErrJobInProgress, project, stack)
+	}
+
+	// Workloads adopted by this update have no known previous state
+	var before map[string]interface{}
+	if record.ID != 0 {
+		before = workloadSnapshot(record)
+	}
+	previousStage := record.Stage
+	previousTeam := record.Team
+	previousStatus := record.Status
+
+	// The record keeps its state until the job has succeeded, the changes are applied then
+	changes := *record
+	if req.Stage != "" {
+		changes.Stage = req.Stage
+	}
+	// The owning team of the record only changes once the new team has access to the stack
+	team := previousTeam
+	if req.Team != "" {
+		team = req.Team
+	}
+	if req.ProjectID != "" {
+		changes.ProjectID = req.ProjectID
+	}
+	// An update keeps the pinned blueprint version unless the request moves it
+	version := req.Version
+	if version == "" {
+		version = record.BlueprintVersion
+	}
+	if version != "" && record.CreationRequest.CookieCut {
+		return nil, fmt.Errorf("cookie-cut workloads cannot be pinned to a blueprint version")
+	}
+
+	blueprintName := record.BlueprintName
+	if blueprintName == "" {
+		blueprintName = project
+	}
+	location, err := s.blueprintService.GetBlueprintLocation(ctx, blueprintName, version)
+	if err != nil {
+		return nil, err
+	}
+	if changes.RepoURL == "" {
+		changes.RepoURL = location.RepoURL
+	}
+	changes.BlueprintVersion = location.Version
+	changes.BlueprintCommit = location.Commit
+
+	_, properties, err := s.blueprintService.readConfigSchema(ctx, blueprintName, location.Version)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Advanced) > 0 {
+		// Keep the last applied config so that blueprint upgrades can carry it over.
+		// Secrets only live in the ESC environment.
+		changes.CreationRequest.Advanced = redactAdvanced(properties, req.Advanced)
+	}
+
+	if req.DryRun {
+		changes.Team = team
+		return s.previewWorkloadUpdate(ctx, organization, project, stack, req, &changes, location, properties, previousStage, before)
+	}
+
+	var job *model.ProvisioningJob
+	var steps []JobStep
+	if !strings.EqualFold(team, previousTeam) {
+		steps = append(steps, s.transferStep(organization, project, stack, record, team))
+	}
+	steps = append(steps, []JobStep{
+		{
+			Name: model.JobStepEnvironmentWritten,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				return s.writeEnvironmentConfig(ctx, organization, project, stack, changes.Stage, properties, req.Advanced)
+			},
+		},
+		{
+			Name: model.JobStepDeploymentQueued,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				if location.Version != "" {
+					err := s.pulumiService.SetStackTag(ctx, organization, project, stack, model.Tag{
+						Key:   "idp:blueprint-version",
+						Value: location.Version,
+					})
+					if err != nil {
+						return fmt.Errorf("failed to set stack tag idp:blueprint-version: %w", err)
+					}
+				}
+				deployment, err := s.pulumiService.CreateDeployment(ctx, organization, project, stack, location)
+				if err != nil {
+					return err
+				}
+				job.DeploymentID = deployment.ID
+				return nil
+			},
+		},
+	}...)
+
+	// Of two concurrent updates, only the one that claims the workload starts a job
+	if record.ID != 0 {
+		claimed, err := s.workloads.Claim(ctx, record, model.WorkloadStatusUpdating, busyWorkloadStatuses)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, fmt.Errorf("%w: %s/%s", ErrJobInProgress, project, stack)
+		}
+	}
+
+	job, err = s.jobService.CreateJob(ctx, model.JobKindUpdate, organization, project, stack, steps)
+	if err != nil {
+		if record.ID != 0 {
+			if restoreErr := s.workloads.UpdateStatus(ctx, organization, project, stack, previousStatus); restoreErr != nil {
+				s.logger.Printf("Failed to release workload %s: %v", stack, restoreErr)
+			}
+		}
+		return nil, err
+	}
+
+	record.Status = model.WorkloadStatusUpdating
+	record.LastJobID = job.ID
+	record.UpdatedBy = actor(ctx)
+	if err := s.workloads.Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	started := s.jobService.Start(job, steps, func(ctx context.Context, job *model.ProvisioningJob) {
+		if job.Status == model.JobStatusSucceeded {
+			applyWorkloadChanges(record, &changes)
+		}
+		s.finishWorkloadJob(ctx, record, job, before)
+	})
+
+	return started, nil
+}
+
+// busyWorkloadStatuses are the statuses of workloads that a job is changing
+var busyWorkloadStatuses = []string{
+	model.WorkloadStatusProvisioning,
+	model.WorkloadStatusUpdating,
+	model.WorkloadStatusDeleting,
+}
+
+// applyWorkloadChanges copies the fields an update changes onto the record once its job has
+// succeeded. The team is not among them, the transfer step sets it.
+func applyWorkloadChanges(record, changes *model.WorkloadRecord) {
+	record.Stage = changes.Stage
+	record.ProjectID = changes.ProjectID
+	record.RepoURL = changes.RepoURL
+	record.BlueprintVersion = changes.BlueprintVersion
+	record.BlueprintCommit = changes.BlueprintCommit
+	record.CreationRequest.Advanced = changes.CreationRequest.Advanced
+}
+
+// transferStep hands a workload over to another team. The team is granted access to the stack
+// and becomes the owner of the record, the previous team loses its access.
+func (s *WorkloadService) transferStep(organization, project, stack string, record *model.WorkloadRecord, team string) JobStep {
+	previousTeam := record.Team
+	return JobStep{
+		Name: model.JobStepTeamGranted,
+		Run: func(ctx context.Context, undo *UndoLog) error {
+			if err := s.pulumiService.GrantStackAccessToTeam(ctx, organization, team, project, stack, 103); err != nil {
+				return fmt.Errorf("failed to grant stack access to team: %w", err)
+			}
+			record.Team = team
+			undo.Register("revoke-team-access", func(ctx context.Context) error {
+				record.Team = previousTeam
+				return s.pulumiService.RevokeStackAccessFromTeam(ctx, organization, team, project, stack)
+			})
+
+			if previousTeam == "" {
+				return nil
+			}
+			if err := s.pulumiService.RevokeStackAccessFromTeam(ctx, organization, previousTeam, project, stack); err != nil {
+				return fmt.Errorf("failed to revoke stack access of team %s: %w", previousTeam, err)
+			}
+			undo.Register("restore-team-access", func(ctx context.Context) error {
+				return s.pulumiService.GrantStackAccessToTeam(ctx, organization, previousTeam, project, stack, 103)
+			})
+			return nil
+		},
+	}
 }
 
-// CreateWorkload creates a new workload
-func (s *WorkloadService) CreateWorkload(ctx context.Context, req *model.WorkloadRequest) (*model.RepoCreationResponse, error) {
+// writeEnvironmentConfig writes the requested config into a workload's ESC environment,
+// keeping the stored value of every secret the request omits
+func (s *WorkloadService) writeEnvironmentConfig(ctx context.Context, organization, project, stack, stage string, properties []model.ConfigProperty, advanced []map[string]interface{}) error {
+	stored, err := s.pulumiService.GetEnvironmentConfig(ctx, organization, project, stack)
+	if err != nil {
+		return err
+	}
+	config := secretConfig(properties, mergeConfig(advanced), stored)
+	return s.pulumiService.UpdateEnvironment(ctx, organization, project, stack, stage, []map[string]interface{}{config})
+}
+
+// previewWorkloadUpdate starts a job that writes the requested configuration, previews it against
+// the given blueprint location and then restores the previous configuration
+func (s *WorkloadService) previewWorkloadUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest, record *model.WorkloadRecord, location model.BlueprintLocation, properties []model.ConfigProperty, previousStage string, before map[string]interface{}) (*model.ProvisioningJob, error) {
+	stage := record.Stage
+	after := workloadSnapshot(record)
+
+	// The stored config is restored as is, secrets included
+	var previousConfig map[string]interface{}
+	restore := func(ctx context.Context) error {
+		return s.pulumiService.UpdateEnvironment(ctx, organization, project, stack, previousStage, []map[string]interface{}{previousConfig})
+	}
+
+	var job *model.ProvisioningJob
+	steps := []JobStep{
+		{
+			Name: model.JobStepEnvironmentWritten,
+			Run: func(ctx context.Context, undo *UndoLog) error {
+				stored, err := s.pulumiService.GetEnvironmentConfig(ctx, organization, project, stack)
+				if err != nil {
+					return err
+				}
+				previousConfig = stored
+				if err := s.writeEnvironmentConfig(ctx, organization, project, stack, stage, properties, req.Advanced); err != nil {
+					return err
+				}
+				undo.Register("restore-esc-environment", restore)
+				return nil
+			},
+		},
+	}
+	steps = append(steps, s.previewSteps(organization, project, stack,
+		func(ctx context.Context) (*model.CreateDeploymentResponse, error) {
+			return s.pulumiService.CreatePreviewDeployment(ctx, organization, project, stack, location)
+		},
+		func(preview *model.PreviewResult) {
+			job.Preview = preview
+		},
+	)...)
+	steps = append(steps, JobStep{
+		Name: model.JobStepEnvironmentRestore,
+		Run: func(ctx context.Context, _ *UndoLog) error {
+			return restore(ctx)
+		},
+	})
+
+	job, err := s.jobService.CreateJob(ctx, model.JobKindUpdatePreview, organization, project, stack, steps)
+	if err != nil {
+		return nil, err
+	}
+	started := s.jobService.Start(job, steps, func(ctx context.Context, job *model.ProvisioningJob) {
+		s.auditJob(ctx, record, job, before, after)
+	})
+
+	return started, nil
+}
+
+// CreateWorkload registers a new workload and starts a provisioning job that creates its stack,
+// optional repository, ESC environment and initial deployment. A dry run queues a preview
+// deployment instead of the initial deployment.
+func (s *WorkloadService) CreateWorkload(ctx context.Context, req *model.WorkloadRequest) (*model.ProvisioningJob, error) {
 	if req.Name == "" {
 		return nil, fmt.Errorf("repository name is required")
 	}
@@ -344,114 +661,419 @@ func (s *WorkloadService) CreateWorkload(ctx context.Context, req *model.Workloa
 		return nil, fmt.Errorf("GITHUB_TOKEN environment variable not set")
 	}
 
+	organization := s.cfg.Pulumi.Organization
 	name := stringy.New(req.Name).KebabCase("?", "-").ToLower()
 
-	_, err := s.pulumiService.CreateStack(s.cfg.Pulumi.Organization, req.Blueprint, name)
+	if req.CookieCut && req.Version != "" {
+		return nil, fmt.Errorf("cookie-cut workloads cannot be pinned to a blueprint version")
+	}
+
+	if req.CookieCut && req.DryRun {
+		return nil, fmt.Errorf("dry runs are not supported for cookie-cut workloads")
+	}
+
+	version, err := s.createVersion(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stack: %w", err)
+		return nil, err
 	}
 
-	err = s.pulumiService.GrantStackAccessToTeam(s.cfg.Pulumi.Organization, req.Team, req.Blueprint, name, 103)
+	// Unless the blueprint is cookie-cut into its own repository, the workload deploys
+	// straight from its directory in the blueprint source
+	projectDir := req.BlueprintName
+	location, err := s.blueprintService.GetBlueprintLocation(ctx, req.BlueprintName, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to grant stack access to team: %w", err)
+		return nil, err
 	}
+	repoURL := location.RepoURL
 
-	// Set workload name as stack tag
-	err = s.pulumiService.SetStackTag(s.cfg.Pulumi.Organization, req.Blueprint, name, model.Tag{
-		Key:   "idp:workload",
-		Value: req.Name,
-	})
+	_, properties, err := s.blueprintService.readConfigSchema(ctx, req.BlueprintName, location.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := s.resolveExpiry(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Secrets only live in the ESC environment
+	creationRequest := *req
+	creationRequest.Advanced = redactAdvanced(properties, req.Advanced)
+
+	steps := []JobStep{
+		{
+			Name: model.JobStepStackCreated,
+			Run: func(ctx context.Context, undo *UndoLog) error {
+				if _, err := s.pulumiService.CreateStack(ctx, organization, req.Blueprint, name); err != nil {
+					return fmt.Errorf("failed to create stack: %w", err)
+				}
+				// Deleting the stack also drops its team permissions and tags
+				undo.Register("delete-stack", func(ctx context.Context) error {
+					return s.pulumiService.DeleteStack(ctx, organization, req.Blueprint, name)
+				})
+				return nil
+			},
+		},
+		{
+			Name: model.JobStepTeamGranted,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				if err := s.pulumiService.GrantStackAccessToTeam(ctx, organization, req.Team, req.Blueprint, name, 103); err != nil {
+					return fmt.Errorf("failed to grant stack access to team: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: model.JobStepTagsSet,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				tags := append([]model.Tag{
+					{Key: "idp:workload", Value: req.Name},
+					{Key: "idp:projectid", Value: req.ProjectID},
+					{Key: "idp:stage", Value: req.Stage},
+				}, req.Tags...)
+				if location.Version != "" {
+					tags = append(tags, model.Tag{Key: "idp:blueprint-version", Value: location.Version})
+				}
+				if expiresAt != nil {
+					tags = append(tags, model.Tag{Key: model.ExpiresAtTag, Value: expiresAt.Format(time.RFC3339)})
+				}
+
+				for _, tag := range tags {
+					if err := s.pulumiService.SetStackTag(ctx, organization, req.Blueprint, name, tag); err != nil {
+						return fmt.Errorf("failed to set stack tag %s: %w", tag.Key, err)
+					}
+				}
+				return nil
+			},
+		},
+	}
+
+	if req.CookieCut {
+		steps = append(steps, JobStep{
+			Name: model.JobStepRepoCreated,
+			Run: func(ctx context.Context, undo *UndoLog) error {
+				repo, err := s.createWorkloadRepository(ctx, name, req, undo)
+				if err != nil {
+					return err
+				}
+
+				projectDir = "/"
+				repoURL = *repo.HTMLURL
+				location = model.BlueprintLocation{
+					RepoURL: *repo.CloneURL,
+					RepoDir: projectDir,
+				}
+				return nil
+			},
+		})
+	}
+
+	steps = append(steps,
+		JobStep{
+			Name: model.JobStepEnvironmentWritten,
+			Run: func(ctx context.Context, undo *UndoLog) error {
+				if err := s.pulumiService.CreateEnvironment(ctx, organization, projectDir, name); err != nil {
+					return err
+				}
+				// The ESC project is only known once the repository step has run
+				envProject := projectDir
+				undo.Register("delete-esc-environment", func(ctx context.Context) error {
+					return s.pulumiService.DeleteEnvironment(ctx, organization, envProject, name)
+				})
+				config := secretConfig(properties, mergeConfig(req.Advanced), nil)
+				return s.pulumiService.UpdateEnvironment(ctx, organization, projectDir, name, req.Stage, []map[string]interface{}{config})
+			},
+		},
+	)
+
+	var job *model.ProvisioningJob
+	kind := model.JobKindCreate
+	if req.DryRun {
+		// A dry run keeps the stack and environment so that the previewed workload
+		// can be deployed by a regular update or removed with a delete
+		kind = model.JobKindCreatePreview
+		steps = append(steps, s.previewSteps(organization, projectDir, name,
+			func(ctx context.Context) (*model.CreateDeploymentResponse, error) {
+				if err := s.pulumiService.CreateStackSettings(ctx, organization, projectDir, name, location); err != nil {
+					return nil, err
+				}
+				return s.pulumiService.CreatePreviewDeployment(ctx, organization, projectDir, name, location)
+			},
+			func(preview *model.PreviewResult) {
+				job.Preview = preview
+			},
+		)...)
+	} else {
+		steps = append(steps, JobStep{
+			Name: model.JobStepDeploymentQueued,
+			Run: func(ctx context.Context, _ *UndoLog) error {
+				deployment, err := s.pulumiService.CreateDeployment(ctx, organization, projectDir, name, location)
+				if err != nil {
+					return err
+				}
+				job.DeploymentID = deployment.ID
+				return nil
+			},
+		})
+	}
+
+	record := &model.WorkloadRecord{
+		Organization:     organization,
+		Blueprint:        req.Blueprint,
+		Stack:            name,
+		Name:             req.Name,
+		BlueprintName:    req.BlueprintName,
+		Stage:            req.Stage,
+		Team:             req.Team,
+		ProjectID:        req.ProjectID,
+		RepoURL:          repoURL,
+		BlueprintVersion: location.Version,
+		BlueprintCommit:  location.Commit,
+		CreationRequest:  creationRequest,
+		Status:           model.WorkloadStatusProvisioning,
+		ExpiresAt:        expiresAt,
+		CreatedBy:        actor(ctx),
+		UpdatedBy:        actor(ctx),
+	}
+	if err := s.workloads.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save workload: %w", err)
+	}
+
+	job, err = s.jobService.CreateJob(ctx, kind, organization, req.Blueprint, name, steps)
+	if err != nil {
+		_ = s.workloads.DeleteByStack(ctx, organization, req.Blueprint, name)
+		return nil, err
+	}
 
-	// Set projectid as stack tag
-	err = s.pulumiService.SetStackTag(s.cfg.Pulumi.Organization, req.Blueprint, name, model.Tag{
-		Key:   "idp:projectid",
-		Value: req.ProjectID,
+	record.LastJobID = job.ID
+	if err := s.workloads.Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	started := s.jobService.Start(job, steps, func(ctx context.Context, job *model.ProvisioningJob) {
+		record.RepoURL = repoURL
+		s.finishWorkloadJob(ctx, record, job, nil)
 	})
 
-	// Set stage as stack tag
-	err = s.pulumiService.SetStackTag(s.cfg.Pulumi.Organization, req.Blueprint, name, model.Tag{
-		Key:   "idp:stage",
-		Value: req.Stage,
+	return started, nil
+}
+
+// createWorkloadRepository creates a GitHub repository for the workload and seeds it with the blueprint
+func (s *WorkloadService) createWorkloadRepository(ctx context.Context, name string, req *model.WorkloadRequest, undo *UndoLog) (*github.Repository, error) {
+	repoRequest := model.RepoCreationRequest{
+		RepoName:               name,
+		Description:            "Generated repository via Pulumi IDP",
+		Private:                false,
+		EnableBranchProtection: true,
+		ProtectedBranches:      []string{"main"},
+		RequireReviews:         true,
+	}
+
+	repo, _, err := s.githubService.CreateRepository(ctx, &repoRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository: %w", err)
+	}
+	undo.Register("delete-repository", func(ctx context.Context) error {
+		return s.githubService.DeleteRepository(ctx, repo.GetOwner().GetLogin(), repo.GetName())
 	})
 
+	tempDir, err := ioutil.TempDir("", "pulumi-project-")
 	if err != nil {
-		return nil, fmt.Errorf("failed to set stack tag: %w", err)
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
+	defer os.RemoveAll(tempDir)
 
-	for _, tag := range req.Tags {
-		err = s.pulumiService.SetStackTag(s.cfg.Pulumi.Organization, req.Blueprint, name, tag)
-		if err != nil {
-			return nil, fmt.Errorf("failed to set stack tags: %w", err)
-		}
+	pulumiTemplate := stringy.New(req.Blueprint).KebabCase("?", "-").ToLower()
+	err = s.pulumiService.RunPulumiNew(ctx, tempDir, pulumiTemplate, name, "Pulumi project created via Pulumi IDP")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pulumi project: %w", err)
 	}
 
-	if req.CookieCut {
-		repoRequest := model.RepoCreationRequest{
-			RepoName:               name,
-			Description:            "Generated repository via Pulumi IDP",
-			Private:                false,
-			EnableBranchProtection: true,
-			ProtectedBranches:      []string{"main"},
-			RequireReviews:         true,
+	err = s.githubService.CommitPulumiFilesToRepo(ctx, tempDir, repo.GetOwner().GetLogin(), *repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit Pulumi files: %w", err)
+	}
+
+	if repoRequest.EnableBranchProtection && len(repoRequest.ProtectedBranches) > 0 {
+		for _, branch := range repoRequest.ProtectedBranches {
+			err = s.githubService.SetupBranchProtection(ctx, repo.GetOwner().GetLogin(), *repo.Name, branch, repoRequest.RequireReviews)
+			if err != nil {
+				return nil, fmt.Errorf("failed to set up branch protection for %s: %w", branch, err)
+			}
 		}
+	}
 
-		repo, _, err := s.githubService.CreateRepository(ctx, &repoRequest)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create repository: %w", err)
+	return repo, nil
+}
+
+// finishWorkloadJob records the outcome of a provisioning job on the workload and in the audit log.
+// before is the snapshot of the workload from before the job, nil for a create.
+func (s *WorkloadService) finishWorkloadJob(ctx context.Context, record *model.WorkloadRecord, job *model.ProvisioningJob, before map[string]interface{}) {
+	s.auditJob(ctx, record, job, before, workloadSnapshot(record))
+	s.invalidateStatus(record.Organization, record.Blueprint, record.Stack)
+
+	// A failed create that was fully rolled back, or failed before it created anything to undo,
+	// leaves nothing behind to list in the catalog
+	created := job.Kind == model.JobKindCreate || job.Kind == model.JobKindCreatePreview
+	if created && job.Status == model.JobStatusFailed && job.RollbackStatus != model.RollbackStatusFailed {
+		if err := s.workloads.DeleteByStack(ctx, record.Organization, record.Blueprint, record.Stack); err != nil {
+			s.logger.Printf("Failed to remove rolled back workload %s: %v", record.Stack, err)
 		}
+		return
+	}
 
-		tempDir, err := ioutil.TempDir("", "pulumi-project-")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	if job.Status == model.JobStatusSucceeded && job.Kind == model.JobKindCreatePreview {
+		record.Status = model.WorkloadStatusPreviewed
+	} else if job.Status == model.JobStatusSucceeded {
+		record.Status = model.WorkloadStatusActive
+	} else {
+		record.Status = model.WorkloadStatusFailed
+	}
+
+	if err := s.workloads.Save(ctx, record); err != nil {
+		s.logger.Printf("Failed to update workload %s after job %s: %v", record.Stack, job.ID, err)
+	}
+}
+
+// invalidateStatus makes the workload list read the status of a stack that has just been deployed or destroyed
+func (s *WorkloadService) invalidateStatus(organization, project, stack string) {
+	if s.statusService != nil {
+		s.statusService.Invalidate(organization, project, stack)
+	}
+}
+
+// jobAuditActions maps provisioning job kinds to the audited action
+var jobAuditActions = map[string]string{
+	model.JobKindCreate:         model.AuditActionWorkloadCreate,
+	model.JobKindUpdate:         model.AuditActionWorkloadUpdate,
+	model.JobKindCreatePreview:  model.AuditActionWorkloadCreatePreview,
+	model.JobKindUpdatePreview:  model.AuditActionWorkloadUpdatePreview,
+	model.JobKindUpgradePreview: model.AuditActionWorkloadUpgradePreview,
+}
+
+// auditJob records a finished provisioning job in the audit log, attributed to the user that requested it
+func (s *WorkloadService) auditJob(ctx context.Context, record *model.WorkloadRecord, job *model.ProvisioningJob, before, after map[string]interface{}) {
+	deploymentID := job.DeploymentID
+	if deploymentID == "" && job.Preview != nil {
+		deploymentID = job.Preview.DeploymentID
+	}
+
+	s.auditService.Record(ctx, &model.AuditEvent{
+		Actor:        job.RequestedBy,
+		Action:       jobAuditActions[job.Kind],
+		Organization: job.Organization,
+		Project:      job.Project,
+		Stack:        job.Stack,
+		Blueprint:    recordBlueprintName(record),
+		JobID:        job.ID,
+		DeploymentID: deploymentID,
+		Outcome:      job.Status,
+		Error:        job.Error,
+		Before:       before,
+		After:        after,
+	})
+}
+
+// recordBlueprintName returns the blueprint a workload was created from. Records from before
+// blueprint names were stored use the Pulumi project, which is named after the blueprint.
+func recordBlueprintName(record *model.WorkloadRecord) string {
+	if record.BlueprintName != "" {
+		return record.BlueprintName
+	}
+	return record.Blueprint
+}
+
+// RecoverOrphanedJobs fails the jobs of replicas that are no longer alive and marks the
+// workloads they were provisioning as failed
+func (s *WorkloadService) RecoverOrphanedJobs(ctx context.Context, workers []string) error {
+	jobs, err := s.jobService.FailOrphanedJobs(ctx, workers)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		record, err := s.workloads.FindByStack(ctx, job.Organization, job.Project, job.Stack)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
 		}
-		defer os.RemoveAll(tempDir)
+		if record.LastJobID != job.ID {
+			continue
+		}
+		if record.Status == model.WorkloadStatusProvisioning || record.Status == model.WorkloadStatusUpdating {
+			record.Status = model.WorkloadStatusFailed
+			if err := s.workloads.Save(ctx, record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		pulumiTemplate := stringy.New(req.Blueprint).KebabCase("?", "-").ToLower()
-		err = s.pulumiService.RunPulumiNew(tempDir, pulumiTemplate, name, "Pulumi project created via Pulumi IDP")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Pulumi project: %w", err)
+// GetOutdatedWorkloads lists the workloads pinned to an older blueprint version than the latest release.
+// Workloads without a pinned version count as outdated once their blueprint has releases.
+func (s *WorkloadService) GetOutdatedWorkloads(ctx context.Context) ([]model.OutdatedWorkload, error) {
+	teams, err := s.visibleTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.workloads.List(ctx, model.WorkloadFilter{
+		Organization: s.cfg.Pulumi.Organization,
+		Teams:        teams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outdated := []model.OutdatedWorkload{}
+	latestVersions := make(map[string]string)
+	for _, record := range records {
+		if record.CreationRequest.CookieCut {
+			continue
 		}
 
-		err = s.githubService.CommitPulumiFilesToRepo(ctx, tempDir, repo.GetOwner().GetLogin(), *repo.Name)
-		if err != nil {
-			return nil, fmt.Errorf("failed to commit Pulumi files: %w", err)
+		blueprintName := record.BlueprintName
+		if blueprintName == "" {
+			blueprintName = record.Blueprint
 		}
 
-		if repoRequest.EnableBranchProtection && len(repoRequest.ProtectedBranches) > 0 {
-			for _, branch := range repoRequest.ProtectedBranches {
-				err = s.githubService.SetupBranchProtection(ctx, repo.GetOwner().GetLogin(), *repo.Name, branch, repoRequest.RequireReviews)
-				if err != nil {
-					return nil, fmt.Errorf("failed to set up branch protection for %s: %w", branch, err)
-				}
+		latest, ok := latestVersions[blueprintName]
+		if !ok {
+			latest, err = s.blueprintService.LatestBlueprintVersion(ctx, blueprintName)
+			if err != nil {
+				s.logger.Printf("Failed to resolve latest version of blueprint %s: %v", blueprintName, err)
 			}
+			latestVersions[blueprintName] = latest
 		}
 
-		s.pulumiService.RunPulumiUp(name, "/", *repo.CloneURL, req.Advanced, req.Stage)
-
-		return &model.RepoCreationResponse{
-			RepoURL:  *repo.HTMLURL,
-			CloneURL: *repo.CloneURL,
-			Message:  "Repository created successfully with Pulumi project files",
-			Success:  true,
-		}, nil
-	} else {
-		repo := fmt.Sprintf("https://github.com/%s.git", s.cfg.Pulumi.BlueprintGithubLocation)
-		s.pulumiService.RunPulumiUp(name, req.BlueprintName, fmt.Sprintf("https://github.com/%s.git", s.cfg.Pulumi.BlueprintGithubLocation), req.Advanced, req.Stage)
+		if latest == "" || record.BlueprintVersion == latest {
+			continue
+		}
+		if record.BlueprintVersion != "" && compareVersions(record.BlueprintVersion, latest) >= 0 {
+			continue
+		}
 
-		return &model.RepoCreationResponse{
-			RepoURL:  repo,
-			CloneURL: repo,
-			Message:  "Repository created successfully with Pulumi project files",
-			Success:  true,
-		}, nil
+		outdated = append(outdated, model.OutdatedWorkload{
+			Organization:   record.Organization,
+			Project:        record.Blueprint,
+			Stack:          record.Stack,
+			Name:           record.Name,
+			Blueprint:      blueprintName,
+			CurrentVersion: record.BlueprintVersion,
+			LatestVersion:  latest,
+		})
 	}
+
+	return outdated, nil
 }
 
 // GetWorkloadDetails retrieves detailed information about a workload
-func (s *WorkloadService) GetWorkloadDetails(organization, project, stack string) (*model.WorkloadResponse, error) {
+func (s *WorkloadService) GetWorkloadDetails(ctx context.Context, organization, project, stack string) (*model.WorkloadResponse, error) {
 	configuration := esc.NewConfiguration()
 	escClient := esc.NewClient(configuration)
-	authCtx := esc.NewAuthContext(s.cfg.Pulumi.APIToken)
+	authCtx := escAuthContext(ctx, s.cfg.Pulumi.APIToken)
 
 	_, values, err := escClient.OpenAndReadEnvironment(authCtx, organization, project, stack)
 	if err != nil {
@@ -463,20 +1085,13 @@ func (s *WorkloadService) GetWorkloadDetails(organization, project, stack string
 		return nil, fmt.Errorf("secret 'pulumiConfig' not found in environment %s/%s", project, stack)
 	}
 
-	cleanPulumiConfig := make(map[string]interface{})
-	for key, value := range pulumiConfig {
-		if !strings.Contains(key, "token") && !strings.Contains(key, "kubeconfig") {
-			cleanPulumiConfig[key] = value
-		}
-	}
-
 	options := &model.ListStacksOptions{
 		Organization: s.cfg.Pulumi.Organization,
 		TagName:      "idp:workload",
 		TagValue:     stack,
 	}
 
-	stacks, err := s.pulumiService.ListStacks(options)
+	stacks, err := s.pulumiService.ListStacks(ctx, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list stacks: %w", err)
 	}
@@ -486,7 +1101,7 @@ func (s *WorkloadService) GetWorkloadDetails(organization, project, stack string
 	}
 
 	for i := range stacks.Stacks {
-		handler, err := s.pulumiService.GetStackUpdates(&model.ListStackUpdatesParams{
+		handler, err := s.pulumiService.GetStackUpdates(ctx, &model.ListStackUpdatesParams{
 			Page:       1,
 			PageSize:   1,
 			OutputType: "cli",
@@ -504,12 +1119,12 @@ func (s *WorkloadService) GetWorkloadDetails(organization, project, stack string
 			stacks.Stacks[i].DeploymentId = ""
 		}
 
-		stackHandler, err := s.pulumiService.GetStack(stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
+		stackHandler, err := s.pulumiService.GetStack(ctx, stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
 		if err != nil {
 			return nil, err
 		}
 
-		stackResources, err := s.pulumiService.GetLatestStackResources(s.cfg.Pulumi.Organization, stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
+		stackResources, err := s.pulumiService.GetLatestStackResources(ctx, s.cfg.Pulumi.Organization, stacks.Stacks[i].ProjectName, stacks.Stacks[i].StackName)
 		if err != nil {
 			return nil, err
 		}
@@ -521,59 +1136,44 @@ func (s *WorkloadService) GetWorkloadDetails(organization, project, stack string
 		stacks.Stacks[i].Tags = stackHandler.Tags
 	}
 
-	return &model.WorkloadResponse{
+	response := &model.WorkloadResponse{
 		Name:          stack,
 		Blueprint:     project,
 		BlueprintName: project,
 		ProjectID:     stacks.Stacks[0].Tags["idp:projectid"],
 		Stack:         stacks.Stacks[0],
 		Stage:         stacks.Stacks[0].Tags["idp:stage"],
-		Advanced: []map[string]interface{}{
-			cleanPulumiConfig,
-		},
-	}, nil
-}
-
-// GetDeploymentLogs retrieves logs for a deployment
-func (s *WorkloadService) GetDeploymentLogs(organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error) {
-	// Construct the URL for the Pulumi API
-	url := fmt.Sprintf("%s/stacks/%s/%s/%s/deployments/%s/logs",
-		s.cfg.Pulumi.APIBaseURL, organization, project, stack, deploymentID)
-
-	if continuationToken != "" {
-		url = fmt.Sprintf("%s?continuationToken=%s", url, continuationToken)
 	}
 
-	// Create a new request
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	record, err := s.workloads.FindByStack(ctx, organization, project, stack)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if record != nil {
+		response.Team = record.Team
+		response.CookieCut = record.CreationRequest.CookieCut
+		response.Version = record.BlueprintVersion
+		response.ExpiresAt = record.ExpiresAt
+		response.Tags = record.CreationRequest.Tags
+		if record.BlueprintName != "" {
+			response.BlueprintName = record.BlueprintName
+		}
 	}
 
-	// Set headers
-	req.Header.Set("Accept", s.cfg.Pulumi.APIVersion)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", s.cfg.Pulumi.APIToken))
-
-	// Make the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// The opened environment holds secrets in plaintext, so only config the blueprint
+	// schema marks as non-secret is returned
+	_, properties, err := s.blueprintService.readConfigSchema(ctx, response.BlueprintName, response.Version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch logs: %w", err)
+		return nil, fmt.Errorf("failed to read config schema of blueprint %s: %w", response.BlueprintName, err)
 	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Pulumi API returned error: %s", string(body))
+	response.Advanced = []map[string]interface{}{
+		redactSecrets(properties, pulumiConfig),
 	}
 
-	// Parse response
-	var logResponse model.LogResponse
-	if err := json.NewDecoder(resp.Body).Decode(&logResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse logs: %w", err)
-	}
+	return response, nil
+}
 
-	return &logResponse, nil
+// GetDeploymentLogs retrieves a page of the logs of a deployment
+func (s *WorkloadService) GetDeploymentLogs(ctx context.Context, organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error) {
+	return s.pulumiService.GetDeploymentLogs(ctx, organization, project, stack, deploymentID, continuationToken)
 }
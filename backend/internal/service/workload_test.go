@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi-idp/internal/config"
+	"github.com/pulumi-idp/internal/model"
+	"github.com/pulumi-idp/internal/repository"
+)
+
+func TestClaimLetsOneUpdateStart(t *testing.T) {
+	ctx := context.Background()
+	workloads := repository.NewWorkloadRepository(newTestDB(t, &model.WorkloadRecord{}))
+	record := &model.WorkloadRecord{Organization: "acme", Blueprint: "web", Stack: "dev", Status: model.WorkloadStatusActive}
+	if err := workloads.Create(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := workloads.Claim(ctx, record, model.WorkloadStatusUpdating, busyWorkloadStatuses)
+	if err != nil || !first {
+		t.Fatalf("the first claim failed: %t, %v", first, err)
+	}
+	second, err := workloads.Claim(ctx, record, model.WorkloadStatusUpdating, busyWorkloadStatuses)
+	if err != nil || second {
+		t.Fatalf("the second claim succeeded: %t, %v", second, err)
+	}
+}
+
+func TestHasUnfinishedJob(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewJobService(&config.Config{}, repository.NewJobRepository(newTestDB(t, &model.ProvisioningJob{}, &model.ProvisioningStep{})))
+
+	if _, err := jobs.CreateJob(ctx, model.JobKindUpdate, "acme", "web", "dev", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if running, err := jobs.HasUnfinishedJob(ctx, "acme", "web", "dev"); err != nil || !running {
+		t.Fatalf("the pending job was not found: %t, %v", running, err)
+	}
+	if running, err := jobs.HasUnfinishedJob(ctx, "acme", "web", "prod"); err != nil || running {
+		t.Fatalf("a job of another stack was found: %t, %v", running, err)
+	}
+}
+
+func TestApplyWorkloadChangesKeepsTeam(t *testing.T) {
+	record := &model.WorkloadRecord{Stage: "development", Team: "payments"}
+	changes := *record
+	changes.Stage = "production"
+	changes.Team = "checkout"
+	changes.CreationRequest.Advanced = []map[string]interface{}{{"replicas": 3}}
+
+	applyWorkloadChanges(record, &changes)
+	if record.Stage != "production" || len(record.CreationRequest.Advanced) != 1 {
+		t.Fatalf("the changes were not applied: %+v", record)
+	}
+	if record.Team != "payments" {
+		t.Fatalf("the team changed outside of the transfer step: %s", record.Team)
+	}
+}
+
+func TestFinishWorkloadJobRemovesCreatesThatLeftNothing(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		rollback string
+		removed  bool
+	}{
+		{"failed before creating anything", model.JobStatusFailed, "", true},
+		{"rolled back", model.JobStatusFailed, model.RollbackStatusSucceeded, true},
+		{"rollback failed", model.JobStatusFailed, model.RollbackStatusFailed, false},
+		{"succeeded", model.JobStatusSucceeded, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t, &model.WorkloadRecord{}, &model.AuditEvent{})
+			workloads := repository.NewWorkloadRepository(db)
+			s := NewWorkloadService(&config.Config{}, workloads)
+			s.SetAuditService(NewAuditService(&config.Config{}, repository.NewAuditRepository(db)))
+
+			record := &model.WorkloadRecord{Organization: "acme", Blueprint: "web", Stack: "dev", Status: model.WorkloadStatusProvisioning}
+			if err := workloads.Create(ctx, record); err != nil {
+				t.Fatal(err)
+			}
+			job := &model.ProvisioningJob{Kind: model.JobKindCreate, Organization: "acme", Project: "web", Stack: "dev",
+				Status: test.status, RollbackStatus: test.rollback}
+
+			s.finishWorkloadJob(ctx, record, job, nil)
+			_, err := workloads.FindByStack(ctx, "acme", "web", "dev")
+			if removed := err != nil; removed != test.removed {
+				t.Fatalf("removed = %t, want %t (%v)", removed, test.removed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package job
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	GetJob(ctx context.Context, id string) (*model.ProvisioningJob, error)
+}
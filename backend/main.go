@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/pulumi-idp/internal/api/handler"
 	"github.com/pulumi-idp/internal/config"
 	cleanup "github.com/pulumi-idp/internal/cron"
+	"github.com/pulumi-idp/internal/database"
 	"github.com/pulumi-idp/internal/repository"
 	"github.com/pulumi-idp/internal/service"
 	"github.com/pulumi-idp/router"
-	"log"
+	"gorm.io/gorm/logger"
 )
 
 func main() {
@@ -20,18 +28,42 @@ func main() {
 
 	cfg := config.Load()
 
-	repos := repository.NewRepository()
+	db, err := database.NewDatabase(database.Config{
+		Driver:          cfg.Database.Driver,
+		ConnectionURL:   cfg.Database.ConnectionURL,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		LogLevel:        logger.Warn,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatalf("Failed to run database migrations: %v", err)
+	}
+
+	repos := repository.NewRepository(db)
 	services := service.NewService(repos, cfg)
+	if err := services.AuthService.Validate(); err != nil {
+		log.Fatalf("Invalid authentication settings: %v", err)
+	}
+	if err := services.PolicyService.Load(); err != nil {
+		log.Fatalf("Failed to load policies: %v", err)
+	}
 
-	deletionCriteria := cleanup.StackDeletionCriteria{
-		DeletionTags: cleanup.DeletionTags{
-			Key:   "idp:auto-delete",
-			Value: "true",
-		},
+	deletionCriteria, err := cleanup.LoadDeletionCriteria(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load cleanup rules: %v", err)
 	}
 	r := router.New(cfg)
 
-	cleanupService := cleanup.NewStackCleanupService(cfg, deletionCriteria, r.StdLogger)
+	cleanupService := cleanup.NewStackCleanupService(cfg, repos, deletionCriteria, r.StdLogger)
+	cleanupService.SetPulumiService(services.PulumiService)
+	cleanupService.SetWorkloadService(services.WorkloadService)
+	cleanupService.SetNotificationService(services.NotificationService)
+	cleanupService.SetAuditService(services.AuditService)
 	if err := cleanupService.Start(); err != nil {
 		r.Logger.Fatalf("Failed to start stack cleanup service: %v", err)
 	}
@@ -43,12 +75,63 @@ func main() {
 
 	v1 := r.Group("/api")
 	h := handler.NewHandler(services, cfg)
+	h.SetCleanupService(cleanupService)
 	h.Register(v1)
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The leases are released only after the jobs have drained, so the leader cancels them last
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		services.LeaderService.Run(leaderCtx, cleanupService.SetLeader)
+	}()
+	go services.StackStatusService.Run(ctx)
+
+	// Workloads created before the catalog existed are added to it in the background
+	go func() {
+		added, err := services.WorkloadService.BackfillWorkloads(ctx)
+		if err != nil {
+			log.Printf("Failed to backfill the workload catalog: %v", err)
+		}
+		if added > 0 {
+			log.Printf("Added %d existing workloads to the catalog", added)
+		}
+	}()
+
 	port := cfg.Server.Port
 	if port == "" {
 		port = "3000"
 	}
-	log.Printf("Server running on port %s\n", port)
-	r.Logger.Fatal(r.Start(":" + port))
+	go func() {
+		log.Printf("Server running on port %s\n", port)
+		if err := r.Start(":" + port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down, waiting up to %s for requests and jobs", cfg.Server.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain HTTP requests: %v", err)
+	}
+	if err := cleanupService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain the cleanup: %v", err)
+	}
+	if err := services.JobService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain jobs: %v", err)
+	}
+	if err := services.BlueprintService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain blueprint catalog rebuilds: %v", err)
+	}
+
+	cancelLeader()
+	<-leaderDone
+	log.Println("Server stopped")
 }
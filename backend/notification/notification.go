@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Notify(ctx context.Context, notification *model.Notification) error
+	NotifyExpiry(ctx context.Context, event string, record *model.WorkloadRecord) error
+	NotifyDestroyFailed(ctx context.Context, organization, project, stack string, record *model.WorkloadRecord) error
+	NotifyCleanupMatched(ctx context.Context, rule, organization, project, stack string, record *model.WorkloadRecord) error
+}
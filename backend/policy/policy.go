@@ -0,0 +1,16 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Load() error
+	Policies() []model.Policy
+	EvaluateCreate(ctx context.Context, req *model.WorkloadRequest) ([]model.PolicyViolation, error)
+	EvaluateUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) ([]model.PolicyViolation, error)
+	AdmitCreate(ctx context.Context, req *model.WorkloadRequest) error
+	AdmitUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) error
+}
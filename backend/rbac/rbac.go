@@ -0,0 +1,16 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/pulumi-idp/internal/model"
+)
+
+type Service interface {
+	Role(user *model.User) string
+	AuthorizeWorkload(ctx context.Context, action, organization, project, stack string) error
+	AuthorizeProvision(ctx context.Context, blueprint, team string) error
+	AuthorizeTransfer(ctx context.Context, organization, project, stack, team string) error
+	VisibleTeams(ctx context.Context) ([]string, error)
+	AuthorizeRole(ctx context.Context, required string) error
+}
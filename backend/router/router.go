@@ -12,9 +12,26 @@ func New(cfg *config.Config) *echo.Echo {
 	e.Logger.SetLevel(log.DEBUG)
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Pre(middleware.RemoveTrailingSlash())
-	e.Use(middleware.Logger())
+	// The query string is left out of the access log, it may carry credentials
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}",` +
+			`"host":"${host}","method":"${method}","path":"${path}","user_agent":"${user_agent}",` +
+			`"status":${status},"error":"${error}","latency":${latency},"latency_human":"${latency_human}"` +
+			`,"bytes_in":${bytes_in},"bytes_out":${bytes_out}}` + "\n",
+	}))
 	e.Use(middleware.Recover())
 
+	// ReadTimeout bounds reading a request, WriteTimeout how long handling it may take. The
+	// deadline is on the request context, so that the outbound calls of a request are
+	// cancelled with it. WebSockets stream for as long as the client is connected.
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Skipper: func(c echo.Context) bool {
+			return c.IsWebSocket()
+		},
+		Timeout: cfg.Server.WriteTimeout,
+	}))
+
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     cfg.Cors.AllowOrigin,
 		AllowMethods:     cfg.Cors.AllowMethods,
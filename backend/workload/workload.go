@@ -2,16 +2,29 @@ package workload
 
 import (
 	"context"
-	"github.com/labstack/echo/v4"
+	"time"
+
 	"github.com/pulumi-idp/internal/model"
 )
 
 type Service interface {
-	GetWorkloadSchema(ctx echo.Context) (map[string]interface{}, error)
-	GetWorkloads(ctx echo.Context, workload, projectID string) (*model.ListStacksResponse, error)
-	DeleteWorkload(organization, project, stack string) error
-	UpdateWorkload(organization, project, stack string, req *model.WorkloadRequest) error
-	CreateWorkload(ctx context.Context, req *model.WorkloadRequest) (*model.RepoCreationResponse, error)
-	GetWorkloadDetails(organization, project, stack string) (*model.WorkloadResponse, error)
-	GetDeploymentLogs(organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error)
+	GetWorkloadSchema(ctx context.Context) (map[string]interface{}, error)
+	GetWorkloads(ctx context.Context, workload, projectID string) (*model.ListStacksResponse, error)
+	DeleteWorkload(ctx context.Context, organization, project, stack string) error
+	UpdateWorkload(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest) (*model.ProvisioningJob, error)
+	ValidateWorkloadRequest(ctx context.Context, req *model.WorkloadRequest, raw map[string]interface{}) error
+	ValidateWorkloadUpdate(ctx context.Context, organization, project, stack string, req *model.WorkloadRequest, raw map[string]interface{}) error
+	CreateWorkload(ctx context.Context, req *model.WorkloadRequest) (*model.ProvisioningJob, error)
+	PreviewUpgrade(ctx context.Context, organization, project, stack, version string) (*model.UpgradePlan, error)
+	ApplyUpgrade(ctx context.Context, organization, project, stack string, req *model.UpgradeRequest) (*model.ProvisioningJob, error)
+	GetOutdatedWorkloads(ctx context.Context) ([]model.OutdatedWorkload, error)
+	GetWorkloadDetails(ctx context.Context, organization, project, stack string) (*model.WorkloadResponse, error)
+	GetDeploymentLogs(ctx context.Context, organization, project, stack, deploymentID, continuationToken string) (*model.LogResponse, error)
+	ExtendWorkload(ctx context.Context, organization, project, stack string, req *model.ExtendRequest) (*model.WorkloadRecord, error)
+	ExpiringWorkloads(ctx context.Context, until time.Time) ([]model.WorkloadRecord, error)
+	ExpireWorkload(ctx context.Context, record *model.WorkloadRecord) error
+	MarkExpiryNotified(ctx context.Context, record *model.WorkloadRecord) error
+	CleanupDeletedWorkload(ctx context.Context, organization, project, stack string) (string, *model.WorkloadRecord, error)
+	DestroyStack(ctx context.Context, organization, project, stack string) (bool, error)
+	RecoverOrphanedJobs(ctx context.Context, workers []string) error
 }
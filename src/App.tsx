@@ -21,7 +21,7 @@ const oidcConfig = {
     token_endpoint: import.meta.env.VITE_GITHUB_TOKEN_ENDPOINT,
     userinfo_endpoint: import.meta.env.VITE_GITHUB_USER_API,
 
-    scope: import.meta.env.VITE_GITHUB_SCOPE || 'read:user user:email',
+    scope: import.meta.env.VITE_GITHUB_SCOPE || 'read:user user:email read:org',
     loadUserInfo: true,
 
     response_type: 'code',
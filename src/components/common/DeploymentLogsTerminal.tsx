@@ -1,5 +1,6 @@
 import React, {useEffect, useRef, useState} from 'react';
 import {Terminal} from 'lucide-react';
+import {useAuth} from "react-oidc-context";
 
 export default function DeploymentLogsTerminal({host, organization, project, stack, deploymentID}) {
     const [logs, setLogs] = useState([]);
@@ -9,6 +10,7 @@ export default function DeploymentLogsTerminal({host, organization, project, sta
     const terminalRef = useRef(null);
     const wsRef = useRef(null);
     const [isConnected, setIsConnected] = useState(false);
+    const auth = useAuth();
 
     const formatTimestamp = (timestamp) => {
         if (!timestamp || timestamp === '0001-01-01T00:00:00Z') return '';
@@ -38,7 +40,9 @@ export default function DeploymentLogsTerminal({host, organization, project, sta
 
             const wsUrl = `${window.location.protocol === 'https:' ? 'wss:' : 'ws:'}//${host.replace(/^https?:\/\//, '')}/api/workloads/ws/${organization}/${project}/${stack}/deployments/${deploymentID}/logs`;
 
-            const socket = new WebSocket(wsUrl);
+            // Browsers cannot set headers on WebSockets, the token is sent as a subprotocol instead
+            const token = btoa(auth.user?.access_token ?? '').replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+            const socket = new WebSocket(wsUrl, ['pulumi-idp.logs', `base64url.bearer.${token}`]);
             wsRef.current = socket;
 
             socket.onopen = () => {
@@ -102,7 +106,7 @@ export default function DeploymentLogsTerminal({host, organization, project, sta
                     continuationToken ? `?continuationToken=${continuationToken}` : ''
                 }`;
 
-                const response = await fetch(url);
+                const response = await fetch(url, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}});
 
                 if (!response.ok) {
                     const errorText = await response.text();
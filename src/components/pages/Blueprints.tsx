@@ -2,6 +2,7 @@ import {useEffect, useState} from 'react';
 import {AlertCircle} from 'lucide-react';
 import Card, {CardContent, CardHeader, CardTitle} from "../common/Card.tsx";
 import {useNavigate} from "react-router-dom";
+import {useAuth} from "react-oidc-context";
 import LoadingSpinner from "../common/LoadingsSpinner.tsx";
 import BlueprintCard from "../common/BlueprintCard.tsx";
 import {IconName} from "lucide-react/dynamic";
@@ -33,12 +34,13 @@ export default function BlueprintGrid() {
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState(null);
     const navigate = useNavigate();
+    const auth = useAuth();
 
     useEffect(() => {
         let isMounted = true;
         const API_URL = import.meta.env.MODE === 'production' ? import.meta.env.VITE_API_URL : '/';
 
-        fetch(`${API_URL}api/blueprints/`)
+        fetch(`${API_URL}api/blueprints/`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => {
                 if (!res.ok) throw new Error('Failed to fetch blueprints');
                 return res.json() as Promise<Blueprint[]>;
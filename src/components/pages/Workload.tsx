@@ -50,7 +50,7 @@ export default function ListWorkloads() {
 
         const queryString = params.toString() ? `?${params.toString()}` : '';
 
-        fetch(`${API_URL}api/workloads${queryString}`)
+        fetch(`${API_URL}api/workloads${queryString}`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => res.json())
             .then((data: StacksResponse) => {
                 setStacks(data.stacks);
@@ -84,6 +84,9 @@ export default function ListWorkloads() {
 
         fetch(`${API_URL}api/workloads/${selectedStack.orgName}/${selectedStack.projectName}/${selectedStack.stackName}`, {
             method: 'DELETE',
+            headers: {
+                Authorization: `Bearer ${auth.user?.access_token}`,
+            },
         })
             .then(res => {
                 if (!res.ok) {
@@ -409,20 +412,21 @@ export function CreateWorkload() {
     const [mergedData, setMergedData] = useState(null);
     const navigate = useNavigate();
     const [loading, setLoading] = useState(true);
+    const auth = useAuth();
 
     useEffect(() => {
         let isMounted = true;
         const API_URL = import.meta.env.MODE === 'production' ? import.meta.env.VITE_API_URL : '/';
 
-        const fetchSchema = fetch(`${API_URL}api/blueprints/${blueprintName}/schema`)
+        const fetchSchema = fetch(`${API_URL}api/blueprints/${blueprintName}/schema`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => res.json());
 
 
-        const fetchMetadata = fetch(`${API_URL}api/workloads/schema`)
+        const fetchMetadata = fetch(`${API_URL}api/workloads/schema`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => res.json());
 
 
-        const fetchUiSchema = fetch(`${API_URL}api/blueprints/${blueprintName}/ui-schema`)
+        const fetchUiSchema = fetch(`${API_URL}api/blueprints/${blueprintName}/ui-schema`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => res.json());
 
 
@@ -498,6 +502,7 @@ export function CreateWorkload() {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
+                Authorization: `Bearer ${auth.user?.access_token}`,
             },
             body: JSON.stringify({
                 blueprintName,
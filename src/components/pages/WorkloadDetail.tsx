@@ -65,7 +65,7 @@ export default function WorkloadDetail() {
 
         const API_URL = import.meta.env.MODE === 'production' ? import.meta.env.VITE_API_URL : '/';
 
-        fetch(`${API_URL}api/workloads/${organization}/${blueprintName}/${name}`)
+        fetch(`${API_URL}api/workloads/${organization}/${blueprintName}/${name}`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => {
                 if (!res.ok) {
                     throw new Error(`Failed to fetch details: ${res.status}`);
@@ -93,7 +93,7 @@ export default function WorkloadDetail() {
         const API_URL = import.meta.env.MODE === 'production' ? import.meta.env.VITE_API_URL : '/';
 
 
-        fetch(`${API_URL}api/blueprints/${blueprintName}/schema`)
+        fetch(`${API_URL}api/blueprints/${blueprintName}/schema`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}})
             .then(res => {
                 if (!res.ok) {
                     throw new Error(`Failed to fetch blueprint schema: ${res.status}`);
@@ -111,7 +111,7 @@ export default function WorkloadDetail() {
                 setEditSchema(advancedSchema);
 
 
-                return fetch(`${API_URL}api/blueprints/${blueprintName}/ui-schema`);
+                return fetch(`${API_URL}api/blueprints/${blueprintName}/ui-schema`, {headers: {Authorization: `Bearer ${auth.user?.access_token}`}});
             })
             .then(res => {
                 if (!res.ok) {
@@ -161,6 +161,7 @@ export default function WorkloadDetail() {
             method: 'PUT',
             headers: {
                 'Content-Type': 'application/json',
+                Authorization: `Bearer ${auth.user?.access_token}`,
             },
             body: JSON.stringify({
                 ...workloadDetails,